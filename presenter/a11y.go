@@ -0,0 +1,226 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"codeberg.org/t73fde/sxpf"
+	"zettelstore.de/c/api"
+	"zettelstore.de/c/sexpr"
+	"zettelstore.de/contrib/presenter/deck"
+)
+
+// minContrast is the minimum contrast ratio of normal text, according to
+// WCAG 2.1, success criterion 1.4.3.
+const minContrast = 4.5
+
+// a11yIssue is an accessibility problem of a slide show.
+type a11yIssue struct {
+	slideNo int          // number of the slide; 0 for the whole slide show
+	zid     api.ZettelID // zettel that causes the problem
+	msg     string
+}
+
+// auditAccessibility checks the slide show for some accessibility problems:
+// missing language, images without alternative text, videos without captions,
+// headings that skip a level, and custom colors with a low contrast to the
+// white background.
+// The given CSS definitions are checked in addition to the CSS of the slide
+// set.
+func auditAccessibility(slides *deck.SlideSet, cfg *slidesConfig, userCSS []byte, lang string) []a11yIssue {
+	var issues []a11yIssue
+	if slides.Lang() == "" {
+		issues = append(issues, a11yIssue{0, slides.Zid(), translate(lang, "The slide set specifies no language.")})
+	}
+	for _, color := range []string{cfg.branding.primaryColor, cfg.branding.secondaryColor} {
+		issues = appendContrastIssue(issues, lang, 0, slides.Zid(), color)
+	}
+	for _, css := range [][]byte{userCSS, slides.CSS()} {
+		for _, color := range cssColors(css) {
+			issues = appendContrastIssue(issues, lang, 0, slides.Zid(), color)
+		}
+	}
+
+	headingOffset := cfg.headingOffset(deck.SlideRoleShow)
+	offset := 1
+	if !slides.Title().IsEmpty() {
+		offset++
+	}
+	for si := slides.Slides(deck.SlideRoleShow, offset); si != nil; si = si.Next() {
+		for sub := si.Child(); sub != nil; sub = sub.Next() {
+			sl := sub.Slide
+			prevLevel := 0
+			if !sl.Title().IsEmpty() {
+				prevLevel = 1
+			}
+			walkNodes(sl.Content(), func(node *sxpf.Pair) {
+				switch node.GetFirst() {
+				case sexpr.SymHeading:
+					level, err := node.GetTail().GetInteger()
+					if err != nil {
+						return
+					}
+					hLevel := int(level) + headingOffset
+					if hLevel > prevLevel+1 {
+						issues = append(issues, a11yIssue{sub.SlideNo, sl.Zid(), fmt.Sprintf(
+							translate(lang, "Heading level h%d follows h%d."), hLevel, prevLevel)})
+					}
+					prevLevel = hLevel
+				case sexpr.SymEmbed:
+					args := node.GetTail()
+					ref, _ := args.GetTail().GetPair()
+					src, _ := ref.GetTail().GetString()
+					if video, found := slides.GetVideo(api.ZettelID(src)); found {
+						if len(video.Tracks) == 0 {
+							issues = append(issues, a11yIssue{sub.SlideNo, sl.Zid(), fmt.Sprintf(
+								translate(lang, "Video %s has no captions."), src)})
+						}
+						return
+					}
+					if args.GetTail().GetTail().GetTail().IsEmpty() {
+						if img, found := slides.GetImage(api.ZettelID(src)); found && img.Title != "" {
+							return // title is used as alternative text
+						}
+						issues = append(issues, a11yIssue{sub.SlideNo, sl.Zid(), fmt.Sprintf(
+							translate(lang, "Image %s has no alternative text."), src)})
+					}
+				}
+			})
+		}
+	}
+	return issues
+}
+
+// walkNodes calls the function for every node of the given list, including
+// all nested nodes, in document order.
+func walkNodes(list *sxpf.Pair, fn func(*sxpf.Pair)) {
+	for elem := list; !elem.IsNil(); elem = elem.GetTail() {
+		node, err := elem.GetPair()
+		if err != nil || node.IsNil() {
+			continue
+		}
+		if _, isSym := node.GetFirst().(*sxpf.Symbol); isSym {
+			fn(node)
+			walkNodes(node.GetTail(), fn)
+		} else {
+			walkNodes(node, fn)
+		}
+	}
+}
+
+func appendContrastIssue(issues []a11yIssue, lang string, slideNo int, zid api.ZettelID, color string) []a11yIssue {
+	if color == "" {
+		return issues
+	}
+	r, g, b, ok := parseColor(color)
+	if !ok {
+		return issues
+	}
+	if ratio := contrastRatio(r, g, b, 255, 255, 255); ratio < minContrast {
+		issues = append(issues, a11yIssue{slideNo, zid, fmt.Sprintf(
+			translate(lang, "Color %s has a contrast of %.1f:1 to a white background, at least %.1f:1 is needed."),
+			color, ratio, minContrast)})
+	}
+	return issues
+}
+
+var reCSSColor = regexp.MustCompile(`(?:^|[\s;{])color\s*:\s*([^;}!]+)`)
+
+// cssColors returns all values of the CSS property "color".
+func cssColors(css []byte) []string {
+	var result []string
+	for _, match := range reCSSColor.FindAllSubmatch(css, -1) {
+		result = append(result, strings.TrimSpace(string(match[1])))
+	}
+	return result
+}
+
+// parseColor parses a CSS color, given as "#rgb", "#rrggbb", or "rgb(r, g, b)".
+func parseColor(s string) (r, g, b uint8, ok bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if strings.HasPrefix(s, "#") {
+		hex := s[1:]
+		if len(hex) == 3 {
+			hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+		}
+		if len(hex) != 6 {
+			return 0, 0, 0, false
+		}
+		val, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			return 0, 0, 0, false
+		}
+		return uint8(val >> 16), uint8(val >> 8), uint8(val), true
+	}
+	if strings.HasPrefix(s, "rgb(") {
+		parts := strings.Split(strings.TrimSuffix(strings.TrimPrefix(s, "rgb("), ")"), ",")
+		if len(parts) != 3 {
+			return 0, 0, 0, false
+		}
+		var rgb [3]uint8
+		for i, part := range parts {
+			val, err := strconv.ParseUint(strings.TrimSpace(part), 10, 8)
+			if err != nil {
+				return 0, 0, 0, false
+			}
+			rgb[i] = uint8(val)
+		}
+		return rgb[0], rgb[1], rgb[2], true
+	}
+	return 0, 0, 0, false
+}
+
+// contrastRatio computes the contrast ratio of two colors, as defined by WCAG.
+func contrastRatio(r1, g1, b1, r2, g2, b2 uint8) float64 {
+	l1, l2 := luminance(r1, g1, b1), luminance(r2, g2, b2)
+	if l1 < l2 {
+		l1, l2 = l2, l1
+	}
+	return (l1 + 0.05) / (l2 + 0.05)
+}
+
+func luminance(r, g, b uint8) float64 {
+	channel := func(c uint8) float64 {
+		v := float64(c) / 255
+		if v <= 0.03928 {
+			return v / 12.92
+		}
+		return math.Pow((v+0.055)/1.055, 2.4)
+	}
+	return 0.2126*channel(r) + 0.7152*channel(g) + 0.0722*channel(b)
+}
+
+// writeA11yIssues writes the result of the accessibility audit.
+func writeA11yIssues(w io.Writer, lang string, issues []a11yIssue) {
+	fmt.Fprintf(w, "<h2>%s</h2>\n", translate(lang, "Accessibility"))
+	if len(issues) == 0 {
+		fmt.Fprintf(w, "<p>%s</p>\n", translate(lang, "No accessibility problems found."))
+		return
+	}
+	fmt.Fprintf(w, "<table>\n<tr><th>%s</th><th>%s</th><th>%s</th></tr>\n",
+		translate(lang, "Slide"), translate(lang, "Zettel"), translate(lang, "Message"))
+	for _, issue := range issues {
+		slideNo := ""
+		if issue.slideNo > 0 {
+			slideNo = strconv.Itoa(issue.slideNo)
+		}
+		fmt.Fprintf(w, "<tr><td>%s</td><td><a href=\"%s\">%s</a></td><td>%s</td></tr>\n",
+			slideNo, issue.zid, issue.zid, html.EscapeString(issue.msg))
+	}
+	io.WriteString(w, "</table>\n")
+}
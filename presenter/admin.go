@@ -0,0 +1,289 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"zettelstore.de/c/api"
+)
+
+// The admin page shows the state of zettel presenter to its operator. It is
+// only available, if credentials are given by the option "-admin", and it is
+// never served from the last-good cache.
+
+const adminPath = "/admin"
+
+// Parameter of the client tracker.
+const (
+	clientWindow = 10 * time.Minute // a client is listed, if it sent a request within this window
+	maxClients   = 1000
+)
+
+// clientTracker remembers the clients that sent a request recently.
+type clientTracker struct {
+	mx      sync.Mutex
+	clients map[string]clientEntry
+}
+
+type clientEntry struct {
+	lastSeen time.Time
+	requests int
+	agent    string
+}
+
+func (ct *clientTracker) add(r *http.Request) {
+	client := auditClient(r)
+	now := time.Now()
+	ct.mx.Lock()
+	defer ct.mx.Unlock()
+	if ct.clients == nil {
+		ct.clients = make(map[string]clientEntry)
+	}
+	entry, found := ct.clients[client]
+	if !found && len(ct.clients) >= maxClients {
+		ct.expire(now)
+		if len(ct.clients) >= maxClients {
+			return
+		}
+	}
+	ct.clients[client] = clientEntry{lastSeen: now, requests: entry.requests + 1, agent: r.UserAgent()}
+}
+
+func (ct *clientTracker) expire(now time.Time) {
+	for client, entry := range ct.clients {
+		if now.Sub(entry.lastSeen) > clientWindow {
+			delete(ct.clients, client)
+		}
+	}
+}
+
+type clientInfo struct {
+	client string
+	clientEntry
+}
+
+// recent returns all clients that sent a request recently, most recent first.
+func (ct *clientTracker) recent() []clientInfo {
+	ct.mx.Lock()
+	ct.expire(time.Now())
+	result := make([]clientInfo, 0, len(ct.clients))
+	for client, entry := range ct.clients {
+		result = append(result, clientInfo{client, entry})
+	}
+	ct.mx.Unlock()
+	sort.Slice(result, func(i, j int) bool { return result[i].lastSeen.After(result[j].lastSeen) })
+	return result
+}
+
+// newAdminToken returns a random token that protects the forms of the admin
+// page against cross-site requests.
+func newAdminToken() string {
+	var buf [16]byte
+	rand.Read(buf[:])
+	return hex.EncodeToString(buf[:])
+}
+
+// adminAuthorized checks the credentials of the request. The given
+// credentials have the form "user:password".
+func adminAuthorized(r *http.Request, credentials string) bool {
+	wantUser, wantPassword, _ := strings.Cut(credentials, ":")
+	user, password, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+	userOK := subtle.ConstantTimeCompare([]byte(user), []byte(wantUser))
+	passwordOK := subtle.ConstantTimeCompare([]byte(password), []byte(wantPassword))
+	return userOK&passwordOK == 1
+}
+
+// requireAdmin checks the credentials of the request against the credentials
+// of the admin page. If they do not match, the browser is asked for them, and
+// false is returned. Responses are never cached.
+func requireAdmin(w http.ResponseWriter, r *http.Request, credentials string) bool {
+	w.Header().Set("Cache-Control", "no-store")
+	if !adminAuthorized(r, credentials) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="Zettel Presenter Admin", charset="UTF-8"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// processAdmin writes the admin page, or executes one of its actions.
+func processAdmin(w http.ResponseWriter, r *http.Request, cs *configStore) {
+	credentials := cs.opts.admin
+	if credentials == "" || r.URL.Path != adminPath {
+		http.NotFound(w, r)
+		return
+	}
+	if !requireAdmin(w, r, credentials) {
+		return
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		if r.URL.Query().Get("action") == "mirror" {
+			processMirror(w, r, cs.get(), api.ZettelID(strings.TrimSpace(r.URL.Query().Get("zid"))))
+			return
+		}
+		writeAdminPage(w, r, cs)
+	case http.MethodPost:
+		if r.PostFormValue("token") != cs.adminToken {
+			http.Error(w, "Invalid token", http.StatusForbidden)
+			return
+		}
+		cfg := cs.get()
+		switch action := r.PostFormValue("action"); action {
+		case "flush":
+			cfg.thumbs.flush()
+			cfg.external.flush()
+			cfg.lastGood.flush()
+			log.Println("ADMN caches flushed")
+		case "prerender":
+			zid := api.ZettelID(strings.TrimSpace(r.PostFormValue("zid")))
+			if !zid.IsValid() {
+				http.Error(w, fmt.Sprintf("Invalid zettel identifier %q", zid), http.StatusBadRequest)
+				return
+			}
+			go prerender(cfg, zid)
+		default:
+			http.Error(w, fmt.Sprintf("Unknown action %q", action), http.StatusBadRequest)
+			return
+		}
+		// The location is relative, because of the path prefix of a tenant.
+		w.Header().Set("Location", strings.TrimPrefix(adminPath, "/"))
+		w.WriteHeader(http.StatusSeeOther)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// prerender renders the slide show and the handout of a slide set, so that
+// the responses are stored in the last-good cache, and all zettel are stored
+// in the disk cache.
+func prerender(cfg *slidesConfig, zid api.ZettelID) {
+	for _, suffix := range []string{"reveal", "html"} {
+		prerenderPath(cfg, "/"+string(zid)+"."+suffix)
+	}
+}
+
+func prerenderPath(cfg *slidesConfig, path string) {
+	ctx := context.Background()
+	if timeout := cfg.limits.renderTimeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		log.Println("ADMN", path, err)
+		return
+	}
+	rb := responseBuffer{header: make(http.Header)}
+	cfg.lastGood.serve(&rb, r, cfg.lang, cfg.limits, makeRequestHandler(cfg))
+	log.Println("ADMN prerendered", path, rb.status, rb.buf.Len())
+}
+
+const adminCSS = `<style>
+body { font-family: sans-serif; margin: 1rem 2rem }
+table { border-collapse: collapse }
+th, td { text-align: left; padding: .25rem .75rem; border-bottom: 1px solid #ccc }
+td.right { text-align: right }
+form { display: inline-block; margin-right: 1rem }
+</style>
+`
+
+func writeAdminPage(w http.ResponseWriter, r *http.Request, cs *configStore) {
+	cfg := cs.get()
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	io.WriteString(w, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>Zettel Presenter Admin</title>\n")
+	io.WriteString(w, adminCSS)
+	io.WriteString(w, "</head>\n<body>\n<h1>Zettel Presenter Admin</h1>\n")
+
+	io.WriteString(w, "<h2>Zettelstore</h2>\n<table>\n")
+	fmt.Fprintf(w, "<tr><th>URL</th><td>%s</td></tr>\n", html.EscapeString(cfg.c.Base()))
+	start := time.Now()
+	_, err := cfg.c.GetMeta(r.Context(), zidConfig)
+	if err == nil {
+		fmt.Fprintf(w, "<tr><th>Status</th><td>reachable (%s)</td></tr>\n", time.Since(start).Round(time.Millisecond))
+	} else {
+		fmt.Fprintf(w, "<tr><th>Status</th><td>not reachable: %s</td></tr>\n", html.EscapeString(err.Error()))
+	}
+	failures, openUntil := cfg.c.breaker.state()
+	fmt.Fprintf(w, "<tr><th>Failed calls in a row</th><td>%d</td></tr>\n", failures)
+	if time.Now().Before(openUntil) {
+		fmt.Fprintf(w, "<tr><th>Calls suspended until</th><td>%s</td></tr>\n", openUntil.Format("15:04:05"))
+	}
+	io.WriteString(w, "</table>\n")
+
+	io.WriteString(w, "<h2>Caches</h2>\n<table>\n<tr><th>Cache</th><th>Entries</th><th>Size</th><th>Hits</th><th>Misses</th><th>Hit rate</th></tr>\n")
+	entries, size, hits, misses := cfg.thumbs.stats()
+	writeCacheRow(w, "Thumbnails", entries, int64(size), int64(hits), int64(misses))
+	entries, size, hits, misses = cfg.external.stats()
+	writeCacheRow(w, "External slide content", entries, int64(size), int64(hits), int64(misses))
+	entries, size, stale := cfg.lastGood.stats()
+	fmt.Fprintf(w, "<tr><td>Last good responses</td><td class=\"right\">%d</td><td class=\"right\">%s</td><td class=\"right\">%d</td><td></td><td></td></tr>\n",
+		entries, formatSize(int64(size)), stale)
+	if cfg.disk != nil {
+		files, diskSize, diskHits, diskMisses := cfg.disk.stats()
+		name := "Disk: " + cfg.disk.dir
+		if cfg.disk.dir == "" {
+			name = "Shared"
+		}
+		writeCacheRow(w, name, files, diskSize, diskHits, diskMisses)
+	}
+	io.WriteString(w, "</table>\n<p>Hits of the last good responses count the responses served while Zettelstore was not reachable.</p>\n")
+
+	io.WriteString(w, "<h2>Actions</h2>\n")
+	fmt.Fprintf(w, "<form method=\"post\" action=\"admin\"><input type=\"hidden\" name=\"token\" value=\"%s\"><input type=\"hidden\" name=\"action\" value=\"flush\"><button type=\"submit\">Flush caches</button></form>\n", cs.adminToken)
+	fmt.Fprintf(w, "<form method=\"post\" action=\"admin\"><input type=\"hidden\" name=\"token\" value=\"%s\"><input type=\"hidden\" name=\"action\" value=\"prerender\"><label>Slide set <input name=\"zid\" size=\"14\" maxlength=\"14\" pattern=\"[0-9]{14}\" required></label> <button type=\"submit\">Pre-render</button></form>\n", cs.adminToken)
+	io.WriteString(w, "<form method=\"get\" action=\"admin\"><input type=\"hidden\" name=\"action\" value=\"mirror\"><label>Slide set <input name=\"zid\" size=\"14\" maxlength=\"14\" pattern=\"[0-9]{14}\" required></label> <button type=\"submit\">Download for offline use</button></form>\n")
+
+	clients := cs.clients.recent()
+	fmt.Fprintf(w, "<h2>Clients of the last %s</h2>\n<table>\n<tr><th>Client</th><th>Last request</th><th>Requests</th><th>User agent</th></tr>\n", clientWindow)
+	for _, ci := range clients {
+		fmt.Fprintf(w, "<tr><td>%s</td><td>%s</td><td class=\"right\">%d</td><td>%s</td></tr>\n",
+			html.EscapeString(ci.client), ci.lastSeen.Format("15:04:05"), ci.requests, html.EscapeString(ci.agent))
+	}
+	io.WriteString(w, "</table>\n<p><a href=\"./\">Home</a></p>\n</body>\n</html>\n")
+}
+
+func writeCacheRow(w io.Writer, name string, entries int, size, hits, misses int64) {
+	rate := ""
+	if total := hits + misses; total > 0 {
+		rate = fmt.Sprintf("%.1f%%", float64(hits)*100/float64(total))
+	}
+	fmt.Fprintf(w, "<tr><td>%s</td><td class=\"right\">%d</td><td class=\"right\">%s</td><td class=\"right\">%d</td><td class=\"right\">%d</td><td class=\"right\">%s</td></tr>\n",
+		html.EscapeString(name), entries, formatSize(size), hits, misses, rate)
+}
+
+// formatSize returns a human readable size.
+func formatSize(size int64) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(size)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", size)
+}
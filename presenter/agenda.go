@@ -0,0 +1,110 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"codeberg.org/t73fde/sxpf"
+	"zettelstore.de/c/sexpr"
+	"zettelstore.de/contrib/presenter/deck"
+	"zettelstore.de/contrib/presenter/render"
+)
+
+// maxShortAgenda is the number of entries of an agenda that fit into one
+// column.
+const maxShortAgenda = 8
+
+// addAgenda adds the agenda slide to a completed slide set, if its metadata
+// key "slide-agenda" is true.
+func addAgenda(slides *deck.SlideSet) {
+	if slides.HasAgenda() {
+		title := translate(slides.Lang(), "Agenda")
+		slides.AddAgenda(sxpf.NewPair(sxpf.NewPair(sexpr.SymText, sxpf.NewPair(sxpf.NewString(title), nil)), nil))
+	}
+}
+
+// addClosing adds the closing slide to a completed slide set, if its
+// metadata key "slide-closing" is true.
+func addClosing(slides *deck.SlideSet, author string) {
+	if slides.HasClosing() {
+		title := translate(slides.Lang(), "Thank you!")
+		slides.AddClosing(sxpf.NewPair(sxpf.NewPair(sexpr.SymText, sxpf.NewPair(sxpf.NewString(title), nil)), nil), author)
+	}
+}
+
+// renderAgenda writes the section of the agenda slide. It lists the titles
+// of all following slides, or only the titles of the parts, if several slide
+// sets are combined. Generated slides are not listed.
+func (*revealRenderer) renderAgenda(w io.Writer, he *render.Generator, si *deck.SlideInfo) {
+	var entries []*deck.SlideInfo
+	onlyDividers := false
+	for next := si.Next(); next != nil; next = next.Next() {
+		if !next.Slide.Zid().IsValid() {
+			continue // e.g. the closing slide
+		}
+		if next.Slide.IsDivider() && !onlyDividers {
+			onlyDividers = true
+			entries = entries[:0]
+		}
+		if !onlyDividers || next.Slide.IsDivider() {
+			entries = append(entries, next)
+		}
+	}
+	fmt.Fprintf(w, "<section id=\"(%d)\" class=\"zs-agenda\">\n<h1>%s</h1>\n", si.SlideNo, render.EvaluateInline(he, si.Slide.Title()))
+	if len(entries) > maxShortAgenda {
+		io.WriteString(w, "<ol class=\"long\">\n")
+	} else {
+		io.WriteString(w, "<ol>\n")
+	}
+	for _, entry := range entries {
+		title := entry.Slide.Title()
+		if title.IsEmpty() {
+			title = sxpf.NewPair(sxpf.NewPair(sexpr.SymText, sxpf.NewPair(sxpf.NewString(string(entry.Slide.Zid())), nil)), nil)
+		}
+		fmt.Fprintf(w, "<li data-slide=\"%d\"><a href=\"#/(%d)\">%s</a></li>\n", entry.SlideNo, entry.SlideNo, render.EvaluateInline(he, title))
+	}
+	io.WriteString(w, "</ol>\n</section>\n")
+}
+
+// writeAgendaScript writes the script that highlights the entry of the
+// agenda, which was shown last, when the agenda slide is shown again.
+func writeAgendaScript(w io.Writer) {
+	io.WriteString(w, `<style type="text/css">
+.reveal section.zs-agenda ol.long { columns: 2; font-size: .75em }
+.reveal section.zs-agenda li { break-inside: avoid }
+.reveal section.zs-agenda li.current { font-weight: bold }
+.reveal section.zs-agenda li.current::marker { color: var(--r-link-color) }
+</style>
+<script>
+(function() {
+var last = 0;
+function update(ev) {
+var slide = ev.currentSlide;
+if (!slide) { return; }
+if (!slide.classList.contains("zs-agenda")) {
+var m = /^\((\d+)\)$/.exec(slide.id);
+if (m) { last = parseInt(m[1], 10); }
+return;
+}
+var current = null;
+slide.querySelectorAll("li[data-slide]").forEach(function(li) {
+li.classList.remove("current");
+if (last > 0 && parseInt(li.getAttribute("data-slide"), 10) <= last) { current = li; }
+});
+if (current) { current.classList.add("current"); }
+}
+Reveal.on("slidechanged", update);
+})();
+</script>
+`)
+}
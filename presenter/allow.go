@@ -0,0 +1,207 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"zettelstore.de/c/api"
+	"zettelstore.de/contrib/presenter/deck"
+)
+
+// Keys of the presenter configuration zettel to restrict the zettel that are
+// rendered. Every value is a list of words, separated by space characters.
+const (
+	KeyAllowZids  = "allow-zids"
+	KeyAllowRoles = "allow-roles"
+	KeyAllowTags  = "allow-tags"
+)
+
+// allowList restricts the zettel that are rendered. A zettel is allowed, if
+// its identifier, its role, or one of its tags is listed. In addition, all
+// zettel that are used by an allowed slide set, e.g. its slides and images,
+// are allowed after the slide set was rendered. If nothing is listed, all
+// zettel are allowed.
+//
+// When the configuration zettel is read again, zettel granted before are
+// inherited. They are allowed again as soon as one of the slide sets that
+// granted them is allowed by the new list.
+type allowList struct {
+	zids  map[api.ZettelID]bool
+	roles map[string]bool
+	tags  map[string]bool
+
+	mx        sync.RWMutex
+	granted   map[api.ZettelID][]api.ZettelID // zettel -> granting slide sets
+	inherited map[api.ZettelID][]api.ZettelID // not yet checked against this list
+}
+
+func newAllowList(m api.ZettelMeta) *allowList {
+	al := &allowList{
+		zids:    make(map[api.ZettelID]bool),
+		roles:   make(map[string]bool),
+		tags:    make(map[string]bool),
+		granted: make(map[api.ZettelID][]api.ZettelID),
+	}
+	for _, val := range strings.Fields(m[KeyAllowZids]) {
+		if zid := api.ZettelID(val); zid.IsValid() {
+			al.zids[zid] = true
+		} else {
+			log.Println("ALLW", "invalid zettel identifier", val)
+		}
+	}
+	for _, role := range strings.Fields(m[KeyAllowRoles]) {
+		al.roles[role] = true
+	}
+	for _, tag := range strings.Fields(m[KeyAllowTags]) {
+		al.tags[normalizeTag(tag)] = true
+	}
+	return al
+}
+
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(tag, "#"))
+}
+
+// enabled returns true, if the zettel to be rendered are restricted.
+func (al *allowList) enabled() bool {
+	return al != nil && (len(al.zids) > 0 || len(al.roles) > 0 || len(al.tags) > 0)
+}
+
+// allowsMeta returns true, if the zettel with the given identifier and
+// metadata is allowed.
+func (al *allowList) allowsMeta(zid api.ZettelID, m api.ZettelMeta) bool {
+	return al.isListed(zid, m) || al.isGranted(zid)
+}
+
+// isListed returns true, if the zettel is allowed by the list itself.
+func (al *allowList) isListed(zid api.ZettelID, m api.ZettelMeta) bool {
+	if !al.enabled() || al.zids[zid] || al.roles[m[api.KeyRole]] {
+		return true
+	}
+	for _, tag := range strings.Fields(m[api.KeyTags]) {
+		if al.tags[normalizeTag(tag)] {
+			return true
+		}
+	}
+	return false
+}
+
+func (al *allowList) isGranted(zid api.ZettelID) bool {
+	al.mx.RLock()
+	defer al.mx.RUnlock()
+	return len(al.granted[zid]) > 0
+}
+
+// allows returns true, if the zettel with the given identifier is allowed.
+// Its metadata is only retrieved, if needed.
+func (al *allowList) allows(ctx context.Context, c *zsClient, zid api.ZettelID) bool {
+	if !al.enabled() || al.zids[zid] || al.isGranted(zid) || al.allowsInherited(ctx, c, zid) {
+		return true
+	}
+	m, err := c.GetMeta(ctx, zid)
+	if err != nil {
+		return false
+	}
+	return al.allowsMeta(zid, m)
+}
+
+// allowsInherited returns true, if the zettel was granted by a slide set
+// before the configuration was read again, and if this slide set is still
+// allowed. Then the zettel is granted again.
+func (al *allowList) allowsInherited(ctx context.Context, c *zsClient, zid api.ZettelID) bool {
+	al.mx.RLock()
+	sets := al.inherited[zid]
+	al.mx.RUnlock()
+	for _, setZid := range sets {
+		if !al.zids[setZid] {
+			m, err := c.GetMeta(ctx, setZid)
+			if err != nil || !al.isListed(setZid, m) {
+				continue
+			}
+		}
+		al.mx.Lock()
+		al.granted[zid] = appendZid(al.granted[zid], setZid)
+		al.mx.Unlock()
+		return true
+	}
+	return false
+}
+
+// inherit takes the zettel granted by the old list, so that they are checked
+// when they are requested.
+func (al *allowList) inherit(old *allowList) {
+	if !al.enabled() || old == nil {
+		return
+	}
+	old.mx.RLock()
+	defer old.mx.RUnlock()
+	inherited := make(map[api.ZettelID][]api.ZettelID, len(old.granted)+len(old.inherited))
+	for _, grants := range []map[api.ZettelID][]api.ZettelID{old.granted, old.inherited} {
+		for zid, sets := range grants {
+			for _, setZid := range sets {
+				inherited[zid] = appendZid(inherited[zid], setZid)
+			}
+		}
+	}
+	al.mx.Lock()
+	al.inherited = inherited
+	al.mx.Unlock()
+}
+
+// appendZid appends the zettel identifier to the list, if it is not already
+// an element of the list.
+func appendZid(zids []api.ZettelID, zid api.ZettelID) []api.ZettelID {
+	for _, z := range zids {
+		if z == zid {
+			return zids
+		}
+	}
+	return append(zids, zid)
+}
+
+// grant allows all zettel that are used by the given slide set.
+func (al *allowList) grant(slides *deck.SlideSet) {
+	if !al.enabled() {
+		return
+	}
+	setZid := slides.Zid()
+	zids := slides.SlideZids()
+	zids = append(zids, slides.Images()...)
+	zids = append(zids, slides.FontZids()...)
+	zids = append(zids, slides.TitleImage(), slides.CSSZid(), slides.PrintCSSZid())
+	for _, zid := range slides.Videos() {
+		zids = append(zids, zid)
+		if video, found := slides.GetVideo(zid); found {
+			for _, track := range video.Tracks {
+				zids = append(zids, track.Zid)
+			}
+		}
+	}
+	al.mx.Lock()
+	defer al.mx.Unlock()
+	for _, zid := range zids {
+		if zid.IsValid() {
+			al.granted[zid] = appendZid(al.granted[zid], setZid)
+		}
+	}
+}
+
+// reportNotAllowed responds as if the zettel does not exist, so that the
+// existence of a zettel is not revealed.
+func reportNotAllowed(w http.ResponseWriter, zid api.ZettelID) {
+	log.Println("ALLW", "denied", zid)
+	http.Error(w, "zettel "+string(zid)+" not found", http.StatusNotFound)
+}
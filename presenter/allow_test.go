@@ -0,0 +1,94 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"zettelstore.de/c/api"
+	"zettelstore.de/c/sexpr"
+	"zettelstore.de/contrib/presenter/deck"
+)
+
+func TestAllowListMeta(t *testing.T) {
+	if al := newAllowList(api.ZettelMeta{}); al.enabled() || !al.allowsMeta("20220101000001", nil) {
+		t.Error("empty allowlist must allow every zettel")
+	}
+	al := newAllowList(api.ZettelMeta{
+		KeyAllowZids:  "20220101000001 invalid",
+		KeyAllowRoles: "slideset",
+		KeyAllowTags:  "#Public talk",
+	})
+	if !al.enabled() {
+		t.Fatal("allowlist must be enabled")
+	}
+	testcases := []struct {
+		zid api.ZettelID
+		m   api.ZettelMeta
+		exp bool
+	}{
+		{"20220101000001", nil, true},
+		{"20220101000002", nil, false},
+		{"20220101000002", api.ZettelMeta{api.KeyRole: "slideset"}, true},
+		{"20220101000002", api.ZettelMeta{api.KeyRole: "zettel"}, false},
+		{"20220101000002", api.ZettelMeta{api.KeyTags: "#draft #public"}, true},
+		{"20220101000002", api.ZettelMeta{api.KeyTags: "#TALK"}, true},
+		{"20220101000002", api.ZettelMeta{api.KeyTags: "#draft"}, false},
+	}
+	for _, tc := range testcases {
+		if got := al.allowsMeta(tc.zid, tc.m); got != tc.exp {
+			t.Errorf("%s %v: expected %v, but got %v", tc.zid, tc.m, tc.exp, got)
+		}
+	}
+	if !al.allows(context.Background(), nil, "20220101000001") {
+		t.Error("listed zettel must be allowed without its metadata")
+	}
+}
+
+func TestAllowListGrant(t *testing.T) {
+	const (
+		setZid   = api.ZettelID("20220101000001")
+		slideZid = api.ZettelID("20220101000002")
+	)
+	al := newAllowList(api.ZettelMeta{KeyAllowZids: string(setZid)})
+	slides := deck.NewMeta(setZid, sexpr.Meta{})
+	slides.AdditionalSlide(slideZid, sexpr.Meta{}, nil)
+	if al.isGranted(slideZid) {
+		t.Fatal("slide must not be granted before the slide set was rendered")
+	}
+	al.grant(slides)
+	if !al.allowsMeta(slideZid, nil) {
+		t.Error("slide of a rendered slide set must be allowed")
+	}
+
+	// After the configuration was read again, the slide is allowed as long
+	// as the slide set is listed.
+	next := newAllowList(api.ZettelMeta{KeyAllowZids: string(setZid)})
+	next.inherit(al)
+	if !next.allows(context.Background(), nil, slideZid) || !next.isGranted(slideZid) {
+		t.Error("slide of a listed slide set must be granted again")
+	}
+	other := newAllowList(api.ZettelMeta{KeyAllowZids: "20220101000003"})
+	other.inherit(al)
+	if other.allowsInherited(context.Background(), nil, "20220101000004") {
+		t.Error("zettel that was never granted must not be allowed")
+	}
+}
+
+func TestAppendZid(t *testing.T) {
+	zids := appendZid(nil, "20220101000001")
+	zids = appendZid(zids, "20220101000002")
+	zids = appendZid(zids, "20220101000001")
+	if len(zids) != 2 {
+		t.Errorf("expected 2 zettel identifier, but got %v", zids)
+	}
+}
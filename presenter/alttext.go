@@ -0,0 +1,63 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+
+	"zettelstore.de/c/api"
+	"zettelstore.de/contrib/presenter/deck"
+)
+
+// KeyImageAltTitle is the configuration key to use the title of an image
+// zettel as the alternative text of an image without a description.
+const KeyImageAltTitle = "image-alt-title"
+
+// getImageAltTitle returns the value of KeyImageAltTitle. Only a false value
+// disables the alternative text.
+func getImageAltTitle(m map[string]string) bool {
+	val := m[KeyImageAltTitle]
+	return strings.TrimSpace(val) == "" || deck.IsTrue(val)
+}
+
+// addImageTitles retrieves the titles of all images of the slide set, if
+// they are needed as alternative texts.
+func addImageTitles(ctx context.Context, cfg *slidesConfig, slides *deck.SlideSet) {
+	if !cfg.imageAltTitle {
+		return
+	}
+	zids := slides.Images()
+	titles := make([]string, len(zids))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxParallelRequests)
+	for i, zid := range zids {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, zid api.ZettelID) {
+			defer func() { <-sem; wg.Done() }()
+			m, err := cfg.c.GetMeta(ctx, zid)
+			if err != nil {
+				log.Println("ALTT", zid, err)
+				return
+			}
+			titles[i] = m[api.KeyTitle]
+		}(i, zid)
+	}
+	wg.Wait()
+	for i, zid := range zids {
+		if title := titles[i]; title != "" {
+			slides.SetImageTitle(zid, title)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"zettelstore.de/contrib/presenter/deck"
+	"zettelstore.de/contrib/presenter/render"
+)
+
+// OutputAsciiDoc is the output type of the handout as AsciiDoc.
+const OutputAsciiDoc = "adoc"
+
+// asciiDocRenderer produces the handout as an AsciiDoc document, e.g. to
+// include it into a documentation that is built by Asciidoctor or Antora.
+type asciiDocRenderer struct {
+	cfg *slidesConfig
+}
+
+func (*asciiDocRenderer) Role() string { return OutputAsciiDoc }
+func (ar *asciiDocRenderer) Prepare(_ context.Context, cfg *slidesConfig) {
+	ar.cfg = cfg
+}
+func (ar *asciiDocRenderer) Render(w http.ResponseWriter, slides *deck.SlideSet, author string) {
+	w.Header().Set("Content-Type", "text/asciidoc; charset=utf-8")
+	aw := render.NewAsciiDoc(w, slides, ar.cfg.zettelLinks(deck.SlideRoleHandout))
+	offset := 1
+	if title := slides.Title(); !title.IsEmpty() {
+		offset++
+		aw.WriteHeading(0, "", title)
+		if author != "" {
+			fmt.Fprintf(w, "%s\n", author)
+		}
+	}
+	for _, attr := range []struct{ key, value string }{
+		{"lang", slides.Lang()},
+		{"copyright", slides.Copyright()},
+		{"license", slides.License()},
+	} {
+		if attr.value != "" {
+			fmt.Fprintf(w, ":%s: %s\n", attr.key, attr.value)
+		}
+	}
+	io.WriteString(w, ":stem:\n\n")
+	if subtitle := slides.Subtitle(); !subtitle.IsEmpty() && offset > 1 {
+		aw.WriteParagraph(subtitle)
+	}
+	for si := slides.Slides(deck.SlideRoleHandout, offset); si != nil; si = si.Next() {
+		sl := si.Slide
+		anchor := render.AsciiDocAnchor(sl.Zid())
+		if title := sl.Title(); !title.IsEmpty() {
+			aw.WriteHeading(1, anchor, title)
+		} else {
+			fmt.Fprintf(w, "[[%s]]\n", anchor)
+		}
+		aw.WriteBlock(sl.Content(), 1)
+	}
+}
@@ -0,0 +1,84 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// useAssetDir replaces the embedded frontend files by the files of the given
+// directory. Files below "revealjs/" replace the corresponding reveal.js
+// files, all other reveal.js files are still taken from the embedded files.
+// The file "mermaid/mermaid.min.js" replaces the embedded mermaid script.
+func useAssetDir(dir string) error {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	upper := os.DirFS(dir)
+	if fi, err = fs.Stat(upper, "revealjs"); err == nil && fi.IsDir() {
+		revealjs = overlayFS{upper: upper, lower: embeddedRevealjs}
+		assetPrefix = "/revealjs-" + hashFS(revealjs) + "/"
+		log.Println("ASST", filepath.Join(dir, "revealjs"))
+	}
+	data, err := fs.ReadFile(upper, "mermaid/mermaid.min.js")
+	if err == nil {
+		mermaid = string(data)
+		log.Println("ASST", filepath.Join(dir, "mermaid", "mermaid.min.js"))
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// overlayFS is a file system, where the files of the upper file system take
+// precedence over the files of the lower file system.
+type overlayFS struct {
+	upper, lower fs.FS
+}
+
+func (ofs overlayFS) Open(name string) (fs.File, error) {
+	if f, err := ofs.upper.Open(name); err == nil {
+		return f, nil
+	}
+	return ofs.lower.Open(name)
+}
+
+// ReadDir returns the entries of both file systems, sorted by name.
+func (ofs overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	upper, errUpper := fs.ReadDir(ofs.upper, name)
+	lower, errLower := fs.ReadDir(ofs.lower, name)
+	if errUpper != nil && errLower != nil {
+		return nil, errLower
+	}
+	seen := make(map[string]bool, len(upper))
+	result := make([]fs.DirEntry, 0, len(upper)+len(lower))
+	for _, entry := range upper {
+		seen[entry.Name()] = true
+		result = append(result, entry)
+	}
+	for _, entry := range lower {
+		if !seen[entry.Name()] {
+			result = append(result, entry)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name() < result[j].Name() })
+	return result, nil
+}
@@ -0,0 +1,68 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"net/http"
+)
+
+// revealjs contains all reveal.js files below the directory "revealjs". By
+// default, these are the embedded files.
+var revealjs fs.FS = embeddedRevealjs
+
+// assetPrefix is the URL path prefix of all reveal.js files. It contains a
+// hash value of these files, so that a browser is allowed to cache them
+// forever: a new version of presenter with other files uses another prefix.
+var assetPrefix = "/revealjs-" + hashFS(revealjs) + "/"
+
+// hashFS returns a hash value of the names and contents of all files.
+func hashFS(fsys fs.FS) string {
+	h := sha256.New()
+	fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return err
+		}
+		h.Write([]byte(path))
+		h.Write([]byte{0})
+		h.Write(data)
+		return nil
+	})
+	return hex.EncodeToString(h.Sum(nil)[:6])
+}
+
+// makeAssetHandler returns a handler that serves the reveal.js files below
+// the fingerprinted prefix.
+func makeAssetHandler() http.Handler {
+	sub, _ := fs.Sub(revealjs, "revealjs")
+	fileServer := http.StripPrefix(assetPrefix, http.FileServer(http.FS(sub)))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		fileServer.ServeHTTP(w, r)
+	})
+}
+
+// makeUncachedAssetHandler returns a handler that serves the reveal.js files
+// below the prefix "/revealjs/". This is needed for own templates that do not
+// use the fingerprinted prefix.
+func makeUncachedAssetHandler() http.Handler {
+	fileServer := http.FileServer(http.FS(revealjs))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-cache")
+		fileServer.ServeHTTP(w, r)
+	})
+}
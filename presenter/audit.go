@@ -0,0 +1,212 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"zettelstore.de/c/api"
+)
+
+// Configuration keys of the audit log.
+const (
+	KeyAuditRetention = "audit-retention"
+	KeyAuditZettel    = "audit-zettel"
+)
+
+// auditTimeFormat is the format of a timestamp within the audit log.
+const auditTimeFormat = "2006-01-02 15:04:05"
+
+// auditWriteDelay is the time to collect entries before they are written to
+// the audit zettel.
+const auditWriteDelay = 30 * time.Second
+
+// auditLog records which slide sets were rendered, when, and for which
+// client. Entries older than the retention time are removed. The log is kept
+// when the configuration is read again.
+type auditLog struct {
+	mx      sync.Mutex
+	entries []auditEntry // oldest first
+	pending bool         // a write to the audit zettel is scheduled
+	writeMx sync.Mutex   // serializes the writes to the audit zettel
+}
+
+type auditEntry struct {
+	time   time.Time
+	zid    api.ZettelID
+	title  string // plain text
+	output string
+	client string
+}
+
+// auditConfig contains the configuration values of the audit log.
+type auditConfig struct {
+	retention time.Duration // if zero, nothing is recorded
+	zid       api.ZettelID  // zettel to store the log, if valid
+}
+
+func getAuditConfig(m map[string]string) auditConfig {
+	var result auditConfig
+	if val, found := m[KeyAuditRetention]; found {
+		if d, err := time.ParseDuration(val); err == nil && d >= 0 {
+			result.retention = d
+		} else {
+			log.Println("AUDT", KeyAuditRetention, val)
+		}
+	}
+	if val, found := m[KeyAuditZettel]; found {
+		if zid := api.ZettelID(val); zid.IsValid() {
+			result.zid = zid
+		} else {
+			log.Println("AUDT", KeyAuditZettel, val)
+		}
+	}
+	return result
+}
+
+func (ac auditConfig) enabled() bool { return ac.retention > 0 }
+
+// auditClient returns the address of the client of a request. If the request
+// was forwarded by a proxy, the forwarded address is added.
+func auditClient(r *http.Request) string {
+	client := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(client); err == nil {
+		client = host
+	}
+	if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+		client += " (" + strings.TrimSpace(strings.Split(fwd, ",")[0]) + ")"
+	}
+	return client
+}
+
+// recordAudit adds an entry to the audit log, removes expired entries, and
+// schedules a write of the log to the audit zettel, if configured. The output
+// of a preview gets the suffix "-preview".
+func (cfg *slidesConfig) recordAudit(r *http.Request, zid api.ZettelID, title, output string) {
+	if !cfg.auditCfg.enabled() {
+		return
+	}
+	if isPreview(r.Context()) {
+		output += "-preview"
+	}
+	entry := auditEntry{
+		time:   time.Now(),
+		zid:    zid,
+		title:  title,
+		output: output,
+		client: auditClient(r),
+	}
+	log.Println("AUDT", entry.zid, entry.output, entry.client)
+	al := cfg.audit
+	al.mx.Lock()
+	al.entries = append(al.entries, entry)
+	al.expire(cfg.auditCfg.retention)
+	al.mx.Unlock()
+	if cfg.auditCfg.zid.IsValid() {
+		al.scheduleWrite(cfg.c, cfg.auditCfg)
+	}
+}
+
+// scheduleWrite writes the log to the audit zettel after some delay, so that
+// all entries recorded in the meantime are written at once.
+func (al *auditLog) scheduleWrite(c *zsClient, ac auditConfig) {
+	al.mx.Lock()
+	defer al.mx.Unlock()
+	if al.pending {
+		return
+	}
+	al.pending = true
+	time.AfterFunc(auditWriteDelay, func() {
+		al.mx.Lock()
+		al.pending = false
+		al.mx.Unlock()
+		al.writeZettel(c, ac.zid, al.current(ac.retention))
+	})
+}
+
+// expire removes all entries that are older than the given retention time.
+func (al *auditLog) expire(retention time.Duration) {
+	limit := time.Now().Add(-retention)
+	i := 0
+	for i < len(al.entries) && al.entries[i].time.Before(limit) {
+		i++
+	}
+	if i > 0 {
+		al.entries = append([]auditEntry(nil), al.entries[i:]...)
+	}
+}
+
+// current returns a copy of all entries that are not expired.
+func (al *auditLog) current(retention time.Duration) []auditEntry {
+	al.mx.Lock()
+	defer al.mx.Unlock()
+	al.expire(retention)
+	return append([]auditEntry(nil), al.entries...)
+}
+
+// writeZettel replaces the audit zettel with the given entries, as a table.
+func (al *auditLog) writeZettel(c *zsClient, zid api.ZettelID, entries []auditEntry) {
+	al.writeMx.Lock()
+	defer al.writeMx.Unlock()
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s: Zettel presenter audit log\n%s: configuration\n%s: zmk\n%s: owner\n\n",
+		api.KeyTitle, api.KeyRole, api.KeySyntax, api.KeyVisibility)
+	io.WriteString(&buf, "|=Time|=Slide set|=Output|=Client\n")
+	for _, entry := range entries {
+		fmt.Fprintf(&buf, "|%s|[[%s|%s]]|%s|%s\n",
+			entry.time.Format(auditTimeFormat), zmkEscape(entry.title), entry.zid, entry.output, zmkEscape(entry.client))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	if err := c.UpdateZettel(ctx, zid, buf.Bytes()); err != nil {
+		log.Println("AUDT", zid, err)
+	}
+}
+
+// zmkEscape escapes all characters that would be interpreted as zettel
+// markup within a table cell.
+func zmkEscape(s string) string {
+	var sb strings.Builder
+	for _, ch := range s {
+		if strings.ContainsRune("|[]\\", ch) {
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(ch)
+	}
+	return sb.String()
+}
+
+// processAudit writes the audit log as a tab separated text file. It is
+// protected by the credentials of the admin page.
+func processAudit(w http.ResponseWriter, r *http.Request, cfg *slidesConfig, credentials string) {
+	if !cfg.auditCfg.enabled() || credentials == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if !requireAdmin(w, r, credentials) {
+		return
+	}
+	w.Header().Set("Content-Type", "text/tab-separated-values; charset=utf-8")
+	io.WriteString(w, "time\tzid\ttitle\toutput\tclient\n")
+	for _, entry := range cfg.audit.current(cfg.auditCfg.retention) {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", entry.time.Format(time.RFC3339), entry.zid,
+			strings.ReplaceAll(entry.title, "\t", " "), entry.output, entry.client)
+	}
+}
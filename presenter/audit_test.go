@@ -0,0 +1,36 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRecordAuditPreview(t *testing.T) {
+	cfg := &slidesConfig{auditCfg: auditConfig{retention: time.Hour}, audit: &auditLog{}}
+	r := httptest.NewRequest("GET", "/20220101000000.reveal", nil)
+	cfg.recordAudit(r, "20220101000000", "Title", "show")
+	r = r.WithContext(context.WithValue(r.Context(), previewKey{}, true))
+	cfg.recordAudit(r, "20220101000000", "Title", "show")
+	entries := cfg.audit.entries
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, but got %d", len(entries))
+	}
+	if got := entries[0].output; got != "show" {
+		t.Errorf("expected output %q, but got %q", "show", got)
+	}
+	if got := entries[1].output; got != "show-preview" {
+		t.Errorf("expected output %q, but got %q", "show-preview", got)
+	}
+}
@@ -0,0 +1,130 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"zettelstore.de/c/api"
+	"zettelstore.de/c/client"
+	"zettelstore.de/contrib/presenter/deck"
+)
+
+const zidBranding = api.ZettelID("00009000001010")
+
+// Constants for metadata keys of the branding zettel.
+const (
+	KeyLogo           = "logo"
+	KeyPrimaryColor   = "primary-color"
+	KeySecondaryColor = "secondary-color"
+	KeyFontFamily     = "font-family"
+	KeyFooter         = "footer"
+)
+
+// branding stores data to adapt the presenter output to a corporate design.
+type branding struct {
+	logo           api.ZettelID
+	primaryColor   string
+	secondaryColor string
+	fontFamily     string
+	footer         string
+}
+
+func getBranding(ctx context.Context, c *zsClient) branding {
+	m, err := c.GetMeta(ctx, zidBranding)
+	if err != nil {
+		var cerr *client.Error
+		if !errors.As(err, &cerr) || cerr.StatusCode != http.StatusNotFound {
+			log.Println("BRND", err)
+		}
+		return branding{}
+	}
+	result := branding{
+		primaryColor:   cssValue(m[KeyPrimaryColor]),
+		secondaryColor: cssValue(m[KeySecondaryColor]),
+		fontFamily:     cssValue(m[KeyFontFamily]),
+		footer:         m[KeyFooter],
+	}
+	if zid := api.ZettelID(m[KeyLogo]); zid.IsValid() {
+		result.logo = zid
+	}
+	return result
+}
+
+// cssValue returns the given value, if it is safe to be used as a CSS
+// property value. Otherwise the empty string is returned.
+func cssValue(val string) string {
+	if strings.ContainsAny(val, "<>{};\\") {
+		return ""
+	}
+	return strings.TrimSpace(val)
+}
+
+// writeCSS writes the branding specific CSS definitions. Reveal.js is styled
+// by its CSS variables, all other output types by some element selectors.
+func (b *branding) writeCSS(w io.Writer, output string) {
+	if b.primaryColor == "" && b.secondaryColor == "" && b.fontFamily == "" {
+		return
+	}
+	io.WriteString(w, "<style type=\"text/css\">\n")
+	if output == deck.SlideRoleShow {
+		io.WriteString(w, ":root {")
+		if b.primaryColor != "" {
+			fmt.Fprintf(w, " --r-heading-color: %s;", b.primaryColor)
+		}
+		if b.secondaryColor != "" {
+			fmt.Fprintf(w, " --r-link-color: %s;", b.secondaryColor)
+		}
+		if b.fontFamily != "" {
+			fmt.Fprintf(w, " --r-main-font: %s; --r-heading-font: %s;", b.fontFamily, b.fontFamily)
+		}
+		io.WriteString(w, " }\n")
+	} else {
+		if b.primaryColor != "" {
+			fmt.Fprintf(w, "h1, h2, h3, h4, h5, h6 { color: %s }\n", b.primaryColor)
+		}
+		if b.secondaryColor != "" {
+			fmt.Fprintf(w, "a { color: %s }\n", b.secondaryColor)
+		}
+		if b.fontFamily != "" {
+			fmt.Fprintf(w, "body { font-family: %s }\n", b.fontFamily)
+		}
+	}
+	if b.logo != api.InvalidZID {
+		io.WriteString(w, "img.logo { max-height: 4rem }\n")
+	}
+	io.WriteString(w, "</style>\n")
+}
+
+// writeLogo writes the logo image, if there is one. The function imageURL
+// returns the URL of the image, e.g. contentURL.
+func (b *branding) writeLogo(w io.Writer, imageURL func(api.ZettelID) string) {
+	if b.logo != api.InvalidZID {
+		fmt.Fprintf(w, "<p><img class=\"logo\" src=\"%s\" alt=\"\"></p>\n", imageURL(b.logo))
+	}
+}
+
+// contentURL returns the URL of the content of the given zettel, e.g. an
+// image.
+func contentURL(zid api.ZettelID) string { return string(zid) + ".content" }
+
+func (b *branding) writeFooter(w io.Writer) {
+	if b.footer != "" {
+		fmt.Fprintf(w, "<p class=\"footer\"><small>%s</small></p>\n", html.EscapeString(b.footer))
+	}
+}
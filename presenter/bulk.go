@@ -0,0 +1,82 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"sync"
+
+	"codeberg.org/t73fde/sxpf"
+	"zettelstore.de/c/api"
+	"zettelstore.de/contrib/presenter/deck"
+)
+
+// maxParallelRequests limits the number of concurrent requests to Zettelstore
+// while the zettel of a slide set are retrieved.
+const maxParallelRequests = 4
+
+type zettelResult struct {
+	val sxpf.Value
+	err error
+}
+
+// prefetchZettel retrieves all zettel of the given list concurrently, each
+// zettel only once, even if it occurs more than once. The returned function
+// returns the prefetched zettel, and retrieves all other zettel, e.g. zettel
+// that are referenced by a slide, by calling the given function.
+//
+// Zettelstore returns the metadata of all slides with the zettel order, but
+// it has no API to retrieve the evaluated content of more than one zettel.
+// Therefore, one request per slide is still needed, but they do not wait for
+// each other. To reduce the number of requests, the list should only contain
+// the slides that are needed, see slidesForRole.
+// slidesForRole returns the slides of the list that are needed to render the
+// given role, according to the metadata of the list. Only the slide show
+// drops slides: the handout numbers the slides of the slide show, and other
+// outputs use all slides.
+func slidesForRole(l []api.ZidMetaJSON, role string) []api.ZidMetaJSON {
+	if role != deck.SlideRoleShow {
+		return l
+	}
+	result := make([]api.ZidMetaJSON, 0, len(l))
+	for _, zm := range l {
+		if sr := zm.Meta[deck.KeySlideRole]; sr == "" || sr == role {
+			result = append(result, zm)
+		}
+	}
+	return result
+}
+
+func prefetchZettel(l []api.ZidMetaJSON, sGetZettel deck.SGetZettelFunc) deck.SGetZettelFunc {
+	results := make(map[api.ZettelID]*zettelResult, len(l))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxParallelRequests)
+	for _, zm := range l {
+		zid := zm.ID
+		if _, found := results[zid]; found {
+			continue
+		}
+		res := &zettelResult{}
+		results[zid] = res
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			res.val, res.err = sGetZettel(zid)
+		}()
+	}
+	wg.Wait()
+	return func(zid api.ZettelID) (sxpf.Value, error) {
+		if res, found := results[zid]; found {
+			return res.val, res.err
+		}
+		return sGetZettel(zid)
+	}
+}
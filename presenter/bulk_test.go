@@ -0,0 +1,83 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"codeberg.org/t73fde/sxpf"
+	"zettelstore.de/c/api"
+	"zettelstore.de/contrib/presenter/deck"
+)
+
+// makeSlideList returns a zettel list, where every slide has the given slide
+// role, separated by ",".
+func makeSlideList(roles string) []api.ZidMetaJSON {
+	var result []api.ZidMetaJSON
+	for i, role := range strings.Split(roles, ",") {
+		zid := api.ZettelID("2022010100000" + string(rune('0'+i)))
+		result = append(result, api.ZidMetaJSON{ID: zid, Meta: map[string]string{deck.KeySlideRole: role}})
+	}
+	return result
+}
+
+func TestSlidesForRole(t *testing.T) {
+	testcases := []struct {
+		roles string
+		role  string
+		exp   string
+	}{
+		{",show,handout", deck.SlideRoleShow, ",show"},
+		{",show,handout", deck.SlideRoleHandout, ",show,handout"},
+		{",show,handout", "check", ",show,handout"},
+		{"handout,handout", deck.SlideRoleShow, ""},
+	}
+	for _, tc := range testcases {
+		var got []string
+		for _, zm := range slidesForRole(makeSlideList(tc.roles), tc.role) {
+			got = append(got, zm.Meta[deck.KeySlideRole])
+		}
+		if s := strings.Join(got, ","); s != tc.exp {
+			t.Errorf("%q/%s: expected %q, but got %q", tc.roles, tc.role, tc.exp, s)
+		}
+	}
+}
+
+func TestPrefetchZettel(t *testing.T) {
+	l := makeSlideList(",,")
+	l = append(l, l[0]) // same slide twice
+	var mx sync.Mutex
+	calls := map[api.ZettelID]int{}
+	get := func(zid api.ZettelID) (sxpf.Value, error) {
+		mx.Lock()
+		calls[zid]++
+		mx.Unlock()
+		return sxpf.NewString(string(zid)), nil
+	}
+	prefetched := prefetchZettel(l, get)
+	for _, zm := range l {
+		if calls[zm.ID] != 1 {
+			t.Errorf("%s retrieved %d times while prefetching", zm.ID, calls[zm.ID])
+		}
+		val, err := prefetched(zm.ID)
+		if err != nil || val.(*sxpf.String).GetValue() != string(zm.ID) {
+			t.Errorf("%s: unexpected result %v/%v", zm.ID, val, err)
+		}
+	}
+	if _, err := prefetched("20220202000000"); err != nil || calls["20220202000000"] != 1 {
+		t.Errorf("other zettel is not retrieved: %v", err)
+	}
+	if len(calls) != 4 || calls[l[0].ID] != 1 {
+		t.Errorf("unexpected calls %v", calls)
+	}
+}
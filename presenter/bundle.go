@@ -0,0 +1,214 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"zettelstore.de/c/api"
+	"zettelstore.de/contrib/presenter/deck"
+)
+
+// OutputBundle is the output type of a slide show that is exported as a zip
+// file, to be served by any static web server.
+const OutputBundle = "bundle"
+
+// bundle collects all files of a standalone slide show. The files are placed
+// like in a reveal.js project: the slide show is "index.html", reveal.js is
+// below "dist/" and its plugins below "plugin/". Images are stored below
+// "images/", fonts below "fonts/".
+//
+// All methods can be called with a nil bundle, i.e. if the slide show is not
+// exported. Then the files of presenter are referenced.
+type bundle struct {
+	ctx   context.Context
+	c     *zsClient
+	files map[string][]byte
+	fonts map[api.ZettelID]string // path of every font file
+}
+
+// assetURL returns the URL of a reveal.js file.
+func (b *bundle) assetURL(path string) string {
+	if b == nil {
+		return assetPrefix + path
+	}
+	return bundleAssetPath(path)
+}
+
+func bundleAssetPath(path string) string {
+	if strings.HasPrefix(path, "plugin/") {
+		return path
+	}
+	return "dist/" + path
+}
+
+// bundleImageURL returns the URL of an image of the slide set.
+func bundleImageURL(zid api.ZettelID, syntax string) string {
+	return "images/" + string(zid) + "." + syntax
+}
+
+// imageURL returns the URL of an image that is not part of the slide set,
+// e.g. a logo. The image is retrieved and stored in the bundle.
+func (b *bundle) imageURL(zid api.ZettelID) string {
+	if b == nil {
+		return contentURL(zid)
+	}
+	m, err := b.c.GetMeta(b.ctx, zid)
+	if err != nil {
+		log.Println("BNDL", zid, err)
+		return contentURL(zid)
+	}
+	data, err := b.c.GetZettel(b.ctx, zid, api.PartContent)
+	if err != nil {
+		log.Println("BNDL", zid, err)
+		return contentURL(zid)
+	}
+	path := bundleImageURL(zid, m[api.KeySyntax])
+	b.files[path] = data
+	return path
+}
+
+// rewriteCSS changes the URLs of all fonts, that are retrieved by addFonts.
+func (b *bundle) rewriteCSS(css []byte) []byte {
+	if b == nil {
+		return css
+	}
+	for zid, path := range b.fonts {
+		css = bytes.ReplaceAll(css, []byte("url(\""+string(zid)+".font\")"), []byte("url(\""+path+"\")"))
+	}
+	return css
+}
+
+// addFonts retrieves all given font zettel.
+func (b *bundle) addFonts(zids []api.ZettelID) {
+	for _, zid := range zids {
+		m, err := b.c.GetMeta(b.ctx, zid)
+		if err != nil {
+			log.Println("BNDL", zid, err)
+			continue
+		}
+		data, err := b.c.GetZettel(b.ctx, zid, api.PartContent)
+		if err != nil {
+			log.Println("BNDL", zid, err)
+			continue
+		}
+		path := "fonts/" + string(zid) + "." + m[api.KeySyntax]
+		b.files[path] = data
+		b.fonts[zid] = path
+	}
+}
+
+// addAssets stores all embedded reveal.js files.
+func (b *bundle) addAssets() error {
+	return fs.WalkDir(revealjs, "revealjs", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(revealjs, path)
+		if err != nil {
+			return err
+		}
+		b.files[bundleAssetPath(strings.TrimPrefix(path, "revealjs/"))] = data
+		return nil
+	})
+}
+
+// writeZip writes all files of the bundle as a zip file, sorted by their
+// path.
+func (b *bundle) writeZip(w http.ResponseWriter, name string) error {
+	paths := make([]string, 0, len(b.files))
+	for path := range b.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, path := range paths {
+		fw, err := zw.Create(name + "/" + path)
+		if err != nil {
+			return err
+		}
+		if _, err = fw.Write(b.files[path]); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.zip\"", name))
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// bundleRenderer exports a slide show, together with all needed files, as a
+// zip file.
+type bundleRenderer struct {
+	revealRenderer
+	mirror bool // download external resources
+}
+
+// processMirror sends the bundle of a slide set, together with all external
+// resources. Since zettel presenter downloads them, this is only available
+// from the admin page.
+func processMirror(w http.ResponseWriter, r *http.Request, cfg *slidesConfig, zid api.ZettelID) {
+	if !zid.IsValid() {
+		http.Error(w, fmt.Sprintf("Invalid zettel identifier %q", zid), http.StatusBadRequest)
+		return
+	}
+	if !cfg.allow.allows(r.Context(), cfg.c, zid) {
+		reportNotAllowed(w, zid)
+		return
+	}
+	processSlideSet(w, r, cfg, zid, &bundleRenderer{mirror: true})
+}
+
+func (*bundleRenderer) Role() string { return OutputBundle }
+func (br *bundleRenderer) Prepare(ctx context.Context, cfg *slidesConfig) {
+	br.revealRenderer.Prepare(ctx, cfg)
+	br.bundle = &bundle{
+		ctx:   ctx,
+		c:     cfg.c,
+		files: make(map[string][]byte),
+		fonts: make(map[api.ZettelID]string),
+	}
+}
+func (br *bundleRenderer) Render(w http.ResponseWriter, slides *deck.SlideSet, author string) {
+	b := br.bundle
+	if err := b.addAssets(); err != nil {
+		http.Error(w, fmt.Sprintf("Unable to read reveal.js files: %v", err), http.StatusInternalServerError)
+		return
+	}
+	b.addFonts(slides.FontZids())
+	for _, zid := range slides.Images() {
+		if img, found := slides.GetImage(zid); found {
+			b.files[bundleImageURL(zid, img.Syntax)] = img.Data
+		}
+	}
+	rb := responseBuffer{header: make(http.Header)}
+	br.revealRenderer.Render(&rb, slides, author)
+	doc := rb.buf.Bytes()
+	if br.mirror {
+		doc = b.mirrorExternal(doc)
+	}
+	b.files["index.html"] = doc
+	if err := b.writeZip(w, string(slides.Zid())); err != nil {
+		log.Println("BNDL", slides.Zid(), err)
+	}
+}
@@ -0,0 +1,75 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"zettelstore.de/c/api"
+	"zettelstore.de/contrib/presenter/deck"
+)
+
+// addVideoTracks retrieves the caption tracks of all videos of the slide set.
+// The metadata key "captions" of a video zettel lists the WebVTT zettel, whose
+// language and title are used as the language and label of a track.
+func addVideoTracks(ctx context.Context, cfg *slidesConfig, slides *deck.SlideSet) {
+	zids := slides.Videos()
+	tracks := make([][]deck.Track, len(zids))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxParallelRequests)
+	for i, zid := range zids {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, zid api.ZettelID) {
+			defer func() { <-sem; wg.Done() }()
+			m, err := cfg.c.GetMeta(ctx, zid)
+			if err != nil {
+				log.Println("CAPT", zid, err)
+				return
+			}
+			for _, val := range strings.Fields(m[deck.KeyCaptions]) {
+				trackZid := api.ZettelID(val)
+				if !trackZid.IsValid() {
+					continue
+				}
+				tm, err := cfg.c.GetMeta(ctx, trackZid)
+				if err != nil {
+					log.Println("CAPT", trackZid, err)
+					continue
+				}
+				if syntax := tm[api.KeySyntax]; syntax != deck.SyntaxWebVTT {
+					log.Println("CAPT", trackZid, "syntax", syntax)
+					continue
+				}
+				tracks[i] = append(tracks[i], deck.Track{Zid: trackZid, Lang: tm[api.KeyLang], Label: tm[api.KeyTitle]})
+			}
+		}(i, zid)
+	}
+	wg.Wait()
+	for i, zid := range zids {
+		if len(tracks[i]) > 0 {
+			slides.SetVideoTracks(zid, tracks[i])
+		}
+	}
+}
+
+// processVTT delivers the content of a WebVTT zettel. Browsers accept caption
+// tracks only with the appropriate content type.
+func processVTT(w http.ResponseWriter, r *http.Request, c *zsClient, zid api.ZettelID) {
+	if content := retrieveContent(w, r, c, zid); len(content) > 0 {
+		w.Header().Set("Content-Type", "text/vtt; charset=utf-8")
+		w.Write(content)
+	}
+}
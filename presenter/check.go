@@ -0,0 +1,163 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"codeberg.org/t73fde/sxpf"
+	"zettelstore.de/c/api"
+	"zettelstore.de/c/sexpr"
+	"zettelstore.de/contrib/presenter/deck"
+)
+
+// Parameter of checking external links.
+const (
+	headTimeout       = 10 * time.Second // maximum time to wait for an external URL
+	maxParallelChecks = 4                // maximum number of concurrent checks
+)
+
+func processCheck(w http.ResponseWriter, r *http.Request, cfg *slidesConfig, zid api.ZettelID) {
+	ctx := r.Context()
+	o, err := cfg.c.GetZettelOrder(ctx, zid)
+	if err != nil {
+		reportRetrieveError(w, zid, err, "zettel")
+		return
+	}
+	if cfg.limits.tooManySlides(len(o.List)) {
+		cfg.reportTooManySlides(w, len(o.List))
+		return
+	}
+	sMeta, err := cfg.c.GetEvaluatedSexpr(ctx, zid, api.PartMeta)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Unable to read zettel %s: %v", zid, err), http.StatusBadRequest)
+		return
+	}
+	slides := deck.New(zid, sexpr.MakeMeta(sMeta))
+	getZettel := func(zid api.ZettelID) ([]byte, error) { return cfg.c.GetZettel(ctx, zid, api.PartContent) }
+	sGetZettel := func(zid api.ZettelID) (sxpf.Value, error) {
+		return cfg.c.GetEvaluatedSexpr(ctx, zid, api.PartZettel)
+	}
+	getZettel, sGetZettel = cfg.disk.wrap(ctx, cfg.c, o.List, getZettel, sGetZettel)
+	setupSlideSet(slides, cfg.author, o.List, getZettel, sGetZettel)
+	addImageTitles(ctx, cfg, slides)
+	addVideoTracks(ctx, cfg, slides)
+	if cssZid := slides.CSSZid(); cssZid != api.InvalidZID {
+		if data, err := getZettel(cssZid); err == nil {
+			slides.AddCSS(data)
+		}
+	}
+
+	_, checkHead := r.URL.Query()["head"]
+	var linkErrors []string
+	if checkHead {
+		linkErrors = checkExternalLinks(ctx, slides.ExtLinks())
+	}
+
+	lang := slides.Lang()
+	title := translate(lang, "Check")
+	page := cfg.newHTMLPage(OutputZettel, lang, "", cfg.getTemplate(ctx, OutputZettel))
+	writeHTMLHeader(w, page)
+	fmt.Fprintf(w, "<title>%s</title>\n", html.EscapeString(title))
+	writeHTMLBody(w, page)
+	writeNavigation(w, lang, navItem{string(zid), slides.HTMLTitle()}, navItem{"", html.EscapeString(title)})
+	fmt.Fprintf(w, "<h1>%s: %s</h1>\n", html.EscapeString(title), slides.HTMLTitle())
+
+	fmt.Fprintf(w, "<h2>%s</h2>\n", translate(lang, "Problems"))
+	if len(slides.Issues()) == 0 {
+		fmt.Fprintf(w, "<p>%s</p>\n", translate(lang, "No problems found."))
+	} else {
+		fmt.Fprintf(w, "<table>\n<tr><th>%s</th><th>%s</th><th>%s</th><th>%s</th></tr>\n",
+			translate(lang, "Problem"), translate(lang, "Zettel"), translate(lang, "Reference"), translate(lang, "Message"))
+		for _, issue := range slides.Issues() {
+			fmt.Fprintf(w, "<tr><td>%s</td><td><a href=\"%s\">%s</a></td><td>%s</td><td>%s</td></tr>\n",
+				translate(lang, issue.Kind), issue.Zid, issue.Zid, issue.Ref, html.EscapeString(issue.Msg))
+		}
+		io.WriteString(w, "</table>\n")
+	}
+
+	writeA11yIssues(w, lang, auditAccessibility(slides, cfg, cfg.getUserCSS(ctx, deck.SlideRoleShow), lang))
+	writeLintIssues(w, lang, lintSlides(slides, cfg.lint, lang))
+
+	fmt.Fprintf(w, "<h2>%s</h2>\n", translate(lang, "External links"))
+	if len(slides.ExtLinks()) == 0 {
+		fmt.Fprintf(w, "<p>%s</p>\n", translate(lang, "No external links found."))
+	} else {
+		io.WriteString(w, "<ul>\n")
+		for i, link := range slides.ExtLinks() {
+			fmt.Fprintf(w, "<li><a href=\"%s\">%s</a>: <a href=\"%s\">%s</a>",
+				link.Zid, link.Zid, html.EscapeString(link.URL), html.EscapeString(link.URL))
+			if checkHead {
+				if msg := linkErrors[i]; msg != "" {
+					fmt.Fprintf(w, " &mdash; <strong>%s</strong>", html.EscapeString(translate(lang, msg)))
+				} else {
+					io.WriteString(w, " &mdash; OK")
+				}
+			}
+			io.WriteString(w, "</li>\n")
+		}
+		io.WriteString(w, "</ul>\n")
+		if !checkHead {
+			fmt.Fprintf(w, "<p><a href=\"%s.check?head\">%s</a></p>\n", zid, translate(lang, "Check external links"))
+		}
+	}
+	writeHTMLFooter(w, page, "")
+}
+
+// checkExternalLinks sends a HEAD request to all external links. The result
+// contains an error message for every link that is not reachable. Since the
+// page is public, links to internal hosts are not checked, like with external
+// slide content, and errors are not described in detail.
+func checkExternalLinks(ctx context.Context, links []deck.Link) []string {
+	result := make([]string, len(links))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxParallelChecks)
+	for i, link := range links {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, url string) {
+			defer func() { <-sem; wg.Done() }()
+			result[i] = checkExternalLink(ctx, url)
+		}(i, link.URL)
+	}
+	wg.Wait()
+	return result
+}
+
+// checkExternalLink sends a HEAD request to the given URL and returns an
+// error message, if it is not reachable.
+func checkExternalLink(ctx context.Context, url string) string {
+	ctx, cancel := context.WithTimeout(ctx, headTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil || checkExternalURL(req.URL.Scheme, req.URL.Hostname()) != nil {
+		return "not checked"
+	}
+	resp, err := externalClient.Do(req)
+	if err != nil {
+		if errors.Is(err, errInternalAddress) {
+			return "not checked"
+		}
+		return "not reachable"
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return resp.Status
+	}
+	return ""
+}
@@ -0,0 +1,37 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"zettelstore.de/contrib/presenter/deck"
+)
+
+func TestCheckExternalLinks(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	links := []deck.Link{
+		{URL: srv.URL},                      // loopback address
+		{URL: "http://169.254.169.254/"},    // link-local address
+		{URL: "http://localhost:1/"},        // internal host name
+		{URL: "ftp://example.com/file.txt"}, // other scheme
+		{URL: "http://%zz"},                 // invalid URL
+	}
+	for i, msg := range checkExternalLinks(context.Background(), links) {
+		if msg != "not checked" {
+			t.Errorf("%s: expected %q, but got %q", links[i].URL, "not checked", msg)
+		}
+	}
+}
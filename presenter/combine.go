@@ -0,0 +1,134 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"codeberg.org/t73fde/sxpf"
+	"zettelstore.de/c/api"
+	"zettelstore.de/c/sexpr"
+	"zettelstore.de/c/text"
+	"zettelstore.de/contrib/presenter/deck"
+)
+
+// combinePath is the path to combine several slide sets into one slide show
+// ("/combine" or "/combine.reveal"), one handout ("/combine.html"), or one
+// PDF document ("/combine.pdf").
+const combinePath = "/combine"
+
+// maxCombinedSlideSets limits the number of slide sets that are combined.
+const maxCombinedSlideSets = 20
+
+// processCombine concatenates the slide sets given by the query parameter
+// "zids", e.g. "zids=A,B,C". Every slide set starts with a divider slide,
+// which shows its title. The title of the combined slide show is given by
+// the query parameter "title", or it is the title of the first slide set.
+// All other metadata, e.g. the CSS definitions, is taken from the first slide
+// set.
+func processCombine(w http.ResponseWriter, r *http.Request, cfg *slidesConfig) {
+	var ren renderer
+	pdf := false
+	switch strings.TrimPrefix(r.URL.Path, combinePath) {
+	case "", ".reveal":
+		// The remaining slides of a combined slide show cannot be retrieved
+		// later.
+		ren = &revealRenderer{eager: true}
+	case ".html":
+		ren = newHandoutRenderer(r)
+	case "." + OutputPDF:
+		pdf = true
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	query := r.URL.Query()
+	var zids []api.ZettelID
+	for _, val := range strings.Split(query.Get("zids"), ",") {
+		if val = strings.TrimSpace(val); val == "" {
+			continue
+		}
+		zid := api.ZettelID(val)
+		if !zid.IsValid() {
+			http.Error(w, fmt.Sprintf("Invalid zettel identifier %q", val), http.StatusBadRequest)
+			return
+		}
+		zids = append(zids, zid)
+	}
+	if len(zids) == 0 {
+		http.Error(w, "No slide sets given, use ?zids=ZID1,ZID2,...", http.StatusBadRequest)
+		return
+	}
+	if len(zids) > maxCombinedSlideSets {
+		http.Error(w, fmt.Sprintf("At most %d slide sets can be combined", maxCombinedSlideSets), http.StatusBadRequest)
+		return
+	}
+	if pdf {
+		// The combined slide show checks all slide sets, when it is
+		// retrieved by the browser.
+		printSlideShow(w, r, cfg, string(zids[0]), combinePath+".reveal")
+		return
+	}
+
+	ctx := r.Context()
+	parts := make([]*deck.SlideSet, len(zids))
+	lists := make([][]api.ZidMetaJSON, len(zids))
+	var all []api.ZidMetaJSON
+	for i, zid := range zids {
+		if !cfg.allow.allows(ctx, cfg.c, zid) {
+			reportNotAllowed(w, zid)
+			return
+		}
+		o, err := cfg.c.GetZettelOrder(ctx, zid)
+		if err != nil {
+			reportRetrieveError(w, zid, err, "zettel")
+			return
+		}
+		sMeta, err := cfg.c.GetEvaluatedSexpr(ctx, zid, api.PartMeta)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Unable to read zettel %s: %v", zid, err), http.StatusBadRequest)
+			return
+		}
+		parts[i] = deck.NewMeta(zid, sexpr.MakeMeta(sMeta))
+		lists[i] = o.List
+		all = append(all, o.List...)
+	}
+	if cfg.limits.tooManySlides(len(all) + len(zids)) {
+		cfg.reportTooManySlides(w, len(all)+len(zids))
+		return
+	}
+
+	slides := deck.NewMeta(zids[0], parts[0].Meta())
+	if title := strings.TrimSpace(query.Get("title")); title != "" {
+		slides.SetTitle(sxpf.NewPair(sxpf.NewPair(sexpr.SymText, sxpf.NewPair(sxpf.NewString(title), nil)), nil))
+	}
+	slides.SetPreview(isPreview(ctx))
+	getZettel := func(zid api.ZettelID) ([]byte, error) { return cfg.c.GetZettel(ctx, zid, api.PartContent) }
+	sGetZettel := func(zid api.ZettelID) (sxpf.Value, error) {
+		return cfg.c.GetEvaluatedSexpr(ctx, zid, api.PartZettel)
+	}
+	getZettel, sGetZettel = cfg.disk.wrap(ctx, cfg.c, all, getZettel, sGetZettel)
+	sGetZettel = prefetchZettel(all, sGetZettel)
+	addExternalContent(ctx, cfg, slides, all)
+	for i, part := range parts {
+		slides.AddDivider(part)
+		for _, sl := range lists[i] {
+			slides.AddSlide(sl.ID, sGetZettel)
+		}
+	}
+	slides.Completion(getZettel, sGetZettel)
+	addAgenda(slides)
+	addClosing(slides, slides.Author(cfg.author))
+	renderSlides(w, r, cfg, slides, getZettel, ren)
+	cfg.recordAudit(r, zids[0], text.EvaluateInlineString(slides.Title()), ren.Role())
+}
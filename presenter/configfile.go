@@ -0,0 +1,127 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// restartFlags are the command line options that cannot be changed while
+// zettel presenter is running, because the listener would be dropped.
+var restartFlags = []string{"l", "tls-cert", "tls-key", "http3", "debug-addr", "refresh", "config", "tenant", "snapshot", "otlp", "admin", "preview", "history", "assets", "shared-cache"}
+
+// flagFile manages a file with command line options. Every line of the file
+// contains the name of an option without the leading dash, optionally
+// followed by "=", and its value. Empty lines and lines starting with "#" are
+// ignored. Options given on the command line take precedence.
+type flagFile struct {
+	path     string
+	fs       *flag.FlagSet
+	explicit map[string]bool // options given on the command line
+}
+
+// newFlagFile must be called after the command line was parsed.
+func newFlagFile(path string, fs *flag.FlagSet) *flagFile {
+	if path == "" {
+		return nil
+	}
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	return &flagFile{path: path, fs: fs, explicit: explicit}
+}
+
+// flagValue is an option of a file.
+type flagValue struct {
+	name string
+	val  string
+}
+
+// readFlagFile parses the options of the given file. An option may occur more
+// than once, e.g. "tenant".
+func readFlagFile(path string) ([]flagValue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var result []flagValue
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for lineNo := 1; sc.Scan(); lineNo++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		pos := strings.IndexAny(line, "= \t")
+		if pos < 0 {
+			result = append(result, flagValue{strings.TrimPrefix(line, "-"), "true"})
+			continue
+		}
+		name := strings.TrimPrefix(strings.TrimSpace(line[:pos]), "-")
+		val := strings.TrimSpace(line[pos+1:])
+		if val != "" && val[0] == '=' {
+			val = strings.TrimSpace(val[1:])
+		}
+		if name == "" {
+			return nil, fmt.Errorf("%s:%d: missing option name", path, lineNo)
+		}
+		result = append(result, flagValue{name, val})
+	}
+	return result, sc.Err()
+}
+
+// apply sets all options of the file that were not given on the command
+// line. On startup, all options are set. Otherwise, changes of options that
+// need a restart are logged and ignored.
+func (ff *flagFile) apply(startup bool) error {
+	if ff == nil {
+		return nil
+	}
+	values, err := readFlagFile(ff.path)
+	if err != nil {
+		return err
+	}
+	restartVals := make(map[string][]string)
+	for _, fv := range values {
+		if ff.explicit[fv.name] {
+			continue
+		}
+		if ff.fs.Lookup(fv.name) == nil {
+			return fmt.Errorf("%s: unknown option %q", ff.path, fv.name)
+		}
+		if !startup && isRestartFlag(fv.name) {
+			restartVals[fv.name] = append(restartVals[fv.name], fv.val)
+			continue
+		}
+		if err = ff.fs.Set(fv.name, fv.val); err != nil {
+			return fmt.Errorf("%s: option %q: %w", ff.path, fv.name, err)
+		}
+	}
+	for name, vals := range restartVals {
+		if ff.fs.Lookup(name).Value.String() != strings.Join(vals, ",") {
+			log.Printf("CONF option -%s changes after restart only", name)
+		}
+	}
+	return nil
+}
+
+func isRestartFlag(name string) bool {
+	for _, n := range restartFlags {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
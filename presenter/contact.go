@@ -0,0 +1,113 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"net/http"
+
+	"zettelstore.de/contrib/presenter/deck"
+	"zettelstore.de/contrib/presenter/render"
+)
+
+// OutputContact is the output type of the contact sheet.
+const OutputContact = "contact"
+
+// contactRenderer produces a contact sheet: a grid of small images of all
+// slides of the slide show, together with their number and title. Every image
+// is the slide, rendered in its original size and scaled down by CSS.
+type contactRenderer struct {
+	cfg  *slidesConfig
+	tmpl *template.Template
+}
+
+func (*contactRenderer) Role() string { return OutputContact }
+func (cr *contactRenderer) Prepare(ctx context.Context, cfg *slidesConfig) {
+	cr.cfg = cfg
+	cr.tmpl = cfg.getTemplate(ctx, OutputContact)
+}
+func (cr *contactRenderer) Render(w http.ResponseWriter, slides *deck.SlideSet, author string) {
+	lang := slides.Lang()
+	page := cr.cfg.newHTMLPage(OutputContact, lang, "", cr.tmpl)
+	writeHTMLHeader(w, page)
+	io.WriteString(w, `<style type="text/css">
+div.contact-sheet { display: flex; flex-wrap: wrap; gap: 1rem }
+div.contact-item { width: 384px; break-inside: avoid; page-break-inside: avoid }
+div.contact-item a { color: inherit; text-decoration: none }
+div.contact-frame { width: 384px; height: 205px; overflow: hidden; border: 1px solid lightgray; background: white }
+div.contact-slide { width: 1920px; height: 1024px; transform: scale(.2); transform-origin: 0 0; font-size: 42px; text-align: center; padding: 40px; box-sizing: border-box }
+div.contact-caption { font-size: .9rem; margin-top: .25rem; white-space: nowrap; overflow: hidden; text-overflow: ellipsis }
+div.contact-caption span.slide-no { color: gray; margin-right: .5em }
+@media print { nav.breadcrumb { display: none } }
+</style>
+`)
+	cr.cfg.branding.writeCSS(w, OutputContact)
+	title := slides.Title()
+	writeTitle(w, title)
+	writeHTMLBody(w, page)
+	writeNavigation(w, lang,
+		navItem{string(slides.Zid()), slides.HTMLTitle()},
+		navItem{"", translate(lang, "Contact sheet")})
+
+	io.WriteString(w, "<div class=\"contact-sheet\">\n")
+	offset := 1
+	if !title.IsEmpty() {
+		offset++
+		htmlTitle := render.EvaluateInline(nil, title)
+		writeContactStart(w)
+		fmt.Fprintf(w, "<h1>%s</h1>\n", htmlTitle)
+		if subtitle := slides.Subtitle(); !subtitle.IsEmpty() {
+			fmt.Fprintf(w, "<h2>%s</h2>\n", render.EvaluateInline(nil, subtitle))
+		}
+		if author != "" {
+			fmt.Fprintf(w, "<p>%s</p>\n", html.EscapeString(author))
+		}
+		writeContactEnd(w, slides, 1, htmlTitle)
+	}
+	he := render.New(w, slides, deck.SlideRoleShow, cr.cfg.verbatim, cr.cfg.headingOffset(deck.SlideRoleShow), cr.cfg.embedding[deck.SlideRoleShow], cr.cfg.zettelLinks(deck.SlideRoleShow))
+	he.SetEndnoteStyle(cr.cfg.noteStyles[deck.SlideRoleShow])
+	he.SetHideSpeaker(true)
+	cr.cfg.setTypography(he, slides.Lang())
+	for si := slides.Slides(deck.SlideRoleShow, offset); si != nil; si = si.Next() {
+		he.SetCurrentSlide(si)
+		for sub := si.Child(); sub != nil; sub = sub.Next() {
+			he.SetUnique(render.SlideUnique(sub))
+			htmlTitle := render.EvaluateInline(he, sub.Slide.Title())
+			writeContactStart(w)
+			markZettel(w, sub.Slide.Zid())
+			if htmlTitle != "" {
+				fmt.Fprintf(w, "<h1>%s</h1>\n", htmlTitle)
+			}
+			he.EvaluateBlock(sub.Slide.Content())
+			he.WriteEndnotes()
+			writeContactEnd(w, slides, sub.SlideNo, htmlTitle)
+		}
+	}
+	io.WriteString(w, "</div>\n")
+	he.LogUnknown(slides.Zid())
+	writeHTMLFooter(w, page, cr.cfg.verbatim.Scripts(slides.Syntaxes()))
+}
+
+func writeContactStart(w io.Writer) {
+	io.WriteString(w, "<div class=\"contact-item\">\n<div class=\"contact-frame\"><div class=\"contact-slide\">\n")
+}
+
+// writeContactEnd writes the caption of a slide, which links to the slide in
+// the slide show. The slide itself cannot be a link, because it may contain
+// links.
+func writeContactEnd(w io.Writer, slides *deck.SlideSet, slideNo int, htmlTitle string) {
+	fmt.Fprintf(w, "</div></div>\n<div class=\"contact-caption\"><a href=\"%s.reveal#/(%d)\"><span class=\"slide-no\">%d</span>%s</a></div>\n</div>\n",
+		slides.Zid(), slideNo, slideNo, htmlTitle)
+}
@@ -0,0 +1,101 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"zettelstore.de/contrib/presenter/deck"
+)
+
+// The high-contrast theme is enabled by the class "zs-contrast" of the html
+// element. The choice of the user is stored in the local storage of the
+// browser, so that it applies to all slide shows and handouts.
+
+const contrastStorageKey = "zs-contrast"
+
+// contrastShowCSS overrides the variables of the reveal.js theme.
+const contrastShowCSS = `html.zs-contrast {
+  --r-background-color: #000;
+  --r-main-color: #fff;
+  --r-heading-color: #ff0;
+  --r-heading-text-shadow: none;
+  --r-link-color: #0ff;
+  --r-link-color-dark: #0ff;
+  --r-link-color-hover: #fff;
+  --r-selection-background-color: #ff0;
+  --r-selection-color: #000;
+}
+html.zs-contrast .reveal-viewport { background: #000 }
+html.zs-contrast .reveal .slides section, html.zs-contrast .reveal .slides section * { color: #fff; background-color: transparent; border-color: #fff }
+html.zs-contrast .reveal .slides section :is(h1, h2, h3, h4, h5, h6) { color: #ff0 }
+html.zs-contrast .reveal .slides section a { color: #0ff; text-decoration: underline }
+html.zs-contrast .reveal .slides section pre code { background-color: #000; border: 1px solid #fff }
+html.zs-contrast .reveal .controls, html.zs-contrast .reveal .progress, html.zs-contrast .reveal .slide-number { color: #ff0; background-color: #000 }
+html.zs-contrast .reveal .slides section img { background-color: #fff }
+`
+
+// contrastHandoutCSS changes the colors of a handout.
+const contrastHandoutCSS = `html.zs-contrast body, html.zs-contrast body * { color: #fff; background-color: #000; border-color: #fff }
+html.zs-contrast :is(h1, h2, h3, h4, h5, h6) { color: #ff0 }
+html.zs-contrast a, html.zs-contrast a * { color: #0ff; text-decoration: underline }
+html.zs-contrast img { background-color: #fff }
+`
+
+const contrastToggleCSS = `button.zs-contrast-toggle { position: fixed; z-index: 50; padding: .25rem .5rem; font-family: sans-serif; font-size: 1rem; color: #000; background: #fff; border: 2px solid #000; border-radius: .25rem; cursor: pointer }
+button.zs-contrast-toggle[aria-pressed="true"] { color: #000; background: #ff0; border-color: #ff0 }
+button.zs-contrast-toggle.show { left: 1rem; bottom: 1rem; opacity: .5 }
+button.zs-contrast-toggle.show:hover, button.zs-contrast-toggle.show:focus { opacity: 1 }
+button.zs-contrast-toggle.handout { right: 1rem; top: 1rem }
+@media print { button.zs-contrast-toggle { display: none } }
+`
+
+// writeContrastHead writes the CSS of the high-contrast theme for the given
+// output, together with a script that enables the theme before the page is
+// rendered, if the user has chosen it. It must be written into the head of a
+// HTML page, after all other CSS.
+func writeContrastHead(w io.Writer, role string) {
+	io.WriteString(w, "<style type=\"text/css\">\n")
+	if role == deck.SlideRoleShow {
+		io.WriteString(w, contrastShowCSS)
+	} else {
+		io.WriteString(w, contrastHandoutCSS)
+	}
+	io.WriteString(w, contrastToggleCSS)
+	io.WriteString(w, "</style>\n")
+	fmt.Fprintf(w, `<script>try { if (localStorage.getItem(%q) === "1") { document.documentElement.classList.add("zs-contrast"); } } catch (e) {}</script>
+`, contrastStorageKey)
+}
+
+// writeContrastToggle writes the button to toggle the high-contrast theme.
+// For a slide show, it must be written after the reveal.js script, because
+// the key "C" is bound to the toggle too.
+func writeContrastToggle(w io.Writer, lang, role string) {
+	label := translate(lang, "High contrast")
+	fmt.Fprintf(w, `<button type="button" class="zs-contrast-toggle %s" id="zs-contrast-toggle" aria-pressed="false" title="%s">%s</button>
+<script>(function() {
+var button = document.getElementById("zs-contrast-toggle");
+var root = document.documentElement;
+function update() { button.setAttribute("aria-pressed", root.classList.contains("zs-contrast") ? "true" : "false"); }
+function toggle() {
+root.classList.toggle("zs-contrast");
+try { localStorage.setItem(%q, root.classList.contains("zs-contrast") ? "1" : "0"); } catch (e) {}
+update();
+}
+button.addEventListener("click", toggle);
+update();
+`, role, label, label, contrastStorageKey)
+	if role == deck.SlideRoleShow {
+		io.WriteString(w, "Reveal.addKeyBinding({keyCode: 67, key: \"C\", description: \"High contrast\"}, toggle);\n")
+	}
+	io.WriteString(w, "})();</script>\n")
+}
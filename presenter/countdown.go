@@ -0,0 +1,79 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// A slide with the metadata key "slide-countdown", e.g. for a break, shows a
+// clock that counts down, when the slide is shown. If the time is up, the
+// slide show advances to the next slide.
+
+// writeCountdownClock writes the clock of a slide with a countdown.
+func writeCountdownClock(w io.Writer, d time.Duration) {
+	fmt.Fprintf(w, "<p class=\"zs-countdown\" role=\"timer\">%s</p>\n", formatCountdown(d))
+}
+
+// formatCountdown returns the given duration as "M:SS" or "H:MM:SS".
+func formatCountdown(d time.Duration) string {
+	secs := int(d / time.Second)
+	if secs >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", secs/3600, secs/60%60, secs%60)
+	}
+	return fmt.Sprintf("%d:%02d", secs/60, secs%60)
+}
+
+// writeCountdownScript writes the script that starts the countdown of a
+// slide, when it is shown. Slides with a countdown have the attribute
+// "data-countdown" with the number of seconds.
+func writeCountdownScript(w io.Writer) {
+	io.WriteString(w, `<style type="text/css">
+.reveal p.zs-countdown { font-size: 3em; font-weight: bold; font-variant-numeric: tabular-nums }
+.reveal p.zs-countdown.zs-countdown-over { opacity: .5 }
+</style>
+<script>
+(function() {
+var timer = null;
+// The speaker view shows the slide show in frames that must not advance.
+var receiver = new URLSearchParams(window.location.search).has("receiver");
+function format(secs) {
+var h = Math.floor(secs / 3600), m = Math.floor(secs / 60) % 60, s = secs % 60;
+return (h > 0 ? h + ":" + (m < 10 ? "0" : "") : "") + m + ":" + (s < 10 ? "0" : "") + s;
+}
+function start(ev) {
+if (timer !== null) { clearInterval(timer); timer = null; }
+var slide = ev.currentSlide;
+var secs = slide ? parseInt(slide.getAttribute("data-countdown"), 10) : NaN;
+var clock = slide ? slide.querySelector("p.zs-countdown") : null;
+if (!(secs > 0) || !clock) { return; }
+var end = Date.now() + secs * 1000;
+clock.classList.remove("zs-countdown-over");
+function tick() {
+var left = Math.max(0, Math.ceil((end - Date.now()) / 1000));
+clock.textContent = format(left);
+if (left > 0) { return; }
+clearInterval(timer);
+timer = null;
+clock.classList.add("zs-countdown-over");
+if (!receiver && Reveal.getCurrentSlide() === slide) { Reveal.next(); }
+}
+tick();
+timer = setInterval(tick, 250);
+}
+Reveal.on("slidechanged", start);
+if (Reveal.isReady()) { start({currentSlide: Reveal.getCurrentSlide()}); } else { Reveal.on("ready", start); }
+})();
+</script>
+`)
+}
@@ -0,0 +1,158 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package deck
+
+import (
+	"bytes"
+	"strings"
+	"unicode"
+
+	"codeberg.org/t73fde/sxpf"
+	"zettelstore.de/c/api"
+	"zettelstore.de/c/sexpr"
+)
+
+// SetExternalContent replaces the content of a slide zettel by the given
+// text, which was retrieved from an external source. It must be called before
+// the slide is added.
+//
+// Zettelstore is not able to parse text that is not stored in a zettel.
+// Therefore, only the block structure of Markdown is recognized: headings,
+// fenced code blocks, unordered lists, and paragraphs. Inline formatting is
+// shown as it is. As usual, level-1 headings split the content into several
+// slides.
+func (s *SlideSet) SetExternalContent(zid api.ZettelID, text []byte) {
+	if s.external == nil {
+		s.external = make(map[api.ZettelID]*sxpf.Pair)
+	}
+	s.external[zid] = parseText(text)
+}
+
+// getMetaContent returns the metadata and the content of a slide zettel. The
+// content is replaced, if it was retrieved from an external source.
+func (s *SlideSet) getMetaContent(zid api.ZettelID, sxZettel sxpf.Value) (sexpr.Meta, *sxpf.Pair) {
+	sxMeta, sxContent := sexpr.GetMetaContent(sxZettel)
+	if extContent, found := s.external[zid]; found {
+		return sxMeta, extContent
+	}
+	return sxMeta, sxContent
+}
+
+// parseText returns the block nodes of the given Markdown text.
+func parseText(text []byte) *sxpf.Pair {
+	var blocks []sxpf.Value
+	var para []string // lines of the current paragraph
+	var items [][]string
+	endPara := func() {
+		if len(para) > 0 {
+			blocks = append(blocks, sxpf.NewPair(sexpr.SymPara, makeLines(para)))
+			para = nil
+		}
+	}
+	endList := func() {
+		if len(items) > 0 {
+			list := make([]sxpf.Value, len(items))
+			for i, item := range items {
+				list[i] = sxpf.NewPair(sxpf.NewPair(sexpr.SymPara, makeLines(item)), nil)
+			}
+			blocks = append(blocks, sxpf.NewPair(sexpr.SymListUnordered, sxpf.NewPairFromSlice(list)))
+			items = nil
+		}
+	}
+
+	lines := strings.Split(string(bytes.ReplaceAll(text, []byte("\r\n"), []byte("\n"))), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRightFunc(lines[i], unicode.IsSpace)
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			endPara()
+			endList()
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			endPara()
+			endList()
+			fence := trimmed[:3]
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, lines[i])
+			}
+			blocks = append(blocks, sxpf.NewPairFromSlice([]sxpf.Value{
+				sexpr.SymVerbatimCode, sxpf.Nil(), sxpf.NewString(strings.Join(code, "\n"))}))
+		case headingLevel(trimmed) > 0:
+			endPara()
+			endList()
+			level := headingLevel(trimmed)
+			title := strings.TrimSpace(strings.TrimRight(trimmed[level:], "#"))
+			slug := makeSlug(title)
+			blocks = append(blocks, sxpf.NewPairFromSlice([]sxpf.Value{
+				sexpr.SymHeading, sxpf.NewInteger(int64(level)), sxpf.Nil(),
+				sxpf.NewString(slug), sxpf.NewString(slug), makeText(title)}))
+		case isListItem(trimmed):
+			endPara()
+			items = append(items, []string{strings.TrimSpace(trimmed[2:])})
+		case len(items) > 0:
+			items[len(items)-1] = append(items[len(items)-1], trimmed)
+		default:
+			para = append(para, trimmed)
+		}
+	}
+	endPara()
+	endList()
+	return sxpf.NewPairFromSlice(blocks)
+}
+
+// headingLevel returns the level of a Markdown heading, or zero, if the line
+// is not a heading.
+func headingLevel(line string) int {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || level == len(line) || line[level] != ' ' {
+		return 0
+	}
+	return level
+}
+
+func isListItem(line string) bool {
+	return len(line) > 2 && strings.IndexByte("-*+", line[0]) >= 0 && line[1] == ' '
+}
+
+// makeLines returns the inline nodes of the given lines, separated by soft
+// line breaks.
+func makeLines(lines []string) *sxpf.Pair {
+	result := make([]sxpf.Value, 0, 2*len(lines)-1)
+	for i, line := range lines {
+		if i > 0 {
+			result = append(result, sxpf.NewPair(sexpr.SymSoft, nil))
+		}
+		result = append(result, makeText(line))
+	}
+	return sxpf.NewPairFromSlice(result)
+}
+
+// makeSlug returns the fragment identifier of a heading.
+func makeSlug(title string) string {
+	var sb strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && sb.Len() > 0 {
+				sb.WriteByte('-')
+			}
+			sb.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return sb.String()
+}
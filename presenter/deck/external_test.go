@@ -0,0 +1,58 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package deck
+
+import (
+	"testing"
+
+	"codeberg.org/t73fde/sxpf"
+)
+
+func TestParseText(t *testing.T) {
+	testcases := []struct {
+		name string
+		text string
+		exp  string
+	}{
+		{"empty", "", `()`},
+		{"paragraphs", "a\r\nb\n\n  c  \n", `((PARA (TEXT "a") (SOFT) (TEXT "b")) (PARA (TEXT "c")))`},
+		{"headings", "# Intro #\ntext\n## Sub Title!\n#no heading",
+			`((HEADING 1 () "intro" "intro" (TEXT "Intro")) (PARA (TEXT "text")) (HEADING 2 () "sub-title" "sub-title" (TEXT "Sub Title!")) (PARA (TEXT "#no heading")))`},
+		{"code", "```go\nfunc f() {\n\n}\n```\nafter",
+			`((VERBATIM-CODE () "func f() {\n\n}") (PARA (TEXT "after")))`},
+		{"unclosed code", "~~~\nx", `((VERBATIM-CODE () "x"))`},
+		{"list", "- a\n  more\n* b\n\nc",
+			`((UNORDERED ((PARA (TEXT "a") (SOFT) (TEXT "more"))) ((PARA (TEXT "b")))) (PARA (TEXT "c")))`},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := sxpf.Repr(parseText([]byte(tc.text)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.exp {
+				t.Errorf("expected\n%s\nbut got\n%s", tc.exp, got)
+			}
+		})
+	}
+}
+
+func TestSetExternalContent(t *testing.T) {
+	s := NewMeta(zidA, makeMeta(""))
+	s.SetExternalContent(zidA, []byte("text"))
+	_, content := s.getMetaContent(zidA, nil)
+	if got := inlineText(content.GetFirst().(*sxpf.Pair).GetTail()); got != "text" {
+		t.Errorf("expected external content %q, but got %q", "text", got)
+	}
+	if _, content = s.getMetaContent(zidB, nil); content != nil {
+		t.Errorf("expected no content, but got %v", content)
+	}
+}
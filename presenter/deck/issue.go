@@ -0,0 +1,124 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package deck
+
+import (
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"zettelstore.de/c/api"
+	"zettelstore.de/c/sexpr"
+)
+
+// Kinds of issues found while collecting the slides of a slide set.
+const (
+	IssueSlide      = "Slide could not be retrieved"
+	IssueLink       = "Broken zettel link"
+	IssueImage      = "Missing image"
+	IssueVisibility = "Zettel skipped due to visibility"
+	IssueMetadata   = "Invalid metadata value"
+)
+
+// Issue is a problem found while collecting a slide set.
+type Issue struct {
+	Kind string
+	Zid  api.ZettelID // zettel that contains the problem
+	Ref  api.ZettelID // referenced zettel
+	Msg  string
+}
+
+// Link is an external link found in a slide set.
+type Link struct {
+	Zid api.ZettelID
+	URL string
+}
+
+// Issues returns all problems found while collecting the slide set.
+func (s *SlideSet) Issues() []Issue { return s.issues }
+
+// ExtLinks returns all external links of the slide set.
+func (s *SlideSet) ExtLinks() []Link { return s.extLinks }
+
+func (s *SlideSet) addIssue(kind string, zid, ref api.ZettelID, msg string) {
+	s.issues = append(s.issues, Issue{kind, zid, ref, msg})
+}
+
+// validateSlideSet checks the metadata of the slide set zettel.
+func (s *SlideSet) validateSlideSet() {
+	if duration := s.Duration(); duration != "" {
+		if _, ok := ParseDuration(duration); !ok {
+			s.addMetadataIssue(s.zid, KeyDuration, duration)
+		}
+	}
+	switch motion := s.sxMeta.GetString(KeySlideMotion); motion {
+	case "", MotionAuto, MotionFull, MotionReduce:
+	default:
+		s.addMetadataIssue(s.zid, KeySlideMotion, motion)
+	}
+	s.validateTitles(s.zid, s.sxMeta)
+}
+
+// validateSlide checks the metadata of a slide zettel.
+func (s *SlideSet) validateSlide(zid api.ZettelID, sxMeta sexpr.Meta) {
+	switch role := sxMeta.GetString(KeySlideRole); role {
+	case "", SlideRoleShow, SlideRoleHandout:
+	default:
+		s.addMetadataIssue(zid, KeySlideRole, role)
+	}
+	if count := sxMeta.GetString(KeySlideCountdown); count != "" && ParseCountdown(count) == 0 {
+		s.addMetadataIssue(zid, KeySlideCountdown, count)
+	}
+	s.validateTitles(zid, sxMeta)
+}
+
+// validateTitles checks that all given titles could be parsed.
+func (s *SlideSet) validateTitles(zid api.ZettelID, sxMeta sexpr.Meta) {
+	for _, key := range []string{api.KeyTitle, KeySlideTitle, KeySubTitle} {
+		if _, found := sxMeta[key]; found && sxMeta.GetPair(key).IsEmpty() {
+			s.addMetadataIssue(zid, key, sxMeta.GetString(key))
+		}
+	}
+}
+
+func (s *SlideSet) addMetadataIssue(zid api.ZettelID, key, value string) {
+	log.Println("VALI", zid, key, value)
+	s.addIssue(IssueMetadata, zid, zid, key+": "+value)
+}
+
+// ParseDuration returns the duration of a presentation in minutes, if the
+// given value is valid.
+func ParseDuration(val string) (int, bool) {
+	minutes, err := strconv.Atoi(strings.TrimSpace(val))
+	if err != nil || minutes <= 0 {
+		return 0, false
+	}
+	return minutes, true
+}
+
+// ParseCountdown returns the duration of a countdown, given in minutes (e.g.
+// "10") or as a duration (e.g. "90s" or "1m30s"). If the value is not valid,
+// zero is returned.
+func ParseCountdown(val string) time.Duration {
+	val = strings.TrimSpace(val)
+	if minutes, ok := ParseDuration(val); ok {
+		if int64(minutes) > math.MaxInt64/int64(time.Minute) {
+			return 0
+		}
+		return time.Duration(minutes) * time.Minute
+	}
+	if d, err := time.ParseDuration(val); err == nil && d >= time.Second {
+		return d.Truncate(time.Second)
+	}
+	return 0
+}
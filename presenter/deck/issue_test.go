@@ -0,0 +1,42 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package deck
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCountdown(t *testing.T) {
+	testcases := []struct {
+		val string
+		exp time.Duration
+	}{
+		{"10", 10 * time.Minute},
+		{" 5 ", 5 * time.Minute},
+		{"90s", 90 * time.Second},
+		{"1m30s", 90 * time.Second},
+		{"1m30.7s", 90 * time.Second},
+		{"1s", time.Second},
+		{"", 0},
+		{"0", 0},
+		{"-3", 0},
+		{"500ms", 0},
+		{"-1m", 0},
+		{"ten", 0},
+		{"99999999999999", 0},
+	}
+	for _, tc := range testcases {
+		if got := ParseCountdown(tc.val); got != tc.exp {
+			t.Errorf("%q: expected %v, but got %v", tc.val, tc.exp, got)
+		}
+	}
+}
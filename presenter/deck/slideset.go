@@ -0,0 +1,943 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+// Package deck provides the model of a slide set: its slides, their order, and
+// all zettel that are referenced by them.
+package deck
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"codeberg.org/t73fde/sxpf"
+	"zettelstore.de/c/api"
+	"zettelstore.de/c/html"
+	"zettelstore.de/c/sexpr"
+)
+
+// Constants for zettel metadata keys
+const (
+	KeyAuthor         = "author"
+	KeyDuration       = "duration"
+	KeySlideSetRole   = "slideset-role" // Only for Presenter configuration
+	KeySlideAgenda    = "slide-agenda"
+	KeySlideClass     = "slide-class"
+	KeySlideClosing   = "slide-closing"
+	KeySlideCountdown = "slide-countdown"
+	KeySlideCSS       = "slide-css"
+	KeySlideFont      = "slide-font"
+	KeySlideMotion    = "slide-motion"
+	KeySlideProgress  = "slide-progress"
+	KeySlidePrint     = "slide-print-css"
+	KeySlideRole      = "slide-role"
+	KeySlideScript    = "slide-script"
+	KeySlideTitle     = "slide-title"
+	KeySubTitle       = "sub-title" // TODO: Could possibly move to ZS-Client
+	KeyTitleImage     = "title-image"
+	KeyTitleLayout    = "title-layout"
+)
+
+// Constants for some values
+const (
+	DefaultSlideSetRole = "slideset"
+	MotionAuto          = "auto"    // reduce motion, if the user prefers it
+	MotionFull          = "full"    // always use transitions and animations
+	MotionReduce        = "reduce"  // never use transitions and animations
+	SlideRoleHandout    = "handout" // TODO: Includes manual?
+	SlideRoleShow       = "show"
+	SyntaxMermaid       = "mermaid"
+	TitleLayoutCentered = "centered"
+	TitleLayoutMinimal  = "minimal"
+	TitleLayoutSplit    = "split"
+)
+
+// Slide is one slide that is shown one or more times.
+type Slide struct {
+	zid     api.ZettelID // The zettel identifier
+	title   *sxpf.Pair
+	lang    string
+	role    string
+	class   string        // Additional CSS classes, separated by space
+	content *sxpf.Pair    // Zettel / slide content
+	script  api.ZettelID  // Zettel with the script of the speaker, if valid
+	count   time.Duration // Duration of a countdown, e.g. for a break
+	agenda  bool          // Artificial slide with the agenda
+	divider bool          // Artificial slide that starts a part of a combined slide set
+}
+
+func newSlide(zid api.ZettelID, sxMeta sexpr.Meta, sxContent *sxpf.Pair) *Slide {
+	return &Slide{
+		zid:     zid,
+		title:   SlideTitleZid(sxMeta, zid),
+		lang:    sxMeta.GetString(api.KeyLang),
+		role:    sxMeta.GetString(KeySlideRole),
+		class:   slideClass(sxMeta.GetString(KeySlideClass)),
+		content: sxContent,
+		script:  api.ZettelID(sxMeta.GetString(KeySlideScript)),
+		count:   ParseCountdown(sxMeta.GetString(KeySlideCountdown)),
+	}
+}
+
+// newErrorSlide creates an artificial slide that reports a zettel that could
+// not be retrieved, so that the missing content is visible in the slide show
+// and in the handout.
+func newErrorSlide(zid api.ZettelID, msg string) *Slide {
+	return &Slide{
+		zid:   zid,
+		title: makeTextInline("Error: " + string(zid)),
+		content: sxpf.NewPairFromSlice([]sxpf.Value{
+			sxpf.NewPair(sexpr.SymPara, makeTextInline("Zettel "+string(zid)+" could not be retrieved.")),
+			sxpf.NewPair(sexpr.SymPara, makeTextInline(msg)),
+		}),
+	}
+}
+
+func makeTextInline(s string) *sxpf.Pair {
+	return sxpf.NewPair(sxpf.NewPair(sexpr.SymText, sxpf.NewPair(sxpf.NewString(s), nil)), nil)
+}
+
+func (sl *Slide) MakeChild(sxTitle, sxContent *sxpf.Pair) *Slide {
+	return &Slide{
+		zid:     sl.zid,
+		title:   sxTitle,
+		lang:    sl.lang,
+		role:    sl.role,
+		class:   sl.class,
+		content: sxContent,
+	}
+}
+
+// Zid returns the zettel identifier of the slide.
+func (sl *Slide) Zid() api.ZettelID { return sl.zid }
+
+// Title returns the title of the slide.
+func (sl *Slide) Title() *sxpf.Pair { return sl.title }
+
+// Lang returns the language of the slide.
+func (sl *Slide) Lang() string { return sl.lang }
+
+// Class returns the additional CSS classes of the slide, separated by space.
+func (sl *Slide) Class() string { return sl.class }
+
+// IsAgenda returns true, if the slide is the artificial agenda slide.
+func (sl *Slide) IsAgenda() bool { return sl.agenda }
+
+// IsDivider returns true, if the slide starts a part of a combined slide set.
+func (sl *Slide) IsDivider() bool { return sl.divider }
+
+// Countdown returns the duration of the countdown of the slide, or zero, if
+// the slide has no countdown. Only the first slide of a zettel has one.
+func (sl *Slide) Countdown() time.Duration { return sl.count }
+
+// slideClass returns all valid CSS class names of the given metadata value.
+func slideClass(val string) string {
+	var classes []string
+	for _, class := range strings.Fields(val) {
+		if isClassName(class) {
+			classes = append(classes, class)
+		}
+	}
+	return strings.Join(classes, " ")
+}
+
+func isClassName(s string) bool {
+	for _, ch := range s {
+		if !unicode.IsLetter(ch) && !unicode.IsDigit(ch) && ch != '-' && ch != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// Content returns the content of the slide.
+func (sl *Slide) Content() *sxpf.Pair { return sl.content }
+
+func (sl *Slide) HasSlideRole(sr string) bool {
+	if sr == "" {
+		return true
+	}
+	s := sl.role
+	if s == "" {
+		return true
+	}
+	return s == sr
+}
+
+type SlideInfo struct {
+	prev     *SlideInfo
+	Slide    *Slide
+	Number   int // number in document
+	SlideNo  int // number in slide show, if any
+	oldest   *SlideInfo
+	youngest *SlideInfo
+	next     *SlideInfo
+}
+
+func (si *SlideInfo) Next() *SlideInfo {
+	if si == nil {
+		return nil
+	}
+	return si.next
+}
+func (si *SlideInfo) Child() *SlideInfo {
+	if si == nil {
+		return nil
+	}
+	return si.oldest
+}
+func (si *SlideInfo) LastChild() *SlideInfo {
+	if si == nil {
+		return nil
+	}
+	return si.youngest
+}
+
+func (si *SlideInfo) SplitChildren() {
+	var oldest, youngest *SlideInfo
+	title := si.Slide.title
+	var content []sxpf.Value
+	for elem := si.Slide.content; !elem.IsNil(); elem = elem.GetTail() {
+		bn, err := elem.GetPair()
+		if err != nil {
+			return
+		}
+		sym, err := bn.GetSymbol()
+		if err != nil {
+			continue
+		}
+		if sym != sexpr.SymHeading {
+			content = append(content, bn)
+			continue
+		}
+		levelPair := bn.GetTail()
+		if level, err := levelPair.GetInteger(); err != nil || level < 1 || level > 1 {
+			content = append(content, bn)
+			continue
+		}
+		nextTitle := levelPair.GetTail().GetTail().GetTail().GetTail()
+		if nextTitle.IsEmpty() {
+			content = append(content, bn)
+			continue
+		}
+		slInfo := &SlideInfo{
+			prev:  youngest,
+			Slide: si.Slide.MakeChild(title, sxpf.NewPairFromSlice(content)),
+		}
+		content = nil
+		if oldest == nil {
+			oldest = slInfo
+		}
+		if youngest != nil {
+			youngest.next = slInfo
+		}
+		youngest = slInfo
+		title = nextTitle
+	}
+	if oldest == nil {
+		oldest = &SlideInfo{Slide: si.Slide.MakeChild(title, sxpf.NewPairFromSlice(content))}
+		youngest = oldest
+	} else {
+		slInfo := &SlideInfo{
+			prev:  youngest,
+			Slide: si.Slide.MakeChild(title, sxpf.NewPairFromSlice(content)),
+		}
+		if youngest != nil {
+			youngest.next = slInfo
+		}
+		youngest = slInfo
+	}
+	si.oldest = oldest
+	si.youngest = youngest
+}
+
+// Section returns the slide that starts the section of the given slide: the
+// last divider before it, if several slide sets are combined, or the slide
+// itself.
+func (si *SlideInfo) Section() *SlideInfo {
+	for res := si; res != nil; res = res.prev {
+		if res.Slide.divider {
+			return res
+		}
+	}
+	return si
+}
+
+func (si *SlideInfo) FindSlide(zid api.ZettelID) *SlideInfo {
+	if si == nil {
+		return nil
+	}
+
+	// Search backward
+	for res := si; res != nil; res = res.prev {
+		if res.Slide.zid == zid {
+			return res
+		}
+	}
+
+	// Search forward
+	for res := si.next; res != nil; res = res.next {
+		if res.Slide.zid == zid {
+			return res
+		}
+	}
+	return nil
+}
+
+// Image is the content of an image zettel, together with its syntax.
+type Image struct {
+	Syntax string
+	Data   []byte
+	Title  string // title of the image zettel, if it was retrieved
+}
+
+// SlideSet is the sequence of slides shown.
+type SlideSet struct {
+	zid         api.ZettelID
+	sxMeta      sexpr.Meta // Metadata of slideset
+	seqSlide    []*Slide   // slide may occur more than once in seq, but should be stored only once
+	setSlide    map[api.ZettelID]*Slide
+	setImage    map[api.ZettelID]Image
+	setVideo    map[api.ZettelID]Video
+	css         []byte // slideset specific CSS
+	printCSS    []byte // CSS for printing
+	issues      []Issue
+	extLinks    []Link
+	isCompleted bool
+	syntaxes    map[string]bool             // syntaxes of all verbatim-eval nodes
+	preview     bool                        // linked zettel are added regardless of their visibility
+	title       *sxpf.Pair                  // replaces the title of the slide set zettel, if not nil
+	scripts     map[api.ZettelID]*sxpf.Pair // content of the speaker script, for every slide zettel
+	external    map[api.ZettelID]*sxpf.Pair // content of slide zettel, retrieved from an external source
+
+	transclusions map[api.ZettelID]*sxpf.Pair // content of embedded text zettel
+}
+
+func New(zid api.ZettelID, sxMeta sexpr.Meta) *SlideSet {
+	if len(sxMeta) == 0 {
+		return nil
+	}
+	return NewMeta(zid, sxMeta)
+}
+func NewMeta(zid api.ZettelID, sxMeta sexpr.Meta) *SlideSet {
+	return &SlideSet{
+		zid:      zid,
+		sxMeta:   sxMeta,
+		setSlide: make(map[api.ZettelID]*Slide),
+		setImage: make(map[api.ZettelID]Image),
+		setVideo: make(map[api.ZettelID]Video),
+	}
+}
+
+// Zid returns the zettel identifier of the slide set zettel.
+func (s *SlideSet) Zid() api.ZettelID { return s.zid }
+
+// Meta returns the metadata of the slide set zettel.
+func (s *SlideSet) Meta() sexpr.Meta { return s.sxMeta }
+
+// SetPreview specifies whether linked zettel are added to the slide set,
+// even if their visibility is not "public". It must be called before
+// Completion.
+func (s *SlideSet) SetPreview(preview bool) { s.preview = preview }
+
+// Preview returns true, if linked zettel are added regardless of their
+// visibility.
+func (s *SlideSet) Preview() bool { return s.preview }
+
+// Syntaxes returns the syntaxes of all verbatim-eval nodes.
+func (s *SlideSet) Syntaxes() map[string]bool { return s.syntaxes }
+
+func (s *SlideSet) GetSlide(zid api.ZettelID) *Slide {
+	if sl, found := s.setSlide[zid]; found {
+		return sl
+	}
+	return nil
+}
+
+func (s *SlideSet) SlideZids() []api.ZettelID {
+	result := make([]api.ZettelID, len(s.seqSlide))
+	for i, sl := range s.seqSlide {
+		result[i] = sl.zid
+	}
+	return result
+}
+
+func (s *SlideSet) Slides(role string, offset int) *SlideInfo {
+	switch role {
+	case SlideRoleShow:
+		return s.slidesforShow(offset)
+	case SlideRoleHandout:
+		return s.slidesForHandout(offset)
+	}
+	panic(role)
+}
+func (s *SlideSet) slidesforShow(offset int) *SlideInfo {
+	var first, prev *SlideInfo
+	slideNo := offset
+	for _, sl := range s.seqSlide {
+		if !sl.HasSlideRole(SlideRoleShow) {
+			continue
+		}
+		si := &SlideInfo{
+			prev:  prev,
+			Slide: sl,
+		}
+		if first == nil {
+			first = si
+		}
+		if prev != nil {
+			prev.next = si
+		}
+		si.SlideNo = slideNo
+		si.Number = slideNo
+		prev = si
+
+		si.SplitChildren()
+		main := si.Child()
+		main.SlideNo = slideNo
+		main.Number = slideNo
+		for sub := main.Next(); sub != nil; sub = sub.Next() {
+			slideNo++
+			sub.SlideNo = slideNo
+			sub.Number = slideNo
+		}
+		slideNo++
+	}
+	return first
+}
+func (s *SlideSet) slidesForHandout(offset int) *SlideInfo {
+	var first, prev *SlideInfo
+	number, slideNo := offset, offset
+	for _, sl := range s.seqSlide {
+		si := &SlideInfo{
+			prev:  prev,
+			Slide: sl,
+		}
+		if !sl.HasSlideRole(SlideRoleHandout) {
+			if sl.HasSlideRole(SlideRoleShow) {
+				s.addChildrenForHandout(si, &slideNo)
+			}
+			continue
+		}
+		if sl.HasSlideRole(SlideRoleShow) {
+			si.SlideNo = slideNo
+			s.addChildrenForHandout(si, &slideNo)
+		}
+		if first == nil {
+			first = si
+		}
+		if prev != nil {
+			prev.next = si
+		}
+		si.Number = number
+		prev = si
+		number++
+	}
+	return first
+}
+func (*SlideSet) addChildrenForHandout(si *SlideInfo, slideNo *int) {
+	si.SplitChildren()
+	main := si.Child()
+	main.SlideNo = *slideNo
+	for sub := main.Next(); sub != nil; sub = sub.Next() {
+		*slideNo++
+		sub.SlideNo = *slideNo
+	}
+	*slideNo++
+}
+
+func (s *SlideSet) HasImage(zid api.ZettelID) bool {
+	_, found := s.setImage[zid]
+	return found
+}
+func (s *SlideSet) AddImage(zid api.ZettelID, syntax string, data []byte) {
+	s.setImage[zid] = Image{Syntax: syntax, Data: data}
+}
+
+// SetImageTitle stores the title of an image zettel.
+func (s *SlideSet) SetImageTitle(zid api.ZettelID, title string) {
+	if img, found := s.setImage[zid]; found {
+		img.Title = title
+		s.setImage[zid] = img
+	}
+}
+func (s *SlideSet) GetImage(zid api.ZettelID) (Image, bool) {
+	img, found := s.setImage[zid]
+	return img, found
+}
+func (s *SlideSet) Images() []api.ZettelID {
+	result := make([]api.ZettelID, 0, len(s.setImage))
+	for zid := range s.setImage {
+		result = append(result, zid)
+	}
+	return result
+}
+
+func (s *SlideSet) Title() *sxpf.Pair {
+	if s.title != nil {
+		return s.title
+	}
+	return SlideTitle(s.sxMeta)
+}
+
+// Script returns the content of the script zettel of the speaker for the
+// given slide zettel, or nil.
+func (s *SlideSet) Script(zid api.ZettelID) *sxpf.Pair { return s.scripts[zid] }
+
+// SetTitle replaces the title of the slide set zettel, e.g. for a slide set
+// that combines other slide sets.
+func (s *SlideSet) SetTitle(title *sxpf.Pair) { s.title = title }
+
+// HTMLTitle returns the HTML-encoded title, or the zettel identifier if there
+// is no title.
+func (s *SlideSet) HTMLTitle() string {
+	if title := s.Title(); !title.IsEmpty() {
+		return html.EvaluateInline(nil, title, false, false)
+	}
+	return string(s.zid)
+}
+func (s *SlideSet) Subtitle() *sxpf.Pair {
+	if subTitle := s.sxMeta.GetPair(KeySubTitle); !subTitle.IsEmpty() {
+		return subTitle
+	}
+	return nil
+}
+
+func (s *SlideSet) TitleLayout() string {
+	switch layout := s.sxMeta.GetString(KeyTitleLayout); layout {
+	case TitleLayoutMinimal, TitleLayoutSplit:
+		return layout
+	}
+	return TitleLayoutCentered
+}
+
+// Motion returns how the slide show uses transitions and animations.
+func (s *SlideSet) Motion() string {
+	switch motion := s.sxMeta.GetString(KeySlideMotion); motion {
+	case MotionFull, MotionReduce:
+		return motion
+	}
+	return MotionAuto
+}
+func (s *SlideSet) TitleImage() api.ZettelID {
+	if zid := api.ZettelID(s.sxMeta.GetString(KeyTitleImage)); zid.IsValid() {
+		return zid
+	}
+	return api.InvalidZID
+}
+
+func (s *SlideSet) Lang() string { return s.sxMeta.GetString(api.KeyLang) }
+func (s *SlideSet) Author(defAuthor string) string {
+	if author := s.sxMeta.GetString(KeyAuthor); author != "" {
+		return author
+	}
+	return defAuthor
+}
+func (s *SlideSet) Duration() string  { return s.sxMeta.GetString(KeyDuration) }
+func (s *SlideSet) Modified() string  { return s.sxMeta.GetString(api.KeyModified) }
+func (s *SlideSet) Copyright() string { return s.sxMeta.GetString(api.KeyCopyright) }
+func (s *SlideSet) License() string   { return s.sxMeta.GetString(api.KeyLicense) }
+
+func (s *SlideSet) CSSZid() api.ZettelID {
+	if zid := api.ZettelID(s.sxMeta.GetString(KeySlideCSS)); zid.IsValid() {
+		return zid
+	}
+	return api.InvalidZID
+}
+func (s *SlideSet) FontZids() []api.ZettelID {
+	var result []api.ZettelID
+	for _, val := range strings.Fields(s.sxMeta.GetString(KeySlideFont)) {
+		if zid := api.ZettelID(val); zid.IsValid() {
+			result = append(result, zid)
+		}
+	}
+	return result
+}
+func (s *SlideSet) AddCSS(data []byte) { s.css = append(s.css, data...) }
+func (s *SlideSet) CSS() []byte        { return s.css }
+func (s *SlideSet) PrintCSSZid() api.ZettelID {
+	if zid := api.ZettelID(s.sxMeta.GetString(KeySlidePrint)); zid.IsValid() {
+		return zid
+	}
+	return api.InvalidZID
+}
+func (s *SlideSet) AddPrintCSS(data []byte) { s.printCSS = append(s.printCSS, data...) }
+func (s *SlideSet) PrintCSS() []byte        { return s.printCSS }
+
+type GetZettelContentFunc func(api.ZettelID) ([]byte, error)
+type SGetZettelFunc func(api.ZettelID) (sxpf.Value, error)
+
+func (s *SlideSet) AddSlide(zid api.ZettelID, sGetZettel SGetZettelFunc) {
+	if sl, found := s.setSlide[zid]; found {
+		s.seqSlide = append(s.seqSlide, sl)
+		return
+	}
+
+	var sl *Slide
+	sxZettel, err := sGetZettel(zid)
+	if err != nil {
+		log.Println("GETS", zid, err)
+		sl = newErrorSlide(zid, err.Error())
+		s.addIssue(IssueSlide, s.zid, zid, err.Error())
+	} else if sxMeta, sxContent := s.getMetaContent(zid, sxZettel); sxMeta == nil || sxContent == nil {
+		log.Println("MECo", zid)
+		sl = newErrorSlide(zid, "Zettel has no metadata or no content.")
+		s.addIssue(IssueSlide, s.zid, zid, "Zettel has no metadata or no content.")
+	} else {
+		s.validateSlide(zid, sxMeta)
+		sl = newSlide(zid, sxMeta, sxContent)
+	}
+	s.seqSlide = append(s.seqSlide, sl)
+	s.setSlide[zid] = sl
+}
+
+func (s *SlideSet) AdditionalSlide(zid api.ZettelID, sxMeta sexpr.Meta, sxContent *sxpf.Pair) {
+	// TODO: if first, add slide with text "additional content"
+	s.validateSlide(zid, sxMeta)
+	sl := newSlide(zid, sxMeta, sxContent)
+	s.seqSlide = append(s.seqSlide, sl)
+	s.setSlide[zid] = sl
+}
+
+// AddDivider adds an artificial slide that starts a part of a slide set that
+// combines other slide sets. It shows the title and the sub-title of the
+// part.
+func (s *SlideSet) AddDivider(part *SlideSet) {
+	if sl, found := s.setSlide[part.zid]; found {
+		s.seqSlide = append(s.seqSlide, sl)
+		return
+	}
+	title := part.Title()
+	if title.IsEmpty() {
+		title = makeTextInline(string(part.zid))
+	}
+	var content *sxpf.Pair
+	if subTitle := part.Subtitle(); subTitle != nil {
+		content = sxpf.NewPair(sxpf.NewPair(sexpr.SymPara, subTitle), nil)
+	}
+	sl := &Slide{zid: part.zid, title: title, lang: part.Lang(), content: content, divider: true}
+	s.seqSlide = append(s.seqSlide, sl)
+	s.setSlide[part.zid] = sl
+}
+
+// HasAgenda returns true, if the slide show should contain an agenda slide.
+func (s *SlideSet) HasAgenda() bool { return IsTrue(s.sxMeta.GetString(KeySlideAgenda)) }
+
+// AddAgenda adds an artificial slide with the given title before all other
+// slides. It is only shown in the slide show, where it lists the titles of
+// all other slides. It must be called after Completion.
+func (s *SlideSet) AddAgenda(title *sxpf.Pair) {
+	sl := &Slide{zid: api.InvalidZID, title: title, role: SlideRoleShow, agenda: true}
+	s.seqSlide = append([]*Slide{sl}, s.seqSlide...)
+}
+
+// HasClosing returns true, if the slide show should end with a closing slide.
+func (s *SlideSet) HasClosing() bool { return IsTrue(s.sxMeta.GetString(KeySlideClosing)) }
+
+// HasProgress returns true, if every slide of the slide show should show its
+// section and its position.
+func (s *SlideSet) HasProgress() bool { return IsTrue(s.sxMeta.GetString(KeySlideProgress)) }
+
+// IsTrue returns true, if the given metadata value is not empty and not
+// false. It is the same interpretation as a boolean metadata value of
+// Zettelstore.
+func IsTrue(val string) bool {
+	val = strings.TrimSpace(val)
+	return val != "" && strings.IndexByte("0fFnN", val[0]) < 0
+}
+
+// AddClosing adds an artificial slide with the given title after all other
+// slides. It is only shown in the slide show. It contains the given author,
+// all URLs of the slide set metadata, e.g. a contact page, the copyright,
+// and the license. It must be called after Completion.
+func (s *SlideSet) AddClosing(title *sxpf.Pair, author string) {
+	var content []sxpf.Value
+	addPara := func(inl ...sxpf.Value) {
+		content = append(content, sxpf.NewPair(sexpr.SymPara, sxpf.NewPairFromSlice(inl)))
+	}
+	if author != "" {
+		addPara(makeText(author))
+	}
+	var urlKeys []string
+	for key, val := range s.sxMeta {
+		if val.Type == api.MetaURL && s.sxMeta.GetString(key) != "" {
+			urlKeys = append(urlKeys, key)
+		}
+	}
+	sort.Strings(urlKeys)
+	for _, key := range urlKeys {
+		url := s.sxMeta.GetString(key)
+		addPara(sxpf.NewPairFromSlice([]sxpf.Value{sexpr.SymLinkExternal, sxpf.Nil(), sxpf.NewString(url), makeText(url)}))
+	}
+	for _, val := range []string{s.Copyright(), s.License()} {
+		if val != "" {
+			addPara(makeText(val))
+		}
+	}
+	sl := &Slide{zid: api.InvalidZID, title: title, lang: s.Lang(), role: SlideRoleShow, content: sxpf.NewPairFromSlice(content)}
+	s.seqSlide = append(s.seqSlide, sl)
+}
+
+func makeText(s string) *sxpf.Pair {
+	return sxpf.NewPair(sexpr.SymText, sxpf.NewPair(sxpf.NewString(s), nil))
+}
+
+func (s *SlideSet) addErrorSlide(sl *Slide) {
+	s.seqSlide = append(s.seqSlide, sl)
+	s.setSlide[sl.zid] = sl
+}
+
+func (s *SlideSet) Completion(getZettel GetZettelContentFunc, getZettelSexpr SGetZettelFunc) {
+	if s.isCompleted {
+		return
+	}
+	s.validateSlideSet()
+	env := collectEnv{s: s, getZettel: getZettel, sGetZettel: getZettelSexpr}
+	env.initCollection(s)
+	env.collectScripts()
+	for {
+		zid, found := env.pop()
+		if !found {
+			break
+		}
+		if zid == api.InvalidZID {
+			continue
+		}
+		sl := s.GetSlide(zid)
+		if sl == nil {
+			panic(zid)
+		}
+		env.mark(zid)
+		env.curZid = zid
+		sxpf.Eval(&env, sl.content)
+	}
+	s.syntaxes = env.syntaxes
+	s.isCompleted = true
+}
+
+func (ce *collectEnv) initCollection(s *SlideSet) {
+	zids := s.SlideZids()
+	for i := len(zids) - 1; i >= 0; i-- {
+		ce.push(zids[i])
+	}
+	ce.visited = make(map[api.ZettelID]struct{}, len(zids)+16)
+}
+
+// collectScripts retrieves the script zettel of all slides. Zettel that are
+// referenced by a script, e.g. images, are collected too.
+func (ce *collectEnv) collectScripts() {
+	s := ce.s
+	for _, sl := range s.seqSlide {
+		if !sl.script.IsValid() {
+			continue
+		}
+		if _, found := s.scripts[sl.zid]; found {
+			continue
+		}
+		sxZettel, err := ce.sGetZettel(sl.script)
+		if err != nil {
+			log.Println("GETS", sl.script, err)
+			s.addIssue(IssueSlide, sl.zid, sl.script, err.Error())
+			continue
+		}
+		_, sxContent := sexpr.GetMetaContent(sxZettel)
+		if sxContent == nil {
+			continue
+		}
+		if s.scripts == nil {
+			s.scripts = make(map[api.ZettelID]*sxpf.Pair)
+		}
+		s.scripts[sl.zid] = sxContent
+		ce.curZid = sl.script
+		sxpf.Eval(ce, sxContent)
+	}
+}
+
+func (ce *collectEnv) push(zid api.ZettelID) { ce.stack = append(ce.stack, zid) }
+func (ce *collectEnv) pop() (api.ZettelID, bool) {
+	lp := len(ce.stack) - 1
+	if lp < 0 {
+		return api.InvalidZID, false
+	}
+	zid := ce.stack[lp]
+	ce.stack = ce.stack[0:lp]
+	if _, found := ce.visited[zid]; found {
+		return api.InvalidZID, true
+	}
+	return zid, true
+}
+func (ce *collectEnv) mark(zid api.ZettelID) { ce.visited[zid] = struct{}{} }
+func (ce *collectEnv) isMarked(zid api.ZettelID) bool {
+	_, found := ce.visited[zid]
+	return found
+}
+
+type collectEnv struct {
+	s          *SlideSet
+	getZettel  GetZettelContentFunc
+	sGetZettel SGetZettelFunc
+	stack      []api.ZettelID
+	visited    map[api.ZettelID]struct{}
+	curZid     api.ZettelID // zettel that is currently traversed
+	depth      int          // number of nested embedded text zettel
+	syntaxes   map[string]bool
+}
+
+func (ce *collectEnv) LookupForm(sym *sxpf.Symbol) (sxpf.Form, error) {
+	switch sym {
+	case sexpr.SymVerbatimEval:
+		return verbEvalFn, nil
+	case sexpr.SymLinkZettel:
+		return linkZettelFn, nil
+	case sexpr.SymLinkExternal:
+		return linkExternalFn, nil
+	case sexpr.SymEmbed:
+		return embedFn, nil
+	}
+	return ignoreFn, nil
+}
+
+var (
+	verbEvalFn = sxpf.NewBuiltin("verbatim-eval", true, 1, -1,
+		func(env sxpf.Environment, args *sxpf.Pair, _ int) (sxpf.Value, error) {
+			if syntax := VerbatimSyntax(args); syntax != "" {
+				ce := env.(*collectEnv)
+				if ce.syntaxes == nil {
+					ce.syntaxes = make(map[string]bool)
+				}
+				ce.syntaxes[syntax] = true
+			}
+			return nil, nil
+		})
+	linkZettelFn = sxpf.NewBuiltin("link-zettel", true, 2, -1,
+		func(env sxpf.Environment, args *sxpf.Pair, _ int) (sxpf.Value, error) {
+			if zidVal, err := args.GetTail().GetString(); err == nil {
+				zid := api.ZettelID(zidVal)
+				if zid.IsValid() {
+					env.(*collectEnv).visitZettel(zid)
+				}
+			}
+			return nil, nil
+		})
+	linkExternalFn = sxpf.NewBuiltin("link-external", true, 2, -1,
+		func(env sxpf.Environment, args *sxpf.Pair, _ int) (sxpf.Value, error) {
+			if ref, err := args.GetTail().GetString(); err == nil && ref != "" {
+				ce := env.(*collectEnv)
+				ce.s.extLinks = append(ce.s.extLinks, Link{ce.curZid, ref})
+			}
+			return nil, nil
+		})
+	embedFn = sxpf.NewBuiltin("embed-inline", true, 3, -1,
+		func(env sxpf.Environment, args *sxpf.Pair, _ int) (sxpf.Value, error) {
+			argRef := args.GetTail()
+			if ref, err := argRef.GetPair(); err == nil && ref.GetFirst() == sexpr.SymRefStateZettel {
+				if zidVal, ok := ref.GetTail().GetString(); ok == nil {
+					zid := api.ZettelID(zidVal)
+					if syntax, err := argRef.GetTail().GetString(); err == nil && zid.IsValid() {
+						if IsTextSyntax(syntax) {
+							env.(*collectEnv).visitTransclusion(zid)
+						} else {
+							env.(*collectEnv).visitImage(zid, syntax)
+						}
+					}
+				}
+			}
+			return nil, nil
+		})
+	ignoreFn = sxpf.NewBuiltin("traverse", false, 0, -1,
+		func(sxpf.Environment, *sxpf.Pair, int) (sxpf.Value, error) { return nil, nil })
+)
+
+// VerbatimSyntax returns the syntax of a verbatim node.
+func VerbatimSyntax(args *sxpf.Pair) string {
+	if p, ok := args.GetFirst().(*sxpf.Pair); ok {
+		if syntax, found := sexpr.GetAttributes(p).Get(""); found {
+			return syntax
+		}
+	}
+	return ""
+}
+
+func (ce *collectEnv) EvalPair(p *sxpf.Pair) (sxpf.Value, error)       { return sxpf.EvalCallOrSeq(ce, p) }
+func (ce *collectEnv) EvalSymbol(sym *sxpf.Symbol) (sxpf.Value, error) { return sym, nil }
+func (ce *collectEnv) EvalOther(val sxpf.Value) (sxpf.Value, error)    { return val, nil }
+
+func (ce *collectEnv) visitZettel(zid api.ZettelID) {
+	if ce.isMarked(zid) || ce.s.GetSlide(zid) != nil {
+		return
+	}
+	sxZettel, err := ce.sGetZettel(zid)
+	if err != nil {
+		log.Println("GETS", zid, err)
+		ce.s.addErrorSlide(newErrorSlide(zid, err.Error()))
+		ce.s.addIssue(IssueLink, ce.curZid, zid, err.Error())
+		return
+	}
+	sxMeta, sxContent := sexpr.GetMetaContent(sxZettel)
+	if sxMeta == nil || sxContent == nil {
+		log.Println("MECo", zid)
+		ce.s.addErrorSlide(newErrorSlide(zid, "Zettel has no metadata or no content."))
+		ce.s.addIssue(IssueLink, ce.curZid, zid, "Zettel has no metadata or no content.")
+		return
+	}
+
+	if vis := sxMeta.GetString(api.KeyVisibility); vis != api.ValueVisibilityPublic && !ce.s.preview {
+		ce.s.addIssue(IssueVisibility, ce.curZid, zid, vis)
+		return
+	}
+	ce.s.AdditionalSlide(zid, sxMeta, sxContent)
+	ce.push(zid)
+}
+
+func (ce *collectEnv) visitImage(zid api.ZettelID, syntax string) {
+	if IsVideoSyntax(syntax) {
+		ce.s.AddVideo(zid, syntax)
+		return
+	}
+	if ce.s.HasImage(zid) {
+		log.Println("DUPI", zid)
+		return
+	}
+
+	// TODO: check for valid visibility
+
+	data, err := ce.getZettel(zid)
+	if err != nil {
+		log.Println("GETI", err)
+		// TODO: add artificial image with error message / zid
+		ce.s.addIssue(IssueImage, ce.curZid, zid, err.Error())
+		return
+	}
+	ce.s.AddImage(zid, syntax, data)
+}
+
+// Utility function to retrieve some slide/slideset metadata.
+
+func ZettelTitleZid(sxMeta sexpr.Meta, zid api.ZettelID) *sxpf.Pair {
+	if title := sxMeta.GetPair(api.KeyTitle); !title.IsEmpty() {
+		return title
+	}
+	return sxpf.NewPair(sexpr.SymText, sxpf.NewPair(sxpf.NewString(string(zid)), nil))
+}
+
+func SlideTitle(sxMeta sexpr.Meta) *sxpf.Pair {
+	if title := sxMeta.GetPair(KeySlideTitle); title != nil && !title.IsEmpty() {
+		return title
+	}
+	return sxMeta.GetPair(api.KeyTitle)
+}
+func SlideTitleZid(sxMeta sexpr.Meta, zid api.ZettelID) *sxpf.Pair {
+	if title := SlideTitle(sxMeta); title != nil && !title.IsEmpty() {
+		return title
+	}
+	return sxpf.NewPair(sxpf.NewPair(sexpr.SymText, sxpf.NewPair(sxpf.NewString(string(zid)), nil)), nil)
+}
@@ -0,0 +1,220 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package deck
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"codeberg.org/t73fde/sxpf"
+	"zettelstore.de/c/api"
+	"zettelstore.de/c/sexpr"
+)
+
+const (
+	zidSet = api.ZettelID("20220101000000")
+	zidA   = api.ZettelID("20220101000001")
+	zidB   = api.ZettelID("20220101000002")
+	zidC   = api.ZettelID("20220101000003")
+	zidD   = api.ZettelID("20220101000004")
+)
+
+// parseContent returns the block nodes of the given s-expression.
+func parseContent(t *testing.T, src string) *sxpf.Pair {
+	t.Helper()
+	val, err := sxpf.ParseString(sexpr.Smk, src)
+	if err != nil {
+		t.Fatalf("unable to parse %q: %v", src, err)
+	}
+	content, ok := val.(*sxpf.Pair)
+	if !ok {
+		t.Fatalf("%q is not a list", src)
+	}
+	return content
+}
+
+// makeMeta returns the metadata of a slide with the given slide role.
+func makeMeta(role string) sexpr.Meta {
+	if role == "" {
+		return sexpr.Meta{}
+	}
+	return sexpr.Meta{KeySlideRole: {Key: KeySlideRole, Value: sxpf.NewString(role)}}
+}
+
+// inlineText returns the text of the first text node of the given inlines.
+func inlineText(in *sxpf.Pair) string {
+	node, ok := in.GetFirst().(*sxpf.Pair)
+	if !ok || node.GetFirst() != sexpr.SymText {
+		return ""
+	}
+	s, _ := node.GetTail().GetString()
+	return s
+}
+
+// newTestSet returns a slide set with four slides: A for both roles, B only
+// for the handout, C for both roles with two additional slides, and D only
+// for the slide show.
+func newTestSet(t *testing.T) *SlideSet {
+	s := NewMeta(zidSet, sexpr.Meta{})
+	s.AdditionalSlide(zidA, makeMeta(""), parseContent(t, `((PARA (TEXT "a")))`))
+	s.AdditionalSlide(zidB, makeMeta(SlideRoleHandout), parseContent(t, `((PARA (TEXT "b")))`))
+	s.AdditionalSlide(zidC, makeMeta(""), parseContent(t, `((PARA (TEXT "c"))
+(HEADING 1 () "c1" "c1" (TEXT "C1")) (PARA (TEXT "c1"))
+(HEADING 1 () "c2" "c2" (TEXT "C2")) (PARA (TEXT "c2")))`))
+	s.AdditionalSlide(zidD, makeMeta(SlideRoleShow), parseContent(t, `((PARA (TEXT "d")))`))
+	return s
+}
+
+type slideNumber struct {
+	zid     api.ZettelID
+	number  int
+	slideNo int
+	subs    int
+}
+
+func TestSlidesNumbering(t *testing.T) {
+	testcases := []struct {
+		role   string
+		offset int
+		exp    []slideNumber
+	}{
+		{SlideRoleShow, 1, []slideNumber{{zidA, 1, 1, 1}, {zidC, 2, 2, 3}, {zidD, 5, 5, 1}}},
+		{SlideRoleShow, 2, []slideNumber{{zidA, 2, 2, 1}, {zidC, 3, 3, 3}, {zidD, 6, 6, 1}}},
+		{SlideRoleHandout, 1, []slideNumber{{zidA, 1, 1, 1}, {zidB, 2, 0, 0}, {zidC, 3, 2, 3}}},
+		{SlideRoleHandout, 2, []slideNumber{{zidA, 2, 2, 1}, {zidB, 3, 0, 0}, {zidC, 4, 3, 3}}},
+	}
+	for _, tc := range testcases {
+		var got []slideNumber
+		for si := newTestSet(t).Slides(tc.role, tc.offset); si != nil; si = si.Next() {
+			subs := 0
+			for sub := si.Child(); sub != nil; sub = sub.Next() {
+				subs++
+			}
+			got = append(got, slideNumber{si.Slide.Zid(), si.Number, si.SlideNo, subs})
+		}
+		if len(got) != len(tc.exp) {
+			t.Errorf("%s/%d: expected %v, but got %v", tc.role, tc.offset, tc.exp, got)
+			continue
+		}
+		for i, exp := range tc.exp {
+			if got[i] != exp {
+				t.Errorf("%s/%d: slide %d: expected %v, but got %v", tc.role, tc.offset, i, exp, got[i])
+			}
+		}
+	}
+}
+
+func TestSplitChildren(t *testing.T) {
+	testcases := []struct {
+		name   string
+		src    string
+		titles []string
+		blocks []int
+	}{
+		{"no heading", `((PARA (TEXT "x")) (PARA (TEXT "y")))`, []string{"T"}, []int{2}},
+		{"level-1 headings", `((PARA (TEXT "x"))
+(HEADING 1 () "a" "a" (TEXT "A")) (PARA (TEXT "y"))
+(HEADING 1 () "b" "b" (TEXT "B")) (PARA (TEXT "z")) (PARA (TEXT "z")))`,
+			[]string{"T", "A", "B"}, []int{1, 1, 2}},
+		{"other levels", `((HEADING 2 () "a" "a" (TEXT "A")) (PARA (TEXT "y")))`, []string{"T"}, []int{2}},
+		{"empty title", `((HEADING 1 () "a" "a") (PARA (TEXT "y")))`, []string{"T"}, []int{2}},
+		{"first heading", `((HEADING 1 () "a" "a" (TEXT "A")) (PARA (TEXT "y")))`, []string{"T", "A"}, []int{0, 1}},
+	}
+	for _, tc := range testcases {
+		sl := newSlide(zidA, sexpr.Meta{}, parseContent(t, tc.src))
+		sl.title = makeTextInline("T")
+		si := &SlideInfo{Slide: sl}
+		si.SplitChildren()
+		var titles []string
+		var blocks []int
+		for sub := si.Child(); sub != nil; sub = sub.Next() {
+			titles = append(titles, inlineText(sub.Slide.Title()))
+			n := 0
+			for elem := sub.Slide.Content(); !elem.IsNil(); elem = elem.GetTail() {
+				n++
+			}
+			blocks = append(blocks, n)
+			if sub.Slide.Zid() != zidA {
+				t.Errorf("%s: child has zid %v", tc.name, sub.Slide.Zid())
+			}
+		}
+		if strings.Join(titles, ",") != strings.Join(tc.titles, ",") {
+			t.Errorf("%s: expected titles %v, but got %v", tc.name, tc.titles, titles)
+		}
+		if len(blocks) != len(tc.blocks) {
+			t.Errorf("%s: expected blocks %v, but got %v", tc.name, tc.blocks, blocks)
+			continue
+		}
+		for i, exp := range tc.blocks {
+			if blocks[i] != exp {
+				t.Errorf("%s: expected blocks %v, but got %v", tc.name, tc.blocks, blocks)
+				break
+			}
+		}
+		if last := si.LastChild(); last.Next() != nil {
+			t.Errorf("%s: last child has a successor", tc.name)
+		}
+	}
+}
+
+func TestAddSlideError(t *testing.T) {
+	s := NewMeta(zidSet, sexpr.Meta{})
+	calls := 0
+	getter := func(zid api.ZettelID) (sxpf.Value, error) {
+		calls++
+		return nil, errors.New("not reachable")
+	}
+	s.AddSlide(zidA, getter)
+	s.AddSlide(zidA, getter)
+
+	if calls != 1 {
+		t.Errorf("expected one call of the getter, but got %d", calls)
+	}
+	if zids := s.SlideZids(); len(zids) != 2 || zids[0] != zidA || zids[1] != zidA {
+		t.Errorf("expected slide %v twice, but got %v", zidA, zids)
+	}
+	sl := s.GetSlide(zidA)
+	if sl == nil {
+		t.Fatal("no slide for", zidA)
+	}
+	if got, exp := inlineText(sl.Title()), "Error: "+string(zidA); got != exp {
+		t.Errorf("expected title %q, but got %q", exp, got)
+	}
+	var texts []string
+	for elem := sl.Content(); !elem.IsNil(); elem = elem.GetTail() {
+		para, ok := elem.GetFirst().(*sxpf.Pair)
+		if !ok || para.GetFirst() != sexpr.SymPara {
+			t.Errorf("expected a paragraph, but got %v", elem.GetFirst())
+			continue
+		}
+		texts = append(texts, inlineText(para.GetTail()))
+	}
+	if len(texts) != 2 || !strings.Contains(texts[0], string(zidA)) || texts[1] != "not reachable" {
+		t.Errorf("unexpected content of error slide: %q", texts)
+	}
+	if issues := s.Issues(); len(issues) != 1 || issues[0].Kind != IssueSlide {
+		t.Errorf("expected one issue %q, but got %v", IssueSlide, issues)
+	}
+}
+
+func TestIsTrue(t *testing.T) {
+	for _, val := range []string{"true", "1", "yes", "Y", " true ", "on"} {
+		if !IsTrue(val) {
+			t.Errorf("%q must be true", val)
+		}
+	}
+	for _, val := range []string{"", "  ", "false", "0", "no", "N", "F", " false", "\tno\n"} {
+		if IsTrue(val) {
+			t.Errorf("%q must be false", val)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package deck
+
+import (
+	"log"
+
+	"codeberg.org/t73fde/sxpf"
+	"zettelstore.de/c/api"
+	"zettelstore.de/c/sexpr"
+)
+
+// MaxTransclusionDepth is the maximum number of nested text zettel that are
+// embedded into a slide. It stops endless recursion too.
+const MaxTransclusionDepth = 3
+
+var textSyntax = map[string]bool{
+	"markdown": true,
+	"md":       true,
+	"plain":    true,
+	"text":     true,
+	"txt":      true,
+	"zmk":      true,
+}
+
+// IsTextSyntax returns true, if an embedded zettel with the given syntax is
+// presented by its content, instead of an image.
+func IsTextSyntax(syntax string) bool { return textSyntax[syntax] }
+
+// Transclusion returns the content of an embedded text zettel.
+func (s *SlideSet) Transclusion(zid api.ZettelID) (*sxpf.Pair, bool) {
+	content, found := s.transclusions[zid]
+	return content, found
+}
+
+// visitTransclusion retrieves the content of an embedded text zettel. Zettel
+// that are referenced by the content, e.g. images, are collected too.
+func (ce *collectEnv) visitTransclusion(zid api.ZettelID) {
+	s := ce.s
+	if _, found := s.transclusions[zid]; found || ce.depth >= MaxTransclusionDepth {
+		return
+	}
+	sxZettel, err := ce.sGetZettel(zid)
+	if err != nil {
+		log.Println("GETT", zid, err)
+		s.addIssue(IssueLink, ce.curZid, zid, err.Error())
+		return
+	}
+	sxMeta, sxContent := sexpr.GetMetaContent(sxZettel)
+	if sxMeta == nil || sxContent == nil {
+		log.Println("MECo", zid)
+		s.addIssue(IssueLink, ce.curZid, zid, "Zettel has no metadata or no content.")
+		return
+	}
+	if vis := sxMeta.GetString(api.KeyVisibility); vis != api.ValueVisibilityPublic && !s.preview {
+		s.addIssue(IssueVisibility, ce.curZid, zid, vis)
+		return
+	}
+	if s.transclusions == nil {
+		s.transclusions = make(map[api.ZettelID]*sxpf.Pair)
+	}
+	s.transclusions[zid] = sxContent
+
+	curZid := ce.curZid
+	ce.curZid = zid
+	ce.depth++
+	sxpf.Eval(ce, sxContent)
+	ce.depth--
+	ce.curZid = curZid
+}
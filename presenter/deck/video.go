@@ -0,0 +1,69 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package deck
+
+import "zettelstore.de/c/api"
+
+// KeyCaptions is the metadata key of a video zettel that lists the zettel
+// identifiers of WebVTT zettel with captions or subtitles of the video.
+const KeyCaptions = "captions"
+
+// SyntaxWebVTT is the syntax of a zettel with captions in the WebVTT format.
+const SyntaxWebVTT = "vtt"
+
+// videoSyntax contains all syntaxes of embedded zettel that are presented as
+// a video.
+var videoSyntax = map[string]bool{
+	"mp4":  true,
+	"ogv":  true,
+	"webm": true,
+}
+
+// IsVideoSyntax returns true, if an embedded zettel with the given syntax is
+// presented as a video.
+func IsVideoSyntax(syntax string) bool { return videoSyntax[syntax] }
+
+// Video is a video zettel that is embedded in the slide set. In contrast to
+// images, the content of a video is not retrieved.
+type Video struct {
+	Syntax string
+	Tracks []Track
+}
+
+// Track is a WebVTT zettel with captions of a video.
+type Track struct {
+	Zid   api.ZettelID
+	Lang  string // language of the captions, may be empty
+	Label string // label shown by the video player, may be empty
+}
+
+func (s *SlideSet) AddVideo(zid api.ZettelID, syntax string) {
+	s.setVideo[zid] = Video{Syntax: syntax}
+}
+
+// SetVideoTracks stores the caption tracks of a video zettel.
+func (s *SlideSet) SetVideoTracks(zid api.ZettelID, tracks []Track) {
+	if video, found := s.setVideo[zid]; found {
+		video.Tracks = tracks
+		s.setVideo[zid] = video
+	}
+}
+func (s *SlideSet) GetVideo(zid api.ZettelID) (Video, bool) {
+	video, found := s.setVideo[zid]
+	return video, found
+}
+func (s *SlideSet) Videos() []api.ZettelID {
+	result := make([]api.ZettelID, 0, len(s.setVideo))
+	for zid := range s.setVideo {
+		result = append(result, zid)
+	}
+	return result
+}
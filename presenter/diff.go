@@ -0,0 +1,256 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"zettelstore.de/c/api"
+)
+
+// OutputDiff is the output type of the comparison of two renders of a slide
+// show.
+const OutputDiff = "diff"
+
+// diffSlide is a slide of a rendered slide show.
+type diffSlide struct {
+	no    string // number of the slide
+	title string // plain text
+	text  string // plain text, including the title
+}
+
+// Kinds of differences between two slides.
+const (
+	diffSame    = ""
+	diffAdded   = "added"
+	diffRemoved = "removed"
+	diffChanged = "changed"
+)
+
+type diffEntry struct {
+	kind          string
+	before, after *diffSlide
+}
+
+var (
+	reDiffSlide   = regexp.MustCompile(`(?s)<section id="\((\d+)\)"[^>]*>(.*?)</section>`)
+	reDiffHeading = regexp.MustCompile(`(?s)<h[1-6][^>]*>(.*?)</h[1-6]>`)
+	reDiffTag     = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// extractSlides returns all slides of the page of a slide show, except the
+// title slide.
+func extractSlides(page []byte) []diffSlide {
+	var result []diffSlide
+	for _, m := range reDiffSlide.FindAllSubmatch(page, -1) {
+		sl := diffSlide{no: string(m[1]), text: plainText(m[2])}
+		if h := reDiffHeading.FindSubmatch(m[2]); h != nil {
+			sl.title = plainText(h[1])
+		}
+		result = append(result, sl)
+	}
+	return result
+}
+
+// plainText removes all HTML tags and collapses white space.
+func plainText(data []byte) string {
+	text := html.UnescapeString(string(reDiffTag.ReplaceAll(data, []byte{' '})))
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// diffSlides compares the slides by their titles. Slides with the same title,
+// but a different text, are changed.
+func diffSlides(before, after []diffSlide) []diffEntry {
+	// lcs[i][j] is the length of the longest common sequence of titles of
+	// before[i:] and after[j:].
+	lcs := make([][]int, len(before)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(after)+1)
+	}
+	for i := len(before) - 1; i >= 0; i-- {
+		for j := len(after) - 1; j >= 0; j-- {
+			if before[i].title == after[j].title {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var result []diffEntry
+	i, j := 0, 0
+	for i < len(before) || j < len(after) {
+		switch {
+		case i < len(before) && j < len(after) && before[i].title == after[j].title:
+			kind := diffSame
+			if before[i].text != after[j].text {
+				kind = diffChanged
+			}
+			result = append(result, diffEntry{kind, &before[i], &after[j]})
+			i++
+			j++
+		case i < len(before) && (j >= len(after) || lcs[i+1][j] >= lcs[i][j+1]):
+			result = append(result, diffEntry{diffRemoved, &before[i], nil})
+			i++
+		default:
+			result = append(result, diffEntry{diffAdded, nil, &after[j]})
+			j++
+		}
+	}
+	return result
+}
+
+// diffVersion is a rendered slide show that is compared.
+type diffVersion struct {
+	label string
+	page  []byte
+}
+
+// storedVersion returns the page of the history that was stored last on or
+// before the given day.
+func storedVersion(cfg *slidesConfig, zid api.ZettelID, day time.Time) (diffVersion, error) {
+	name := historyEntry(filepath.Join(cfg.history, string(zid)), day, "reveal")
+	if name == "" {
+		return diffVersion{}, fmt.Errorf("%s.reveal was not rendered on or before %s", zid, day.Format("2006-01-02"))
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return diffVersion{}, err
+	}
+	stored, _ := time.Parse(historyDateFormat, strings.TrimSuffix(filepath.Base(name), ".reveal"))
+	return diffVersion{label: stored.Format("2006-01-02"), page: data}, nil
+}
+
+// processDiff compares two versions of the slide show: a stored version
+// given by the query parameter "from" (default: the last one before today)
+// with a stored version given by "to" (default: the current slide show).
+func processDiff(w http.ResponseWriter, r *http.Request, cfg *slidesConfig, zid api.ZettelID) {
+	if cfg.history == "" {
+		http.Error(w, fmt.Sprintf("No history of %s available", zid), http.StatusNotFound)
+		return
+	}
+	query := r.URL.Query()
+	versions := make([]diffVersion, 2)
+	for i, param := range []string{"from", "to"} {
+		val := query.Get(param)
+		if val == "" {
+			continue
+		}
+		day, err := time.Parse(historyDateFormat, val)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid date %q, must be YYYYMMDD", val), http.StatusBadRequest)
+			return
+		}
+		if versions[i], err = storedVersion(cfg, zid, day); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+	}
+	if versions[0].page == nil {
+		yesterday := time.Now().AddDate(0, 0, -1)
+		var err error
+		if versions[0], err = storedVersion(cfg, zid, yesterday); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+	}
+	if versions[1].page == nil {
+		rb := responseBuffer{header: make(http.Header), limit: cfg.limits.maxResponseSize}
+		// All slides are needed, even if lazy-slides is configured, and
+		// the speaker notes are part of the stored version too.
+		processSlideSet(&rb, r, cfg, zid, &revealRenderer{eager: true})
+		if (rb.status != 0 && rb.status != http.StatusOK) || rb.exceeded {
+			copyHeader(w.Header(), rb.header)
+			w.WriteHeader(rb.status)
+			w.Write(rb.buf.Bytes())
+			return
+		}
+		versions[1] = diffVersion{page: rb.buf.Bytes()}
+	}
+	writeDiffPage(w, r, cfg, zid, versions[0], versions[1])
+}
+
+const diffCSS = `<style>
+tr.added { background-color: #dfd }
+tr.removed { background-color: #fdd; text-decoration: line-through }
+tr.changed { background-color: #ffd }
+</style>
+`
+
+func writeDiffPage(w http.ResponseWriter, r *http.Request, cfg *slidesConfig, zid api.ZettelID, before, after diffVersion) {
+	lang := cfg.lang
+	if m := reHTMLLang.FindSubmatch(after.page); m != nil {
+		lang = string(m[1])
+	}
+	afterLabel := after.label
+	if afterLabel == "" {
+		afterLabel = translate(lang, "current")
+	}
+	title := translate(lang, "Changes")
+	page := cfg.newHTMLPage(OutputZettel, lang, "", cfg.getTemplate(r.Context(), OutputZettel))
+	writeHTMLHeader(w, page)
+	fmt.Fprintf(w, "<title>%s</title>\n", html.EscapeString(title))
+	io.WriteString(w, diffCSS)
+	writeHTMLBody(w, page)
+	writeNavigation(w, lang, navItem{string(zid), string(zid)}, navItem{"", html.EscapeString(title)})
+	fmt.Fprintf(w, "<h1>%s: %s &rarr; %s</h1>\n", html.EscapeString(title), html.EscapeString(before.label), html.EscapeString(afterLabel))
+
+	entries := diffSlides(extractSlides(before.page), extractSlides(after.page))
+	var added, removed, changed int
+	for _, e := range entries {
+		switch e.kind {
+		case diffAdded:
+			added++
+		case diffRemoved:
+			removed++
+		case diffChanged:
+			changed++
+		}
+	}
+	fmt.Fprintf(w, "<p>%s</p>\n", fmt.Sprintf(translate(lang, "%d slides added, %d removed, %d changed."), added, removed, changed))
+	fmt.Fprintf(w, "<table>\n<tr><th>%s</th><th>%s</th><th>%s</th><th>%s</th></tr>\n",
+		html.EscapeString(before.label), html.EscapeString(afterLabel), translate(lang, "Title"), translate(lang, "Change"))
+	for _, e := range entries {
+		beforeNo, afterNo, slTitle := "", "", ""
+		if e.before != nil {
+			beforeNo, slTitle = e.before.no, e.before.title
+		}
+		if e.after != nil {
+			afterNo, slTitle = e.after.no, e.after.title
+		}
+		fmt.Fprintf(w, "<tr class=\"%s\"><td>%s</td><td>%s</td><td>", e.kind, beforeNo, afterNo)
+		if e.kind == diffChanged {
+			fmt.Fprintf(w, "<details><summary>%s</summary><p><del>%s</del></p><p><ins>%s</ins></p></details>",
+				html.EscapeString(slTitle), html.EscapeString(e.before.text), html.EscapeString(e.after.text))
+		} else {
+			io.WriteString(w, html.EscapeString(slTitle))
+		}
+		kind := ""
+		if e.kind != diffSame {
+			kind = translate(lang, e.kind)
+		}
+		fmt.Fprintf(w, "</td><td>%s</td></tr>\n", kind)
+	}
+	io.WriteString(w, "</table>\n")
+	writeHTMLFooter(w, page, "")
+}
+
+// reHTMLLang matches the language of a HTML page.
+var reHTMLLang = regexp.MustCompile(`<html[^>]* lang="([^"]+)"`)
@@ -0,0 +1,79 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import "testing"
+
+func TestExtractSlides(t *testing.T) {
+	page := []byte(`<section id="title"><h1>Deck</h1></section>
+<section id="(1)" data-x="y"><h2>First &amp; <em>best</em></h2>
+<p>Some   text</p><aside class="notes">Note</aside></section>
+<section id="(2)"><p>No heading</p></section>`)
+	got := extractSlides(page)
+	exp := []diffSlide{
+		{no: "1", title: "First & best", text: "First & best Some text Note"},
+		{no: "2", title: "", text: "No heading"},
+	}
+	if len(got) != len(exp) {
+		t.Fatalf("expected %d slides, but got %d: %v", len(exp), len(got), got)
+	}
+	for i, sl := range got {
+		if sl != exp[i] {
+			t.Errorf("%d: expected %v, but got %v", i, exp[i], sl)
+		}
+	}
+}
+
+func TestDiffSlides(t *testing.T) {
+	before := []diffSlide{
+		{no: "1", title: "A", text: "A a"},
+		{no: "2", title: "B", text: "B b"},
+		{no: "3", title: "C", text: "C c"},
+	}
+	after := []diffSlide{
+		{no: "1", title: "A", text: "A a"},
+		{no: "2", title: "C", text: "C changed"},
+		{no: "3", title: "D", text: "D d"},
+	}
+	got := diffSlides(before, after)
+	exp := []struct {
+		kind          string
+		before, after string
+	}{
+		{diffSame, "1", "1"},
+		{diffRemoved, "2", ""},
+		{diffChanged, "3", "2"},
+		{diffAdded, "", "3"},
+	}
+	if len(got) != len(exp) {
+		t.Fatalf("expected %d entries, but got %d: %v", len(exp), len(got), got)
+	}
+	for i, e := range got {
+		beforeNo, afterNo := "", ""
+		if e.before != nil {
+			beforeNo = e.before.no
+		}
+		if e.after != nil {
+			afterNo = e.after.no
+		}
+		if e.kind != exp[i].kind || beforeNo != exp[i].before || afterNo != exp[i].after {
+			t.Errorf("%d: expected %v, but got %q %q %q", i, exp[i], e.kind, beforeNo, afterNo)
+		}
+	}
+	if got = diffSlides(before, before); len(got) != 3 {
+		t.Errorf("expected 3 entries, but got %d", len(got))
+	}
+	for _, e := range got {
+		if e.kind != diffSame {
+			t.Errorf("expected no difference, but got %q for slide %s", e.kind, e.before.no)
+		}
+	}
+}
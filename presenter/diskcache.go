@@ -0,0 +1,284 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"codeberg.org/t73fde/sxpf"
+	"zettelstore.de/c/api"
+	"zettelstore.de/c/sexpr"
+	"zettelstore.de/contrib/presenter/deck"
+)
+
+// File extensions of cached data.
+const (
+	extSexpr   = ".sxn"     // evaluated zettel
+	extContent = ".content" // zettel content, e.g. images
+)
+
+// diskCache stores evaluated zettel and zettel content in a local directory,
+// so that they need not be retrieved again after presenter was restarted.
+// Every entry is stored together with a hash of the zettel metadata. An entry
+// is valid as long as the metadata, e.g. the modification date, is unchanged.
+// Since an evaluated zettel contains its transcluded zettel, its hash also
+// covers the modification dates of all zettel it references.
+//
+// If a shared cache is given, entries are stored there too, and entries that
+// are not in the directory are retrieved from there.
+type diskCache struct {
+	hits   int64 // accessed atomically
+	misses int64 // accessed atomically
+	dir    string
+	shared *sharedCache
+}
+
+// newDiskCache creates a disk cache for the given directory. If neither a
+// directory nor a shared cache is given, no cache is used.
+func newDiskCache(dir string, shared *sharedCache) (*diskCache, error) {
+	if dir == "" {
+		if shared == nil {
+			return nil, nil
+		}
+		return &diskCache{shared: shared}, nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &diskCache{dir: dir, shared: shared}, nil
+}
+
+// metaHash returns a hash value of the given metadata.
+func metaHash(m api.ZettelMeta) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, k := range keys {
+		h.Write([]byte(k))
+		h.Write([]byte{0})
+		h.Write([]byte(m[k]))
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// dependencyHash extends the given hash value by the modification dates of
+// all referenced zettel, whose metadata is retrieved by the given function.
+func dependencyHash(hash string, refs []string, metaOf func(api.ZettelID) (api.ZettelMeta, bool)) string {
+	if len(refs) == 0 {
+		return hash
+	}
+	sort.Strings(refs)
+	h := sha256.New()
+	h.Write([]byte(hash))
+	for _, ref := range refs {
+		stamp := "-"
+		if rm, ok := metaOf(api.ZettelID(ref)); ok {
+			stamp = rm[api.KeyModified]
+			if stamp == "" {
+				stamp = metaHash(rm)
+			}
+		}
+		h.Write([]byte{'\n'})
+		h.Write([]byte(ref))
+		h.Write([]byte{0})
+		h.Write([]byte(stamp))
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+func (dc *diskCache) path(zid api.ZettelID, ext string) string {
+	return filepath.Join(dc.dir, string(zid)+ext)
+}
+
+// read returns the cached data of the zettel, if it was stored with the given
+// hash value.
+func (dc *diskCache) read(zid api.ZettelID, ext, hash string) ([]byte, bool) {
+	data, found := dc.load(zid, ext)
+	if !found {
+		atomic.AddInt64(&dc.misses, 1)
+		return nil, false
+	}
+	stored, rest, found := bytes.Cut(data, []byte{'\n'})
+	if !found || string(stored) != hash {
+		atomic.AddInt64(&dc.misses, 1)
+		return nil, false
+	}
+	atomic.AddInt64(&dc.hits, 1)
+	return rest, true
+}
+
+// load returns the stored entry, either from the directory or from the
+// shared cache.
+func (dc *diskCache) load(zid api.ZettelID, ext string) ([]byte, bool) {
+	if dc.dir != "" {
+		if data, err := os.ReadFile(dc.path(zid, ext)); err == nil {
+			return data, true
+		}
+	}
+	return dc.shared.get(string(zid) + ext)
+}
+
+// stats returns the number of stored files, their size in bytes, and the
+// number of cache hits and misses since start.
+func (dc *diskCache) stats() (files int, size int64, hits, misses int64) {
+	if entries, err := os.ReadDir(dc.dir); err == nil {
+		for _, entry := range entries {
+			if info, err := entry.Info(); err == nil && info.Mode().IsRegular() {
+				files++
+				size += info.Size()
+			}
+		}
+	}
+	return files, size, atomic.LoadInt64(&dc.hits), atomic.LoadInt64(&dc.misses)
+}
+
+// write stores the data of the zettel together with the hash value. An older
+// entry is replaced.
+func (dc *diskCache) write(zid api.ZettelID, ext, hash string, data []byte) {
+	if dc.shared != nil {
+		dc.shared.set(string(zid)+ext, append([]byte(hash+"\n"), data...))
+	}
+	if dc.dir == "" {
+		return
+	}
+	f, err := os.CreateTemp(dc.dir, string(zid)+"-*.tmp")
+	if err != nil {
+		log.Println("DSKC", zid, err)
+		return
+	}
+	_, err = f.WriteString(hash + "\n")
+	if err == nil {
+		_, err = f.Write(data)
+	}
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), dc.path(zid, ext))
+	}
+	if err != nil {
+		log.Println("DSKC", zid, err)
+		os.Remove(f.Name())
+	}
+}
+
+// wrap returns functions to retrieve zettel content and evaluated zettel that
+// use the disk cache. The metadata of all slides is given by the list, the
+// metadata of other zettel is retrieved, because it is much smaller than the
+// zettel itself. Retrieved metadata is remembered until the functions are no
+// longer used.
+func (dc *diskCache) wrap(
+	ctx context.Context, c *zsClient, l []api.ZidMetaJSON,
+	getZettel deck.GetZettelContentFunc, sGetZettel deck.SGetZettelFunc,
+) (deck.GetZettelContentFunc, deck.SGetZettelFunc) {
+	if dc == nil {
+		return getZettel, sGetZettel
+	}
+	known := make(map[api.ZettelID]api.ZettelMeta, len(l))
+	for _, zm := range l {
+		known[zm.ID] = zm.Meta
+	}
+	var mx sync.Mutex
+	fetched := map[api.ZettelID]api.ZettelMeta{}
+	getMeta := func(zid api.ZettelID) (api.ZettelMeta, bool) {
+		mx.Lock()
+		m, found := fetched[zid]
+		mx.Unlock()
+		if found {
+			return m, true
+		}
+		m, err := c.GetMeta(ctx, zid)
+		if err != nil {
+			return nil, false
+		}
+		mx.Lock()
+		fetched[zid] = m
+		mx.Unlock()
+		return m, true
+	}
+	metaOf := func(zid api.ZettelID) (api.ZettelMeta, bool) {
+		if m, found := known[zid]; found {
+			return m, true
+		}
+		return getMeta(zid)
+	}
+	hashOf := func(zid api.ZettelID) (string, bool) {
+		m, ok := metaOf(zid)
+		if !ok {
+			return "", false
+		}
+		return metaHash(m), true
+	}
+	sHashOf := func(zid api.ZettelID) (string, bool) {
+		m, ok := metaOf(zid)
+		if !ok {
+			return "", false
+		}
+		refs, found := m[api.KeyForward]
+		if !found {
+			// Metadata of a list may lack computed values, like the
+			// referenced zettel.
+			fm, ok := getMeta(zid)
+			if !ok {
+				return "", false
+			}
+			refs = fm[api.KeyForward]
+		}
+		return dependencyHash(metaHash(m), strings.Fields(refs), metaOf), true
+	}
+
+	cachedGetZettel := func(zid api.ZettelID) ([]byte, error) {
+		hash, ok := hashOf(zid)
+		if ok {
+			if data, found := dc.read(zid, extContent, hash); found {
+				return data, nil
+			}
+		}
+		data, err := getZettel(zid)
+		if err == nil && ok {
+			dc.write(zid, extContent, hash, data)
+		}
+		return data, err
+	}
+	cachedSGetZettel := func(zid api.ZettelID) (sxpf.Value, error) {
+		hash, ok := sHashOf(zid)
+		if ok {
+			if data, found := dc.read(zid, extSexpr, hash); found {
+				if val, err := sxpf.ParseBytes(sexpr.Smk, data); err == nil {
+					return val, nil
+				}
+			}
+		}
+		val, err := sGetZettel(zid)
+		if err == nil && ok {
+			var buf bytes.Buffer
+			if val.Print(&buf) == nil {
+				dc.write(zid, extSexpr, hash, buf.Bytes())
+			}
+		}
+		return val, err
+	}
+	return cachedGetZettel, cachedSGetZettel
+}
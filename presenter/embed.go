@@ -0,0 +1,85 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"log"
+	"strconv"
+
+	"zettelstore.de/c/api"
+	"zettelstore.de/contrib/presenter/deck"
+	"zettelstore.de/contrib/presenter/render"
+)
+
+// KeyImageEmbed is the prefix of the configuration keys that specify which
+// images are embedded into the generated HTML code.
+const KeyImageEmbed = "image-embed"
+
+// Values for image embedding, besides a maximum size in bytes.
+const (
+	valueEmbedAll  = "all"
+	valueEmbedNone = "none"
+)
+
+var embedOutputs = []string{deck.SlideRoleShow, deck.SlideRoleHandout}
+
+// imageSyntaxes lists the syntaxes of images that are retrieved by the slide set.
+var imageSyntaxes = []string{"gif", "jpeg", "jpg", "png", api.ValueSyntaxSVG, "webp"}
+
+// defaultImageEmbedding specifies the image embedding if none is configured.
+// A presentation references images, except SVG, because they are served by
+// presenter. A handout should be self-contained.
+var defaultImageEmbedding = map[string]render.ImageEmbedding{
+	deck.SlideRoleShow: {
+		MaxSize: render.EmbedNone,
+		Syntax:  map[string]int{api.ValueSyntaxSVG: render.EmbedAll},
+	},
+	deck.SlideRoleHandout: {MaxSize: render.EmbedAll},
+}
+
+// getImageEmbedding returns the configured image embedding for the given
+// output type, starting with its default value.
+func getImageEmbedding(m map[string]string, output string) render.ImageEmbedding {
+	def := defaultImageEmbedding[output]
+	result := render.ImageEmbedding{MaxSize: def.MaxSize, Syntax: make(map[string]int, len(def.Syntax))}
+	for syntax, maxSize := range def.Syntax {
+		result.Syntax[syntax] = maxSize
+	}
+	key := KeyImageEmbed + "-" + output
+	if maxSize, ok := parseEmbedSize(key, m[key]); ok {
+		result.MaxSize = maxSize
+		result.Syntax = make(map[string]int)
+	}
+	for _, syntax := range imageSyntaxes {
+		skey := key + "-" + syntax
+		if maxSize, ok := parseEmbedSize(skey, m[skey]); ok {
+			result.Syntax[syntax] = maxSize
+		}
+	}
+	return result
+}
+
+// parseEmbedSize parses the value of an image embedding key.
+func parseEmbedSize(key, val string) (int, bool) {
+	switch val {
+	case "":
+		return 0, false
+	case valueEmbedAll:
+		return render.EmbedAll, true
+	case valueEmbedNone:
+		return render.EmbedNone, true
+	}
+	if size, err := strconv.Atoi(val); err == nil && size >= 0 {
+		return size, true
+	}
+	log.Println("EMBD", key, val)
+	return 0, false
+}
@@ -0,0 +1,63 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"zettelstore.de/c/api"
+	"zettelstore.de/contrib/presenter/deck"
+	"zettelstore.de/contrib/presenter/render"
+)
+
+// Configuration keys for emoji shortcodes.
+const (
+	KeyEmoji       = "emoji"        // false disables the replacement of shortcodes
+	KeyEmojiZettel = "emoji-zettel" // zettel with additional shortcodes
+)
+
+// getEmojis returns the map of all emoji shortcodes: the predefined ones,
+// added / replaced by the zettel named in the configuration. Every line of
+// its content contains a shortcode and the emoji, e.g. ":zettel: 🗒️". If the
+// replacement is disabled, nil is returned.
+func getEmojis(ctx context.Context, c *zsClient, m map[string]string) render.EmojiMap {
+	if val, found := m[KeyEmoji]; found && !deck.IsTrue(val) {
+		return nil
+	}
+	emojis := render.NewEmojiMap()
+	zid := api.ZettelID(m[KeyEmojiZettel])
+	if zid == "" {
+		return emojis
+	}
+	if !zid.IsValid() {
+		log.Println("EMOJ", "invalid zettel identifier", zid)
+		return emojis
+	}
+	data, err := c.GetZettel(ctx, zid, api.PartContent)
+	if err != nil {
+		log.Println("EMOJ", zid, err)
+		return emojis
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		code := strings.Trim(fields[0], ":")
+		if code == "" || strings.Contains(code, ":") {
+			continue
+		}
+		emojis[code] = fields[1]
+	}
+	return emojis
+}
@@ -0,0 +1,63 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"log"
+
+	"zettelstore.de/contrib/presenter/render"
+)
+
+// Configuration keys for the style of endnotes. A key may be suffixed by
+// "-" and an output type to specify a value for this output type only.
+const (
+	KeyEndnoteNumbering = "endnote-numbering"
+	KeyEndnoteHeading   = "endnote-heading"
+	KeyEndnoteGrouping  = "endnote-grouping"
+)
+
+// Values for key KeyEndnoteGrouping.
+const (
+	valueGroupingMerged = "merged"
+	valueGroupingSource = "source"
+)
+
+// getOutputValue returns the configuration value of the given key for the
+// output type. A value for a specific output type overwrites the general value.
+func getOutputValue(m map[string]string, key, output string) string {
+	if val, found := m[key+"-"+output]; found {
+		return val
+	}
+	return m[key]
+}
+
+// getEndnoteStyle returns the configured endnote style for the given output
+// type.
+func getEndnoteStyle(m map[string]string, output string) render.EndnoteStyle {
+	var result render.EndnoteStyle
+	switch val := getOutputValue(m, KeyEndnoteNumbering, output); val {
+	case render.NumberingDecimal, render.NumberingLowerAlpha, render.NumberingUpperAlpha,
+		render.NumberingLowerRoman, render.NumberingUpperRoman, render.NumberingSymbols:
+		result.Numbering = val
+	case "":
+	default:
+		log.Println("NOTE", KeyEndnoteNumbering, output, val)
+	}
+	result.Heading = getOutputValue(m, KeyEndnoteHeading, output)
+	switch val := getOutputValue(m, KeyEndnoteGrouping, output); val {
+	case valueGroupingSource:
+		result.PerSource = true
+	case valueGroupingMerged, "":
+	default:
+		log.Println("NOTE", KeyEndnoteGrouping, output, val)
+	}
+	return result
+}
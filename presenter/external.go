@@ -0,0 +1,242 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"zettelstore.de/c/api"
+	"zettelstore.de/contrib/presenter/deck"
+)
+
+// KeySlideSource is the metadata key of a slide zettel, whose content is
+// retrieved from an external URL.
+const KeySlideSource = "slide-source"
+
+// Parameter of external slide content.
+const (
+	maxExternalSize      = 1 << 20          // maximum size of external content
+	maxExternalRedirects = 5                // maximum number of redirects of an URL
+	maxExternalEntries   = 64               // maximum number of cached URLs
+	maxParallelExternal  = 4                // maximum number of concurrent retrievals
+	externalTTL          = 10 * time.Minute // retrieved content is used that long
+	externalRetry        = time.Minute      // a failed retrieval is not repeated that long
+)
+
+// The external content is retrieved when a slide set is rendered, and it is
+// kept in memory for some time. It replaces the content of the slide zettel
+// only for the rendered page; the slide zettel itself is never changed. If
+// the content cannot be retrieved, the content of the slide zettel is shown.
+//
+// Since the URL is given by an author, but retrieved by zettel presenter, it
+// must not reach hosts of the internal network.
+
+// errInternalAddress is returned, if an URL references a host of the internal
+// network.
+var errInternalAddress = errors.New("internal address not allowed")
+
+// isInternalAddress returns true, if the address is not a public unicast
+// address, e.g. a private, loopback, or link-local address.
+func isInternalAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return !addr.IsValid() || addr.IsPrivate() || addr.IsLoopback() ||
+		addr.IsLinkLocalUnicast() || addr.IsMulticast() || addr.IsUnspecified() || sharedAddressSpace.Contains(addr)
+}
+
+// sharedAddressSpace is used by carrier-grade NAT (RFC 6598).
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// externalClient retrieves external content. Every connection, also of a
+// redirect, is checked after the host name was resolved, so that only public
+// addresses are reached. Proxies are not used.
+var externalClient = &http.Client{
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 10 * time.Second,
+			Control: func(_, address string, _ syscall.RawConn) error {
+				ap, err := netip.ParseAddrPort(address)
+				if err != nil {
+					return err
+				}
+				if isInternalAddress(ap.Addr()) {
+					return fmt.Errorf("%s: %w", ap.Addr(), errInternalAddress)
+				}
+				return nil
+			},
+		}).DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxExternalRedirects {
+			return errors.New("too many redirects")
+		}
+		if err := checkExternalURL(req.URL.Scheme, req.URL.Hostname()); err != nil {
+			return fmt.Errorf("redirect to %s: %w", req.URL.Redacted(), err)
+		}
+		return nil
+	},
+}
+
+// checkExternalURL checks the scheme and host of an URL before it is
+// retrieved. Host names are checked again when they are resolved.
+func checkExternalURL(scheme, host string) error {
+	if scheme != "https" && scheme != "http" {
+		return fmt.Errorf("scheme %q not allowed", scheme)
+	}
+	if host == "" || strings.EqualFold(host, "localhost") {
+		return errInternalAddress
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && isInternalAddress(addr) {
+		return errInternalAddress
+	}
+	return nil
+}
+
+// fetchExternal retrieves the content of the given URL.
+func fetchExternal(ctx context.Context, source string, timeout time.Duration) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkExternalURL(req.URL.Scheme, req.URL.Hostname()); err != nil {
+		return nil, err
+	}
+	resp, err := externalClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxExternalSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxExternalSize {
+		return nil, fmt.Errorf("content is larger than %d bytes", maxExternalSize)
+	}
+	return data, nil
+}
+
+// externalCache stores the retrieved content of external URLs.
+type externalCache struct {
+	mx      sync.Mutex
+	entries map[string]externalEntry
+	hits    int
+	misses  int
+}
+type externalEntry struct {
+	retrieved time.Time
+	data      []byte
+	err       error
+}
+
+// get returns the content of the given URL. It is retrieved, if it is not
+// stored or if it is too old. If the retrieval fails, older content is used.
+func (ec *externalCache) get(ctx context.Context, source string, timeout time.Duration) ([]byte, error) {
+	ec.mx.Lock()
+	entry, found := ec.entries[source]
+	if found && (time.Since(entry.retrieved) < externalTTL || (entry.err != nil && time.Since(entry.retrieved) < externalRetry)) {
+		ec.hits++
+		ec.mx.Unlock()
+		return entry.data, entry.err
+	}
+	ec.misses++
+	ec.mx.Unlock()
+
+	data, err := fetchExternal(ctx, source, timeout)
+	if err != nil && entry.data != nil {
+		log.Println("EXTN", source, err, "using content of", entry.retrieved.Format(time.RFC3339))
+		data, err = entry.data, nil
+	}
+	ec.mx.Lock()
+	defer ec.mx.Unlock()
+	if ec.entries == nil {
+		ec.entries = make(map[string]externalEntry)
+	}
+	if _, found = ec.entries[source]; !found && len(ec.entries) >= maxExternalEntries {
+		ec.removeOldest()
+	}
+	ec.entries[source] = externalEntry{retrieved: time.Now(), data: data, err: err}
+	return data, err
+}
+
+func (ec *externalCache) removeOldest() {
+	var oldest string
+	var oldestTime time.Time
+	for source, entry := range ec.entries {
+		if oldest == "" || entry.retrieved.Before(oldestTime) {
+			oldest, oldestTime = source, entry.retrieved
+		}
+	}
+	delete(ec.entries, oldest)
+}
+
+// stats returns the number of stored URLs, the size of their content in
+// bytes, and the number of cache hits and misses.
+func (ec *externalCache) stats() (entries, size, hits, misses int) {
+	ec.mx.Lock()
+	defer ec.mx.Unlock()
+	for _, entry := range ec.entries {
+		size += len(entry.data)
+	}
+	return len(ec.entries), size, ec.hits, ec.misses
+}
+
+// flush removes all stored content.
+func (ec *externalCache) flush() {
+	ec.mx.Lock()
+	ec.entries = nil
+	ec.mx.Unlock()
+}
+
+// addExternalContent retrieves the content of all slides with an external
+// source and sets it as their content.
+func addExternalContent(ctx context.Context, cfg *slidesConfig, slides *deck.SlideSet, l []api.ZidMetaJSON) {
+	var mx sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxParallelExternal)
+	for _, zm := range l {
+		source := zm.Meta[KeySlideSource]
+		if source == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(zid api.ZettelID, source string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			content, err := cfg.external.get(ctx, source, cfg.c.timeout)
+			if err != nil {
+				log.Println("EXTN", zid, source, err)
+				return
+			}
+			mx.Lock()
+			slides.SetExternalContent(zid, content)
+			mx.Unlock()
+		}(zm.ID, source)
+	}
+	wg.Wait()
+}
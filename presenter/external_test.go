@@ -0,0 +1,104 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestIsInternalAddress(t *testing.T) {
+	testcases := []struct {
+		addr string
+		exp  bool
+	}{
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"100.64.0.1", true},
+		{"0.0.0.0", true},
+		{"224.0.0.1", true},
+		{"::1", true},
+		{"fe80::1", true},
+		{"fd00::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"93.184.216.34", false},
+		{"2606:2800:220:1::", false},
+	}
+	for _, tc := range testcases {
+		if got := isInternalAddress(netip.MustParseAddr(tc.addr)); got != tc.exp {
+			t.Errorf("%s: expected %v, but got %v", tc.addr, tc.exp, got)
+		}
+	}
+	if !isInternalAddress(netip.Addr{}) {
+		t.Error("invalid address must be internal")
+	}
+}
+
+func TestCheckExternalURL(t *testing.T) {
+	testcases := []struct {
+		scheme, host string
+		ok           bool
+	}{
+		{"https", "example.com", true},
+		{"http", "93.184.216.34", true},
+		{"ftp", "example.com", false},
+		{"file", "", false},
+		{"https", "", false},
+		{"https", "LocalHost", false},
+		{"http", "127.0.0.1", false},
+		{"http", "::1", false},
+	}
+	for _, tc := range testcases {
+		if err := checkExternalURL(tc.scheme, tc.host); (err == nil) != tc.ok {
+			t.Errorf("%s://%s: expected ok=%v, but got %v", tc.scheme, tc.host, tc.ok, err)
+		}
+	}
+}
+
+func TestExternalCacheInternal(t *testing.T) {
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.Write([]byte("# Secret"))
+	}))
+	defer srv.Close()
+
+	var ec externalCache
+	for i := 0; i < 2; i++ {
+		if data, err := ec.get(context.Background(), srv.URL, time.Second); !errors.Is(err, errInternalAddress) || data != nil {
+			t.Errorf("expected %v, but got %q, %v", errInternalAddress, data, err)
+		}
+	}
+	if called {
+		t.Error("internal server was called")
+	}
+	if entries, _, hits, misses := ec.stats(); entries != 1 || hits != 1 || misses != 1 {
+		t.Errorf("expected 1 entry, 1 hit, 1 miss, but got %d, %d, %d", entries, hits, misses)
+	}
+}
+
+func TestExternalCacheStale(t *testing.T) {
+	ec := externalCache{entries: map[string]externalEntry{
+		"http://127.0.0.1/": {retrieved: time.Now().Add(-2 * externalTTL), data: []byte("old")},
+	}}
+	data, err := ec.get(context.Background(), "http://127.0.0.1/", time.Second)
+	if err != nil || string(data) != "old" {
+		t.Errorf("expected stored content, but got %q, %v", data, err)
+	}
+}
@@ -0,0 +1,101 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"zettelstore.de/contrib/presenter/deck"
+	"zettelstore.de/contrib/presenter/render"
+)
+
+// OutputFlashcards is the output type of the flashcard export.
+const OutputFlashcards = "flashcards"
+
+// flashcardsRenderer exports a slide set as flashcards in a tab separated
+// text file, which can be imported into Anki.
+//
+// If a slide contains top-level regions with the generic attributes
+// "question" and "answer", every question together with the following answer
+// forms a flashcard. Otherwise the title of a slide is the question and its
+// content is the answer. Slides without a title are ignored.
+type flashcardsRenderer struct {
+	cfg *slidesConfig
+}
+
+func (*flashcardsRenderer) Role() string { return OutputFlashcards }
+func (fr *flashcardsRenderer) Prepare(_ context.Context, cfg *slidesConfig) {
+	fr.cfg = cfg
+}
+func (fr *flashcardsRenderer) Render(w http.ResponseWriter, slides *deck.SlideSet, _ string) {
+	w.Header().Set("Content-Type", "text/tab-separated-values; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.txt\"", slides.Zid()))
+	io.WriteString(w, "#separator:tab\n#html:true\n#columns:Front\tBack\n")
+
+	var buf bytes.Buffer
+	he := render.New(&buf, slides, deck.SlideRoleHandout, fr.cfg.verbatim, fr.cfg.headingOffset(deck.SlideRoleHandout), fr.cfg.embedding[deck.SlideRoleHandout], fr.cfg.zettelLinks(deck.SlideRoleHandout))
+	he.SetEndnoteStyle(fr.cfg.noteStyles[deck.SlideRoleHandout])
+	he.SetHideSpeaker(true)
+	fr.cfg.setTypography(he, slides.Lang())
+	field := func() string {
+		s := flashcardField(buf.Bytes())
+		buf.Reset()
+		return s
+	}
+	offset := 1
+	if !slides.Title().IsEmpty() {
+		offset++
+	}
+	for si := slides.Slides(deck.SlideRoleHandout, offset); si != nil; si = si.Next() {
+		he.SetCurrentSlide(si)
+		he.SetUnique(render.SlideUnique(si))
+		sl := si.Slide
+		content := sl.Content()
+		if regions := render.Regions(content, "question", "answer"); len(regions) > 0 {
+			for i := 0; i < len(regions)-1; i++ {
+				if regions[i].Kind != "question" || regions[i+1].Kind != "answer" {
+					continue
+				}
+				he.EvaluateBlock(regions[i].Content)
+				front := field()
+				he.EvaluateBlock(regions[i+1].Content)
+				he.WriteEndnotes()
+				fmt.Fprintf(w, "%s\t%s\n", front, field())
+				i++
+			}
+			continue
+		}
+		title := sl.Title()
+		if title.IsEmpty() {
+			continue
+		}
+		front := flashcardField([]byte(render.EvaluateInline(he, title)))
+		he.EvaluateBlock(content)
+		he.WriteEndnotes()
+		fmt.Fprintf(w, "%s\t%s\n", front, field())
+	}
+	he.LogUnknown(slides.Zid())
+}
+
+// flashcardField prepares HTML code to be a field of a tab separated line:
+// tabs are encoded as character references and line breaks are removed.
+// Line breaks within preformatted text are kept as "<br>".
+func flashcardField(data []byte) string {
+	s := strings.TrimSpace(string(minifyHTML(data)))
+	s = strings.ReplaceAll(s, "\t", "&#9;")
+	s = strings.ReplaceAll(s, "\r", "")
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
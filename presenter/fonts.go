@@ -0,0 +1,89 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"zettelstore.de/c/api"
+)
+
+// Constants for metadata keys of a font zettel. The font family is given by
+// KeyFontFamily, which is also used for branding.
+const (
+	KeyFontStyle  = "font-style"
+	KeyFontWeight = "font-weight"
+)
+
+// fontFormat maps the syntax of a font zettel to its MIME type and to the
+// format name used in a @font-face rule.
+var fontFormat = map[string]struct{ mime, format string }{
+	"woff2": {"font/woff2", "woff2"},
+	"woff":  {"font/woff", "woff"},
+	"ttf":   {"font/ttf", "truetype"},
+	"otf":   {"font/otf", "opentype"},
+}
+
+// fontFaceCSS produces the @font-face rules for the given font zettel.
+func fontFaceCSS(ctx context.Context, c *zsClient, zids []api.ZettelID) []byte {
+	var buf bytes.Buffer
+	for _, zid := range zids {
+		m, err := c.GetMeta(ctx, zid)
+		if err != nil {
+			log.Println("FONT", zid, err)
+			continue
+		}
+		ff, found := fontFormat[m[api.KeySyntax]]
+		if !found {
+			log.Println("FNTS", zid, m[api.KeySyntax])
+			continue
+		}
+		family := cssValue(m[KeyFontFamily])
+		if family == "" {
+			family = cssValue(m[api.KeyTitle])
+		}
+		if family == "" {
+			continue
+		}
+		fmt.Fprintf(&buf, "@font-face { font-family: \"%s\"; src: url(\"%s.font\") format(\"%s\");",
+			strings.Trim(family, "\"'"), zid, ff.format)
+		if style := cssValue(m[KeyFontStyle]); style != "" {
+			fmt.Fprintf(&buf, " font-style: %s;", style)
+		}
+		if weight := cssValue(m[KeyFontWeight]); weight != "" {
+			fmt.Fprintf(&buf, " font-weight: %s;", weight)
+		}
+		buf.WriteString(" }\n")
+	}
+	return buf.Bytes()
+}
+
+func processFont(w http.ResponseWriter, r *http.Request, c *zsClient, zid api.ZettelID) {
+	m, err := c.GetMeta(r.Context(), zid)
+	if err != nil {
+		reportRetrieveError(w, zid, err, "font")
+		return
+	}
+	ff, found := fontFormat[m[api.KeySyntax]]
+	if !found {
+		http.Error(w, fmt.Sprintf("Zettel %s is not a font", zid), http.StatusNotFound)
+		return
+	}
+	if content := retrieveContent(w, r, c, zid); len(content) > 0 {
+		w.Header().Set("Content-Type", ff.mime)
+		w.Write(content)
+	}
+}
@@ -0,0 +1,62 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"zettelstore.de/c/text"
+	"zettelstore.de/contrib/presenter/deck"
+	"zettelstore.de/contrib/presenter/render"
+)
+
+// OutputGemtext is the output type of the handout as gemtext.
+const OutputGemtext = "gmi"
+
+// gemtextRenderer produces the handout as gemtext, e.g. to publish it in
+// Gemini space.
+type gemtextRenderer struct {
+	cfg *slidesConfig
+}
+
+func (*gemtextRenderer) Role() string { return OutputGemtext }
+func (gr *gemtextRenderer) Prepare(_ context.Context, cfg *slidesConfig) {
+	gr.cfg = cfg
+}
+func (gr *gemtextRenderer) Render(w http.ResponseWriter, slides *deck.SlideSet, author string) {
+	contentType := "text/gemini; charset=utf-8"
+	if lang := slides.Lang(); lang != "" {
+		contentType += "; lang=" + lang
+	}
+	w.Header().Set("Content-Type", contentType)
+	gw := render.NewGemtext(w, slides, gr.cfg.zettelLinks(deck.SlideRoleHandout))
+	offset := 1
+	if title := slides.Title(); !title.IsEmpty() {
+		offset++
+		gw.WriteHeading(1, title)
+		if subtitle := slides.Subtitle(); !subtitle.IsEmpty() {
+			gw.WriteText(text.EvaluateInlineString(subtitle))
+		}
+		for _, s := range []string{author, slides.Copyright(), slides.License()} {
+			if s != "" {
+				gw.WriteText(s)
+			}
+		}
+	}
+	for si := slides.Slides(deck.SlideRoleHandout, offset); si != nil; si = si.Next() {
+		if title := si.Slide.Title(); !title.IsEmpty() {
+			gw.WriteHeading(2, title)
+		}
+		gw.WriteBlock(si.Slide.Content(), 2)
+		gw.WriteNotes()
+	}
+}
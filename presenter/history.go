@@ -0,0 +1,180 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"zettelstore.de/c/api"
+)
+
+// Zettelstore does not keep older versions of a zettel. Therefore, zettel
+// presenter keeps the rendered slide shows and handouts in a history
+// directory, if the option "-history" is given. For every slide set, day, and
+// output, the last rendered page is stored as "ZID/YYYYMMDD.SUFFIX". The query
+// parameter "asof=YYYYMMDD" returns the page that was stored last on or
+// before the given day.
+
+// historyDateFormat is the format of the query parameter "asof" and of the
+// file names within the history directory.
+const historyDateFormat = "20060102"
+
+// historySuffix returns the suffix of the file of a rendered page, or the
+// empty string, if the page is not stored.
+func historySuffix(suffix string) string {
+	switch suffix {
+	case "reveal", "slide":
+		return "reveal"
+	case "html":
+		return "html"
+	}
+	return ""
+}
+
+// isDefaultQuery returns true, if the request does not change the content of
+// the rendered page by a query parameter, e.g. by selecting slides.
+func isDefaultQuery(r *http.Request) bool {
+	query := r.URL.Query()
+	return !query.Has("slides") && !query.Has("solutions") && !query.Has("speaker")
+}
+
+// historyWriter keeps a copy of the response, so that it can be stored in the
+// history directory. Without a response writer, the page is only kept.
+type historyWriter struct {
+	http.ResponseWriter
+	header http.Header
+	buf    bytes.Buffer
+	status int
+}
+
+func (hw *historyWriter) Header() http.Header {
+	if hw.ResponseWriter != nil {
+		return hw.ResponseWriter.Header()
+	}
+	if hw.header == nil {
+		hw.header = make(http.Header)
+	}
+	return hw.header
+}
+
+func (hw *historyWriter) WriteHeader(status int) {
+	if hw.status == 0 {
+		hw.status = status
+	}
+	if hw.ResponseWriter != nil {
+		hw.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (hw *historyWriter) Write(data []byte) (int, error) {
+	if hw.status == 0 {
+		hw.status = http.StatusOK
+	}
+	hw.buf.Write(data)
+	if hw.ResponseWriter == nil {
+		return len(data), nil
+	}
+	return hw.ResponseWriter.Write(data)
+}
+
+// store writes the copied response to the history directory, if it was
+// successful.
+func (hw *historyWriter) store(dir string, zid api.ZettelID, suffix string) {
+	if hw.status != http.StatusOK || hw.buf.Len() == 0 {
+		return
+	}
+	zidDir := filepath.Join(dir, string(zid))
+	if err := os.MkdirAll(zidDir, 0o755); err != nil {
+		log.Println("HIST", zid, err)
+		return
+	}
+	f, err := os.CreateTemp(zidDir, "*.tmp")
+	if err != nil {
+		log.Println("HIST", zid, err)
+		return
+	}
+	_, err = f.Write(hw.buf.Bytes())
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filepath.Join(zidDir, time.Now().Format(historyDateFormat)+"."+suffix))
+	}
+	if err != nil {
+		log.Println("HIST", zid, err)
+		os.Remove(f.Name())
+	}
+}
+
+// reAssetPrefix matches the path prefix of reveal.js files. It changes, if
+// zettel presenter is updated.
+var reAssetPrefix = regexp.MustCompile(`/revealjs-[0-9a-f]+/`)
+
+// processHistory writes the page of the slide set, as it was rendered on the
+// day given by the query parameter "asof", or before.
+func processHistory(w http.ResponseWriter, r *http.Request, cfg *slidesConfig, zid api.ZettelID, suffix string) {
+	asof := r.URL.Query().Get("asof")
+	day, err := time.Parse(historyDateFormat, asof)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid date %q, must be YYYYMMDD", asof), http.StatusBadRequest)
+		return
+	}
+	hsuffix := historySuffix(suffix)
+	if cfg.history == "" || hsuffix == "" {
+		http.Error(w, fmt.Sprintf("No history of %s.%s available", zid, suffix), http.StatusNotFound)
+		return
+	}
+	name := historyEntry(filepath.Join(cfg.history, string(zid)), day, hsuffix)
+	if name == "" {
+		http.Error(w, fmt.Sprintf("%s.%s was not rendered on or before %s", zid, suffix, day.Format("2006-01-02")), http.StatusNotFound)
+		return
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		log.Println("HIST", zid, err)
+		http.Error(w, fmt.Sprintf("Unable to read history of %s", zid), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(reAssetPrefix.ReplaceAll(data, []byte(assetPrefix)))
+}
+
+// historyEntry returns the name of the file that was stored last on or
+// before the given day, or the empty string.
+func historyEntry(dir string, day time.Time, suffix string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	limit := day.Format(historyDateFormat)
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		date, ext, found := strings.Cut(name, ".")
+		if found && ext == suffix && len(date) == len(historyDateFormat) && date <= limit {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	sort.Strings(names)
+	return filepath.Join(dir, names[len(names)-1])
+}
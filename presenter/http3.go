@@ -0,0 +1,37 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"errors"
+	"net/http"
+)
+
+// errNoHTTP3 is returned, if the program was built without HTTP/3 support.
+var errNoHTTP3 = errors.New("built without HTTP/3 support, build with -tags http3")
+
+// listenHTTP3 starts an HTTP/3 listener on the UDP port of the given address
+// and returns a handler for the TCP listener, which announces the HTTP/3
+// listener to clients. It is only set, if the program is built with the tag
+// "http3", since the standard library does not provide QUIC.
+var listenHTTP3 func(addr, certFile, keyFile string, h http.Handler) (http.Handler, error)
+
+// withHTTP3 returns the handler for the TCP listener, after it started an
+// HTTP/3 listener.
+func withHTTP3(addr, certFile, keyFile string, h http.Handler) (http.Handler, error) {
+	if listenHTTP3 == nil {
+		return nil, errNoHTTP3
+	}
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("HTTP/3 needs -tls-cert and -tls-key")
+	}
+	return listenHTTP3(addr, certFile, keyFile, h)
+}
@@ -0,0 +1,37 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+//go:build http3
+
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+func init() {
+	listenHTTP3 = func(addr, certFile, keyFile string, h http.Handler) (http.Handler, error) {
+		srv := &http3.Server{Addr: addr, Handler: h}
+		go func() {
+			if err := srv.ListenAndServeTLS(certFile, keyFile); err != nil {
+				log.Println("HTT3", err)
+			}
+		}()
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := srv.SetQUICHeaders(w.Header()); err != nil {
+				log.Println("HTT3", err)
+			}
+			h.ServeHTTP(w, r)
+		}), nil
+	}
+}
@@ -0,0 +1,26 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+//go:build !http3
+
+package main
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestWithHTTP3Unsupported(t *testing.T) {
+	_, err := withHTTP3(":0", "cert.pem", "key.pem", http.NotFoundHandler())
+	if !errors.Is(err, errNoHTTP3) {
+		t.Errorf("expected %v, but got %v", errNoHTTP3, err)
+	}
+}
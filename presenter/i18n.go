@@ -0,0 +1,244 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import "strings"
+
+// translations maps a language to the translated texts of the user interface.
+// The english text is used as a key.
+var translations = map[string]map[string]string{
+	"de": {
+		"%d slides added, %d removed, %d changed.":                    "%d Folien hinzugefügt, %d entfernt, %d geändert.",
+		"A code block contains %d lines, at most %d are recommended.": "Ein Codeblock enthält %d Zeilen, höchstens %d werden empfohlen.",
+		"Accessibility":        "Barrierefreiheit",
+		"added":                "hinzugefügt",
+		"Agenda":               "Agenda",
+		"All zettel":           "Alle Zettel",
+		"Author":               "Autor",
+		"Black screen":         "Schwarzer Bildschirm",
+		"Broken zettel link":   "Defekter Zettel-Link",
+		"Change":               "Änderung",
+		"changed":              "geändert",
+		"Changes":              "Änderungen",
+		"Check external links": "Externe Links prüfen",
+		"Check":                "Prüfung",
+		"Color %s has a contrast of %.1f:1 to a white background, at least %.1f:1 is needed.": "Farbe %s hat einen Kontrast von %.1f:1 zu weißem Hintergrund, mindestens %.1f:1 ist nötig.",
+		"Contact sheet":                  "Kontaktabzug",
+		"Created":                        "Erstellt",
+		"current":                        "aktuell",
+		"Date":                           "Datum",
+		"Download for offline use":       "Für die Offline-Nutzung herunterladen",
+		"Download":                       "Herunterladen",
+		"Duration":                       "Dauer",
+		"External links":                 "Externe Links",
+		"First / last slide":             "Erste / letzte Folie",
+		"Flashcards":                     "Lernkarten",
+		"Fullscreen":                     "Vollbild",
+		"Handout":                        "Handout",
+		"Heading level h%d follows h%d.": "Überschrift h%d folgt auf h%d.",
+		"High contrast":                  "Hoher Kontrast",
+		"Home":                           "Start",
+		"Image %s has %d×%d pixel, which is larger than %d×%d pixel.": "Bild %s hat %d×%d Pixel, das ist größer als %d×%d Pixel.",
+		"Image %s has no alternative text.":                           "Bild %s hat keinen Alternativtext.",
+		"Invalid metadata value":                                      "Ungültiger Metadatenwert",
+		"Keyboard shortcuts":                                          "Tastaturkürzel",
+		"Larger text":                                                 "Größere Schrift",
+		"Last change":                                                 "Letzte Änderung",
+		"Limit exceeded":                                              "Grenze überschritten",
+		"Message":                                                     "Meldung",
+		"Mirror":                                                      "Spiegeln",
+		"Missing image":                                               "Fehlendes Bild",
+		"Modified":                                                    "Geändert",
+		"Next slide":                                                  "Nächste Folie",
+		"Next":                                                        "Weiter",
+		"No accessibility problems found.":                            "Keine Probleme der Barrierefreiheit gefunden.",
+		"No design problems found.":                                   "Keine Gestaltungsprobleme gefunden.",
+		"No external links found.":                                    "Keine externen Links gefunden.",
+		"not checked":                                                 "nicht geprüft",
+		"not reachable":                                               "nicht erreichbar",
+		"No notes":                                                    "Keine Notizen",
+		"No problems found.":                                          "Keine Probleme gefunden.",
+		"Normal text size":                                            "Normale Schriftgröße",
+		"Overview of all slides":                                      "Übersicht aller Folien",
+		"Please ask the operator of zettel presenter to change the limit.": "Bitten Sie den Betreiber von Zettel Presenter, die Grenze zu ändern.",
+		"Preview":                      "Vorschau",
+		"Previous slide":               "Vorherige Folie",
+		"Previous":                     "Zurück",
+		"Problem":                      "Problem",
+		"Problems":                     "Probleme",
+		"Reading time":                 "Lesezeit",
+		"Recently viewed":              "Zuletzt angesehen",
+		"Reference":                    "Verweis",
+		"removed":                      "entfernt",
+		"Resume at slide %s?":          "Bei Folie %s fortsetzen?",
+		"Resume":                       "Fortsetzen",
+		"Reveal":                       "Präsentation",
+		"S.":                           "F.",
+		"Search":                       "Suchen",
+		"Search: ":                     "Suche: ",
+		"Selected zettel":              "Ausgewählte Zettel",
+		"Show / hide this help":        "Diese Hilfe zeigen / verbergen",
+		"Skip to content":              "Zum Inhalt springen",
+		"Slide %d of %d":               "Folie %d von %d",
+		"Slide could not be retrieved": "Folie konnte nicht gelesen werden",
+		"Slide design":                 "Folien-Gestaltung",
+		"Slide sets":                   "Foliensätze",
+		"Slide":                        "Folie",
+		"Slides":                       "Folien",
+		"Smaller text":                 "Kleinere Schrift",
+		"Sort: ":                       "Sortierung: ",
+		"Space":                        "Leertaste",
+		"Speaker notes":                "Notizen für Vortragende",
+		"Speaker view":                 "Referentenansicht",
+		"Speed":                        "Geschwindigkeit",
+		"Start / stop":                 "Start / Stopp",
+		"Start from the beginning":     "Von vorne beginnen",
+		"Table of contents":            "Inhaltsverzeichnis",
+		"Teleprompter":                 "Teleprompter",
+		"Text size":                    "Textgröße",
+		"Thank you!":                   "Vielen Dank!",
+		"The response could not be produced within %s.":                        "Die Antwort konnte nicht innerhalb von %s erstellt werden.",
+		"The response is larger than %d bytes.":                                "Die Antwort ist größer als %d Bytes.",
+		"The slide contains %d levels of lists, at most %d are recommended.":   "Die Folie enthält %d Listenebenen, höchstens %d werden empfohlen.",
+		"The slide contains %d words, at most %d are recommended.":             "Die Folie enthält %d Wörter, höchstens %d werden empfohlen.",
+		"The slide has no title.":                                              "Die Folie hat keinen Titel.",
+		"The slide set contains %d slides, but at most %d slides are allowed.": "Der Foliensatz enthält %d Folien, erlaubt sind höchstens %d Folien.",
+		"The slide set specifies no language.":                                 "Der Foliensatz gibt keine Sprache an.",
+		"Title":                                                                "Titel",
+		"Video %s has no captions.":                                            "Video %s hat keine Untertitel.",
+		"Zettel skipped due to visibility":                                     "Zettel wegen Sichtbarkeit übersprungen",
+		"Zettel":                                                               "Zettel",
+		"Zettelstore is not reachable. This page was rendered at":              "Zettelstore ist nicht erreichbar. Diese Seite wurde erstellt um",
+	},
+	"fr": {
+		"%d slides added, %d removed, %d changed.":                    "%d diapositives ajoutées, %d supprimées, %d modifiées.",
+		"A code block contains %d lines, at most %d are recommended.": "Un bloc de code contient %d lignes, au plus %d sont recommandées.",
+		"Accessibility":        "Accessibilité",
+		"added":                "ajoutée",
+		"Agenda":               "Ordre du jour",
+		"All zettel":           "Toutes les fiches",
+		"Author":               "Auteur",
+		"Black screen":         "Écran noir",
+		"Broken zettel link":   "Lien de fiche cassé",
+		"Change":               "Modification",
+		"changed":              "modifiée",
+		"Changes":              "Modifications",
+		"Check external links": "Vérifier les liens externes",
+		"Check":                "Vérification",
+		"Color %s has a contrast of %.1f:1 to a white background, at least %.1f:1 is needed.": "La couleur %s a un contraste de %.1f:1 sur fond blanc, au moins %.1f:1 est nécessaire.",
+		"Contact sheet":                  "Planche contact",
+		"Created":                        "Créé",
+		"current":                        "actuel",
+		"Date":                           "Date",
+		"Download for offline use":       "Télécharger pour une utilisation hors ligne",
+		"Download":                       "Télécharger",
+		"Duration":                       "Durée",
+		"External links":                 "Liens externes",
+		"First / last slide":             "Première / dernière diapositive",
+		"Flashcards":                     "Fiches",
+		"Fullscreen":                     "Plein écran",
+		"Handout":                        "Polycopié",
+		"Heading level h%d follows h%d.": "Le titre h%d suit h%d.",
+		"High contrast":                  "Contraste élevé",
+		"Home":                           "Accueil",
+		"Image %s has %d×%d pixel, which is larger than %d×%d pixel.": "L'image %s a %d×%d pixels, ce qui est plus grand que %d×%d pixels.",
+		"Image %s has no alternative text.":                           "L'image %s n'a pas de texte alternatif.",
+		"Invalid metadata value":                                      "Valeur de métadonnée invalide",
+		"Keyboard shortcuts":                                          "Raccourcis clavier",
+		"Larger text":                                                 "Texte plus grand",
+		"Last change":                                                 "Dernière modification",
+		"Limit exceeded":                                              "Limite dépassée",
+		"Message":                                                     "Message",
+		"Mirror":                                                      "Miroir",
+		"Missing image":                                               "Image manquante",
+		"Modified":                                                    "Modifié",
+		"Next slide":                                                  "Diapositive suivante",
+		"Next":                                                        "Suivant",
+		"No accessibility problems found.":                            "Aucun problème d'accessibilité trouvé.",
+		"No design problems found.":                                   "Aucun problème de conception trouvé.",
+		"No external links found.":                                    "Aucun lien externe trouvé.",
+		"not checked":                                                 "non vérifié",
+		"not reachable":                                               "inaccessible",
+		"No notes":                                                    "Pas de notes",
+		"No problems found.":                                          "Aucun problème trouvé.",
+		"Normal text size":                                            "Taille normale du texte",
+		"Overview of all slides":                                      "Vue d'ensemble des diapositives",
+		"Please ask the operator of zettel presenter to change the limit.": "Veuillez demander à l'opérateur de zettel presenter de modifier la limite.",
+		"Preview":                      "Aperçu",
+		"Previous slide":               "Diapositive précédente",
+		"Previous":                     "Précédent",
+		"Problem":                      "Problème",
+		"Problems":                     "Problèmes",
+		"Reading time":                 "Temps de lecture",
+		"Recently viewed":              "Consultés récemment",
+		"Reference":                    "Référence",
+		"removed":                      "supprimée",
+		"Resume at slide %s?":          "Reprendre à la diapositive %s ?",
+		"Resume":                       "Reprendre",
+		"Reveal":                       "Présentation",
+		"S.":                           "D.",
+		"Search":                       "Rechercher",
+		"Search: ":                     "Recherche : ",
+		"Selected zettel":              "Fiches sélectionnées",
+		"Show / hide this help":        "Afficher / masquer cette aide",
+		"Skip to content":              "Aller au contenu",
+		"Slide %d of %d":               "Diapositive %d sur %d",
+		"Slide could not be retrieved": "La diapositive n'a pas pu être récupérée",
+		"Slide design":                 "Conception des diapositives",
+		"Slide sets":                   "Présentations",
+		"Slide":                        "Diapositive",
+		"Slides":                       "Diapositives",
+		"Smaller text":                 "Texte plus petit",
+		"Sort: ":                       "Tri : ",
+		"Space":                        "Espace",
+		"Speaker notes":                "Notes de l'orateur",
+		"Speaker view":                 "Mode présentateur",
+		"Speed":                        "Vitesse",
+		"Start / stop":                 "Démarrer / arrêter",
+		"Start from the beginning":     "Recommencer au début",
+		"Table of contents":            "Table des matières",
+		"Teleprompter":                 "Téléprompteur",
+		"Text size":                    "Taille du texte",
+		"Thank you!":                   "Merci !",
+		"The response could not be produced within %s.":                        "La réponse n'a pas pu être produite en %s.",
+		"The response is larger than %d bytes.":                                "La réponse dépasse %d octets.",
+		"The slide contains %d levels of lists, at most %d are recommended.":   "La diapositive contient %d niveaux de listes, au plus %d sont recommandés.",
+		"The slide contains %d words, at most %d are recommended.":             "La diapositive contient %d mots, au plus %d sont recommandés.",
+		"The slide has no title.":                                              "La diapositive n'a pas de titre.",
+		"The slide set contains %d slides, but at most %d slides are allowed.": "La présentation contient %d diapositives, mais au plus %d diapositives sont autorisées.",
+		"The slide set specifies no language.":                                 "Le jeu de diapositives n'indique aucune langue.",
+		"Title":                                                                "Titre",
+		"Video %s has no captions.":                                            "La vidéo %s n'a pas de sous-titres.",
+		"Zettel skipped due to visibility":                                     "Fiche ignorée en raison de sa visibilité",
+		"Zettel":                                                               "Fiche",
+		"Zettelstore is not reachable. This page was rendered at":              "Zettelstore n'est pas joignable. Cette page a été générée à",
+	},
+}
+
+// translate returns the text translated into the given language. If there is
+// no translation, the text itself is returned.
+func translate(lang, text string) string {
+	lang = strings.ToLower(lang)
+	for lang != "" {
+		if texts, found := translations[lang]; found {
+			if result, found2 := texts[text]; found2 {
+				return result
+			}
+			return text
+		}
+		pos := strings.LastIndexAny(lang, "-_")
+		if pos < 0 {
+			break
+		}
+		lang = lang[:pos]
+	}
+	return text
+}
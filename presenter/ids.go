@@ -0,0 +1,91 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+
+	"zettelstore.de/c/api"
+)
+
+// idWriter remembers all data written, to check for duplicate ids after the
+// document was rendered. It also remembers where the content of a zettel
+// starts, to report problems with the originating zettel.
+type idWriter struct {
+	http.ResponseWriter
+	buf   bytes.Buffer
+	marks []zidMark
+}
+
+type zidMark struct {
+	pos int
+	zid api.ZettelID
+}
+
+// markZettel notes that the following data is produced from the given zettel,
+// if the writer is an idWriter.
+func markZettel(w io.Writer, zid api.ZettelID) {
+	if iw, ok := w.(*idWriter); ok {
+		iw.marks = append(iw.marks, zidMark{iw.buf.Len(), zid})
+	}
+}
+
+// zidAt returns the zettel that produced the data at the given position.
+func (iw *idWriter) zidAt(pos int, defZid api.ZettelID) api.ZettelID {
+	zid := defZid
+	for _, mark := range iw.marks {
+		if mark.pos > pos {
+			break
+		}
+		zid = mark.zid
+	}
+	return zid
+}
+
+func (iw *idWriter) Write(p []byte) (int, error) {
+	iw.buf.Write(p)
+	return iw.ResponseWriter.Write(p)
+}
+
+var reID = regexp.MustCompile(`\sid="([^"]*)"`)
+
+// duplicateIDs returns all ids that occur more than once in the given HTML
+// document.
+func duplicateIDs(data []byte) []string {
+	seen := make(map[string]int)
+	var result []string
+	for _, match := range reID.FindAllSubmatch(data, -1) {
+		id := string(match[1])
+		seen[id]++
+		if seen[id] == 2 {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// logDuplicates logs all ids that were generated more than once.
+func (iw *idWriter) logDuplicates(zid api.ZettelID) {
+	for _, id := range duplicateIDs(iw.buf.Bytes()) {
+		log.Println("DUID", zid, id)
+	}
+}
+
+// logViolations validates the HTML document and logs all violations.
+func (iw *idWriter) logViolations(zid api.ZettelID) {
+	for _, v := range validateHTML(iw.buf.Bytes()) {
+		log.Println("HTML", iw.zidAt(v.pos, zid), v.msg)
+	}
+}
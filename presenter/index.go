@@ -0,0 +1,167 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"zettelstore.de/c/api"
+	"zettelstore.de/contrib/presenter/deck"
+)
+
+// KeyPublished is the metadata key of the computed publishing date.
+const KeyPublished = "published"
+
+// indexEntry stores all data of a slide set to be shown on the index page.
+type indexEntry struct {
+	zid      api.ZettelID
+	title    string
+	subtitle string
+	author   string
+	date     string
+	count    int
+}
+
+// slideCounts remembers the number of slides of every slide set, together
+// with its publishing date. Only if a slide set was changed, its order must
+// be retrieved again to show the index page.
+type slideCounts struct {
+	mx     sync.Mutex
+	counts map[api.ZettelID]slideCount
+}
+
+type slideCount struct {
+	published string
+	count     int
+}
+
+// Get returns the number of slides of the given slide set, if it is known
+// for the given publishing date.
+func (sc *slideCounts) Get(zid api.ZettelID, published string) (int, bool) {
+	sc.mx.Lock()
+	defer sc.mx.Unlock()
+	if entry, found := sc.counts[zid]; found && entry.published == published {
+		return entry.count, true
+	}
+	return 0, false
+}
+
+// Set stores the number of slides of the given slide set.
+func (sc *slideCounts) Set(zid api.ZettelID, published string, count int) {
+	sc.mx.Lock()
+	defer sc.mx.Unlock()
+	if sc.counts == nil {
+		sc.counts = make(map[api.ZettelID]slideCount)
+	}
+	sc.counts[zid] = slideCount{published: published, count: count}
+}
+
+// processIndex writes the index page. It is built from the metadata of the
+// list of all slide sets, so that Zettelstore is asked only once, except for
+// the number of slides of a slide set that is not known yet.
+func processIndex(w http.ResponseWriter, r *http.Request, cfg *slidesConfig) {
+	ctx := r.Context()
+	c := cfg.c
+	_, zl, err := c.ListZettelJSON(ctx, url.Values{api.KeyRole: {cfg.slideSetRole}})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error retrieving slide sets: %s\n", err), http.StatusBadRequest)
+		return
+	}
+	entries := make([]indexEntry, 0, len(zl))
+	for _, jm := range zl {
+		if !cfg.allow.allowsMeta(jm.ID, jm.Meta) {
+			continue
+		}
+		published := jm.Meta[KeyPublished]
+		entry := indexEntry{
+			zid:      jm.ID,
+			title:    html.EscapeString(indexTitle(jm)),
+			subtitle: html.EscapeString(jm.Meta[deck.KeySubTitle]),
+			author:   jm.Meta[deck.KeyAuthor],
+			date:     formatDate(published),
+		}
+		if entry.author == "" {
+			entry.author = cfg.author
+		}
+		if count, found := cfg.slideCounts.Get(jm.ID, published); found {
+			entry.count = count
+		} else if o, err := c.GetZettelOrder(ctx, jm.ID); err == nil {
+			entry.count = len(o.List)
+			cfg.slideCounts.Set(jm.ID, published, entry.count)
+		}
+		entries = append(entries, entry)
+	}
+
+	lang := cfg.lang
+	title := translate(lang, "Slide sets")
+	page := cfg.newHTMLPage(OutputList, lang, "", cfg.getTemplate(ctx, OutputList))
+	writeHTMLHeader(w, page)
+	cfg.branding.writeCSS(w, OutputList)
+	fmt.Fprintf(w, "<title>%s</title>\n", html.EscapeString(title))
+	writeHTMLBody(w, page)
+	writeSkipLink(w, lang)
+	cfg.branding.writeLogo(w, contentURL)
+	writeMainStart(w)
+	fmt.Fprintf(w, "<h1>%s</h1>\n", html.EscapeString(title))
+	writeSearchForm(w, lang, "")
+	if recent := cfg.recent.Entries(); len(recent) > 0 {
+		fmt.Fprintf(w, "<h2>%s</h2>\n<ul class=\"recent\" data-keynav>\n", translate(lang, "Recently viewed"))
+		for _, entry := range recent {
+			fmt.Fprintf(w, "<li><a href=\"%s.reveal\">%s</a> <small>(%s)</small></li>\n",
+				entry.zid, entry.title, entry.viewed.Format("15:04"))
+		}
+		io.WriteString(w, "</ul>\n")
+	}
+	fmt.Fprintf(w, "<table class=\"index\" data-keynav>\n<tr><th></th><th>%s</th><th>%s</th><th>%s</th><th class=\"right\">%s</th><th></th></tr>\n",
+		translate(lang, "Title"), translate(lang, "Author"), translate(lang, "Date"), translate(lang, "Slides"))
+	for _, entry := range entries {
+		fmt.Fprintf(w, "<tr><td><a href=\"%s.reveal\"><img class=\"thumb\" src=\"%s.thumb\" width=\"%d\" height=\"%d\" alt=\"\"></a></td>",
+			entry.zid, entry.zid, thumbWidth/2, thumbHeight/2)
+		fmt.Fprintf(w, "<td><a href=\"%s\">%s</a>", entry.zid, entry.title)
+		if entry.subtitle != "" {
+			fmt.Fprintf(w, "<br><small>%s</small>", entry.subtitle)
+		}
+		fmt.Fprintf(w, "</td><td>%s</td><td>%s</td><td class=\"right\">%d</td>", html.EscapeString(entry.author), entry.date, entry.count)
+		fmt.Fprintf(w, "<td><a href=\"%s.reveal\">%s</a>, <a href=\"%s.html\">%s</a></td></tr>\n",
+			entry.zid, translate(lang, "Reveal"), entry.zid, translate(lang, "Handout"))
+	}
+	io.WriteString(w, "</table>\n")
+	fmt.Fprintf(w, "<p><a href=\"list\">%s</a></p>\n", translate(lang, "All zettel"))
+	writeMainEnd(w)
+	cfg.branding.writeFooter(w)
+	writeHTMLFooter(w, page, keyNavScript)
+}
+
+// indexTitle returns the title of a slide set, as it is given in the list
+// metadata, i.e. without evaluating its markup.
+func indexTitle(jm api.ZidMetaJSON) string {
+	if title := jm.Meta[deck.KeySlideTitle]; title != "" {
+		return title
+	}
+	if title := jm.Meta[api.KeyTitle]; title != "" {
+		return title
+	}
+	return string(jm.ID)
+}
+
+// formatDate returns the date part of a Zettelstore timestamp, formatted as
+// YYYY-MM-DD.
+func formatDate(ts string) string {
+	if len(ts) < 8 {
+		return ""
+	}
+	return ts[0:4] + "-" + ts[4:6] + "-" + ts[6:8]
+}
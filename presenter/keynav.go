@@ -0,0 +1,68 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// Pages with lists of links, like the landing page of a slide set or the list
+// of all zettel, are fully operable by keyboard: a skip link leads to the main
+// content, and the arrow keys move the focus between the entries of lists
+// that are marked with the attribute "data-keynav".
+
+// writeSkipLink writes a link to the main content, which is only visible when
+// it has the focus. It must be the first element of the body.
+func writeSkipLink(w io.Writer, lang string) {
+	fmt.Fprintf(w, "<a class=\"skip-link\" href=\"#main\">%s</a>\n", translate(lang, "Skip to content"))
+}
+
+// writeMainStart starts the main content, the target of the skip link.
+func writeMainStart(w io.Writer) { io.WriteString(w, "<main id=\"main\" tabindex=\"-1\">\n") }
+
+// writeMainEnd ends the main content.
+func writeMainEnd(w io.Writer) { io.WriteString(w, "</main>\n") }
+
+// keyNavScript lets the arrow keys move the focus to the first link of the
+// next or previous entry of a list, Home and End to the first and last entry.
+// The key "/" moves the focus to the search field.
+const keyNavScript = `<script>(function() {
+function entries(list) {
+return Array.prototype.filter.call(list.querySelectorAll("li, tr"), function(e) { return e.parentNode === list || e.parentNode.parentNode === list; });
+}
+document.addEventListener("keydown", function(ev) {
+if (ev.altKey || ev.ctrlKey || ev.metaKey) { return; }
+var t = ev.target;
+if (/^(INPUT|TEXTAREA|SELECT)$/.test(t.tagName)) { return; }
+if (ev.key === "/") {
+var search = document.querySelector("input[type=search]");
+if (search) { ev.preventDefault(); search.focus(); }
+return;
+}
+var list = t.closest("[data-keynav]");
+if (!list) { return; }
+var items = entries(list).filter(function(e) { return e.querySelector("a[href]"); });
+var cur = items.findIndex(function(e) { return e.contains(t); });
+var next;
+switch (ev.key) {
+case "ArrowDown": next = Math.min(cur + 1, items.length - 1); break;
+case "ArrowUp": next = Math.max(cur - 1, 0); break;
+case "Home": next = 0; break;
+case "End": next = items.length - 1; break;
+default: return;
+}
+if (cur < 0 || next === cur) { return; }
+ev.preventDefault();
+items[next].querySelector("a[href]").focus();
+});
+})();</script>
+`
@@ -0,0 +1,123 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+
+	"zettelstore.de/contrib/presenter/deck"
+	"zettelstore.de/contrib/presenter/render"
+)
+
+// landingAction is a link on the landing page of a slide set.
+type landingAction struct {
+	suffix string
+	query  string
+	label  string
+}
+
+var landingActions = []landingAction{
+	{"reveal", "", "Reveal"},
+	{"html", "", "Handout"},
+	{"reveal", "speaker", "Speaker view"},
+	{OutputNotes, "", "Speaker notes"},
+	{OutputPrompter, "", "Teleprompter"},
+	{OutputContact, "", "Contact sheet"},
+	{OutputFlashcards, "", "Flashcards"},
+	{OutputBundle, "", "Download"},
+	{OutputGemtext, "", "Gemtext"},
+	{OutputAsciiDoc, "", "AsciiDoc"},
+	{"check", "", "Check"},
+}
+
+// renderLandingPage writes the landing page of a slide set: a summary of its
+// metadata, links to all presentation forms, and a table of contents.
+func renderLandingPage(w http.ResponseWriter, slides *deck.SlideSet, page *htmlPage, userCSS []byte, author string) {
+	offset, title, htmlTitle, subtitle := 1, slides.Title(), "", slides.Subtitle()
+	if !title.IsEmpty() {
+		offset++
+		htmlTitle = render.EvaluateInline(nil, title)
+	}
+
+	type tocEntry struct {
+		number int
+		title  string
+	}
+	var toc []tocEntry
+	if !title.IsEmpty() {
+		toc = append(toc, tocEntry{1, htmlTitle})
+	}
+	lastSlideNo := offset - 1
+	for si := slides.Slides(deck.SlideRoleShow, offset); si != nil; si = si.Next() {
+		var slideTitle string
+		if t := si.Slide.Title(); !t.IsEmpty() {
+			slideTitle = render.EvaluateInline(nil, t)
+		} else {
+			slideTitle = string(si.Slide.Zid())
+		}
+		toc = append(toc, tocEntry{si.Number, slideTitle})
+		lastSlideNo = si.LastChild().SlideNo
+	}
+
+	lang := page.Lang
+	writeHTMLHeader(w, page)
+	writeCSS(w, userCSS)
+	writeTitle(w, title)
+	writeHTMLBody(w, page)
+	writeSkipLink(w, lang)
+	writeNavigation(w, lang, navItem{"", slides.HTMLTitle()})
+	writeMainStart(w)
+	if !title.IsEmpty() {
+		fmt.Fprintf(w, "<h1>%s</h1>\n", htmlTitle)
+		if !subtitle.IsEmpty() {
+			fmt.Fprintf(w, "<h2>%s</h2>\n", render.EvaluateInline(nil, subtitle))
+		}
+	}
+
+	io.WriteString(w, "<dl class=\"summary\">\n")
+	writeSummaryItem(w, translate(lang, "Author"), html.EscapeString(author))
+	if minutes, ok := deck.ParseDuration(slides.Duration()); ok {
+		writeSummaryItem(w, translate(lang, "Duration"), fmt.Sprintf("%d min", minutes))
+	}
+	writeSummaryItem(w, translate(lang, "Last change"), formatDate(slides.Modified()))
+	writeSummaryItem(w, translate(lang, "Slides"), fmt.Sprint(lastSlideNo))
+	io.WriteString(w, "</dl>\n")
+
+	io.WriteString(w, "<p class=\"actions\">")
+	for i, action := range landingActions {
+		if i > 0 {
+			io.WriteString(w, " ")
+		}
+		href := fmt.Sprintf("%s.%s", slides.Zid(), action.suffix)
+		if action.query != "" {
+			href += "?" + action.query
+		}
+		fmt.Fprintf(w, "<a class=\"button\" href=\"%s\">%s</a>", href, translate(lang, action.label))
+	}
+	io.WriteString(w, "</p>\n")
+
+	fmt.Fprintf(w, "<ol data-keynav aria-label=\"%s\">\n", translate(lang, "Table of contents"))
+	for _, entry := range toc {
+		fmt.Fprintf(w, "<li><a href=\"%s.slide#(%d)\">%s</a></li>\n", slides.Zid(), entry.number, entry.title)
+	}
+	io.WriteString(w, "</ol>\n")
+	writeMainEnd(w)
+	writeHTMLFooter(w, page, keyNavScript)
+}
+
+func writeSummaryItem(w io.Writer, key, value string) {
+	if value != "" {
+		fmt.Fprintf(w, "<dt>%s</dt><dd>%s</dd>\n", key, value)
+	}
+}
@@ -0,0 +1,227 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Limits of the last-good cache: the maximum number of stored responses, and
+// their maximum size in bytes.
+const (
+	maxLastGood     = 512
+	maxLastGoodSize = 64 << 20
+)
+
+// lastGoodCache stores the most recent successful HTML page for every request
+// URI. If Zettelstore cannot be reached, e.g. in the middle of a conference,
+// the stored page is served instead of an error message.
+type lastGoodCache struct {
+	mx      sync.Mutex
+	entries map[string]lastGoodEntry
+	size    int          // size of all stored pages in bytes
+	stale   int          // number of stored responses served instead of an error
+	shared  *sharedCache // responses of other instances, may be nil
+}
+type lastGoodEntry struct {
+	header  http.Header
+	data    []byte
+	created time.Time
+}
+
+func (lc *lastGoodCache) get(uri string) (lastGoodEntry, bool) {
+	lc.mx.Lock()
+	entry, found := lc.entries[uri]
+	lc.mx.Unlock()
+	if !found {
+		return lc.shared.getPage(uri)
+	}
+	return entry, found
+}
+
+// stats returns the number of stored responses, their size in bytes, and the
+// number of times a stored response was served instead of an error.
+func (lc *lastGoodCache) stats() (entries, size, stale int) {
+	lc.mx.Lock()
+	defer lc.mx.Unlock()
+	return len(lc.entries), lc.size, lc.stale
+}
+
+// flush removes all stored responses.
+func (lc *lastGoodCache) flush() {
+	lc.mx.Lock()
+	lc.entries = nil
+	lc.size = 0
+	lc.mx.Unlock()
+}
+
+// set stores a page. The oldest pages are removed, if there are too many or if
+// they are too large.
+func (lc *lastGoodCache) set(uri string, entry lastGoodEntry) {
+	if len(entry.data) > maxLastGoodSize {
+		return
+	}
+	lc.shared.setPage(uri, entry)
+	lc.mx.Lock()
+	defer lc.mx.Unlock()
+	if lc.entries == nil {
+		lc.entries = make(map[string]lastGoodEntry)
+	}
+	if old, found := lc.entries[uri]; found {
+		lc.size -= len(old.data)
+		delete(lc.entries, uri)
+	}
+	for len(lc.entries) > 0 && (len(lc.entries) >= maxLastGood || lc.size+len(entry.data) > maxLastGoodSize) {
+		var oldestURI string
+		var oldest time.Time
+		for key, e := range lc.entries {
+			if oldestURI == "" || e.created.Before(oldest) {
+				oldestURI, oldest = key, e.created
+			}
+		}
+		lc.size -= len(lc.entries[oldestURI].data)
+		delete(lc.entries, oldestURI)
+	}
+	lc.entries[uri] = entry
+	lc.size += len(entry.data)
+}
+
+// unreachableKey is the key of a context value that notes whether Zettelstore
+// could not be reached while handling a request.
+type unreachableKey struct{}
+
+// withUnreachable returns a context, where calls to Zettelstore note whether
+// it could not be reached, and the flag that is set in this case.
+func withUnreachable(ctx context.Context) (context.Context, *int32) {
+	flag := new(int32)
+	return context.WithValue(ctx, unreachableKey{}, flag), flag
+}
+
+// markUnreachable notes that Zettelstore could not be reached, if the context
+// was created by withUnreachable.
+func markUnreachable(ctx context.Context) {
+	if flag, ok := ctx.Value(unreachableKey{}).(*int32); ok {
+		atomic.StoreInt32(flag, 1)
+	}
+}
+
+// responseBuffer is a http.ResponseWriter that stores the response. If a
+// limit is given, data beyond the limit is discarded.
+type responseBuffer struct {
+	header   http.Header
+	status   int
+	buf      bytes.Buffer
+	limit    int
+	exceeded bool
+}
+
+func (rb *responseBuffer) Header() http.Header { return rb.header }
+func (rb *responseBuffer) Write(p []byte) (int, error) {
+	if rb.limit > 0 && rb.buf.Len()+len(p) > rb.limit {
+		rb.exceeded = true
+		return len(p), nil
+	}
+	return rb.buf.Write(p)
+}
+func (rb *responseBuffer) WriteHeader(status int) {
+	if rb.status == 0 {
+		rb.status = status
+	}
+}
+
+// serve calls the handler function. A successful HTML page is stored, if
+// Zettelstore was reachable during the whole request. If Zettelstore could
+// not be reached, even if the handler produced a page, e.g. with error slides,
+// but a page was stored before, the stored page is sent, together with a
+// banner that the page may be out of date. If the response is too large or took too long, an
+// error page is sent instead.
+func (lc *lastGoodCache) serve(w http.ResponseWriter, r *http.Request, lang string, limits resourceLimits, fn http.HandlerFunc) {
+	if r.Method != http.MethodGet {
+		fn(w, r)
+		return
+	}
+	ctx, unreachable := withUnreachable(r.Context())
+	r = r.WithContext(ctx)
+	rb := responseBuffer{header: make(http.Header), limit: limits.maxResponseSize}
+	fn(&rb, r)
+	if rb.exceeded {
+		writeLimitPage(w, lang, fmt.Sprintf(translate(lang, "The response is larger than %d bytes."), limits.maxResponseSize))
+		return
+	}
+	if errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+		writeLimitPage(w, lang, fmt.Sprintf(translate(lang, "The response could not be produced within %s."), limits.renderTimeout))
+		return
+	}
+	status := rb.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	uri := r.URL.RequestURI()
+	if atomic.LoadInt32(unreachable) == 0 {
+		if status == http.StatusOK {
+			data := rb.buf.Bytes()
+			if rb.header.Get("Content-Type") == "" {
+				rb.header.Set("Content-Type", http.DetectContentType(data))
+			}
+			if strings.HasPrefix(rb.header.Get("Content-Type"), "text/html") {
+				lc.set(uri, lastGoodEntry{header: rb.header.Clone(), data: data, created: time.Now()})
+			}
+		}
+	} else {
+		if entry, found := lc.get(uri); found {
+			lc.mx.Lock()
+			lc.stale++
+			lc.mx.Unlock()
+			copyHeader(w.Header(), entry.header)
+			w.Write(addStaleBanner(entry.data, lang, entry.created))
+			return
+		}
+	}
+	copyHeader(w.Header(), rb.header)
+	w.WriteHeader(status)
+	w.Write(rb.buf.Bytes())
+}
+
+func copyHeader(dst, src http.Header) {
+	for key, values := range src {
+		dst[key] = append([]string(nil), values...)
+	}
+}
+
+// addStaleBanner inserts a banner after the body tag of a HTML page. Other
+// data is returned unchanged.
+func addStaleBanner(data []byte, lang string, created time.Time) []byte {
+	pos := bytes.Index(data, []byte("<body"))
+	if pos < 0 {
+		return data
+	}
+	end := bytes.IndexByte(data[pos:], '>')
+	if end < 0 {
+		return data
+	}
+	pos += end + 1
+	banner := fmt.Sprintf("\n<div class=\"zs-stale\" style=\"position: fixed; top: 0; left: 0; right: 0; z-index: 200; padding: .25rem; text-align: center; font-family: sans-serif; font-size: small; background: #ffd; border-bottom: 1px solid #cc8\">%s %s</div>\n",
+		html.EscapeString(translate(lang, "Zettelstore is not reachable. This page was rendered at")),
+		created.Format("15:04"))
+	result := make([]byte, 0, len(data)+len(banner))
+	result = append(result, data[:pos]...)
+	result = append(result, banner...)
+	return append(result, data[pos:]...)
+}
@@ -0,0 +1,80 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLastGoodServe(t *testing.T) {
+	const page = "<!DOCTYPE html>\n<html><body>%s</body></html>"
+	type step struct {
+		body        string // body of the handler
+		status      int    // status of the handler, 0 is OK
+		unreachable bool   // handler notes that Zettelstore is not reachable
+		expStatus   int
+		expBody     string // expected part of the response
+		expStale    bool   // response contains the banner
+	}
+	testcases := []struct {
+		name  string
+		steps []step
+	}{
+		{"store and serve", []step{
+			{body: "good", expStatus: http.StatusOK, expBody: "good"},
+			{status: http.StatusBadGateway, body: "error", unreachable: true, expStatus: http.StatusOK, expBody: "good", expStale: true},
+		}},
+		{"error slides are not stored", []step{
+			{body: "good", expStatus: http.StatusOK, expBody: "good"},
+			{body: "error slide", unreachable: true, expStatus: http.StatusOK, expBody: "good", expStale: true},
+			{status: http.StatusBadGateway, body: "error", unreachable: true, expStatus: http.StatusOK, expBody: "good", expStale: true},
+		}},
+		{"nothing stored", []step{
+			{body: "error slide", unreachable: true, expStatus: http.StatusOK, expBody: "error slide"},
+			{status: http.StatusBadGateway, body: "error", unreachable: true, expStatus: http.StatusBadGateway, expBody: "error"},
+		}},
+		{"other errors", []step{
+			{body: "good", expStatus: http.StatusOK, expBody: "good"},
+			{status: http.StatusNotFound, body: "missing", expStatus: http.StatusNotFound, expBody: "missing"},
+		}},
+	}
+	for _, tc := range testcases {
+		lc := &lastGoodCache{}
+		for i, st := range tc.steps {
+			handler := func(w http.ResponseWriter, r *http.Request) {
+				if st.unreachable {
+					markUnreachable(r.Context())
+				}
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				if st.status != 0 {
+					w.WriteHeader(st.status)
+				}
+				io.WriteString(w, strings.Replace(page, "%s", st.body, 1))
+			}
+			rec := httptest.NewRecorder()
+			lc.serve(rec, httptest.NewRequest(http.MethodGet, "/20220101000000.reveal", nil), "en", resourceLimits{}, handler)
+			if rec.Code != st.expStatus {
+				t.Errorf("%s/%d: expected status %d, but got %d", tc.name, i, st.expStatus, rec.Code)
+			}
+			body := rec.Body.String()
+			if !strings.Contains(body, st.expBody) {
+				t.Errorf("%s/%d: %q not found in %q", tc.name, i, st.expBody, body)
+			}
+			if stale := strings.Contains(body, "zs-stale"); stale != st.expStale {
+				t.Errorf("%s/%d: expected banner %v, but got %v", tc.name, i, st.expStale, stale)
+			}
+		}
+	}
+}
@@ -0,0 +1,108 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"zettelstore.de/c/api"
+	"zettelstore.de/contrib/presenter/deck"
+)
+
+// KeyLazySlides is the configuration key for the number of slides that are
+// written into the page of a slide show. All other slides are retrieved
+// later, when they are needed.
+const KeyLazySlides = "lazy-slides"
+
+// suffixSections is the URL suffix to retrieve slides of a slide show later.
+const suffixSections = "sections"
+
+// writeLazyLoader writes a script that retrieves all remaining slides, if
+// one of the last loaded slides is shown, or if the URL references a slide
+// that is not loaded. The query parameters of the page, e.g. "preview" or
+// "slides", are sent too, so that the remaining slides match the page. After
+// loading, the referenced slide is shown.
+func writeLazyLoader(w io.Writer, zid api.ZettelID, from int) {
+	fmt.Fprintf(w, `<script>
+(function() {
+var state = 0;
+function load() {
+if (state !== 0) { return; }
+state = 1;
+var query = new URLSearchParams(window.location.search);
+query.set("from", "%d");
+fetch("%s.%s?" + query.toString()).then(function(resp) { return resp.json(); }).then(function(sections) {
+var container = document.querySelector(".reveal .slides");
+sections.forEach(function(s) { container.insertAdjacentHTML("beforeend", s); });
+state = 2;
+Reveal.sync();
+if (window.mermaid) { mermaid.init(); }
+var el = document.getElementById(hashID());
+if (el) { var i = Reveal.getIndices(el); Reveal.slide(i.h, i.v); }
+}, function() { state = 0; });
+}
+function hashID() { return decodeURIComponent(window.location.hash.replace(/^#\/?/, "")); }
+function checkHash() {
+var id = hashID();
+if (id !== "" && !document.getElementById(id)) { load(); }
+}
+if (Reveal.isReady()) { checkHash(); } else { Reveal.on("ready", checkHash); }
+window.addEventListener("hashchange", checkHash);
+Reveal.on("slidechanged", function(ev) {
+if (ev.indexh >= Reveal.getHorizontalSlides().length - 3) { load(); }
+});
+})();
+</script>
+`, from, zid, suffixSections)
+}
+
+// sectionsRenderer produces the reveal sections of all slides, starting with
+// the given number of a top-level slide, as a JSON list of strings.
+type sectionsRenderer struct {
+	revealRenderer
+	from int
+}
+
+func newSectionsRenderer(r *http.Request) *sectionsRenderer {
+	from, err := strconv.Atoi(r.URL.Query().Get("from"))
+	if err != nil || from < 0 {
+		from = 0
+	}
+	return &sectionsRenderer{from: from}
+}
+
+func (sr *sectionsRenderer) Render(w http.ResponseWriter, slides *deck.SlideSet, _ string) {
+	offset := 1
+	if !slides.Title().IsEmpty() {
+		offset++
+	}
+	var buf bytes.Buffer
+	he := sr.newGenerator(&buf, slides)
+	lang := slides.Lang()
+	sections := []string{}
+	num := 0
+	for si := slides.Slides(deck.SlideRoleShow, offset); si != nil; si = si.Next() {
+		if num >= sr.from {
+			sr.renderSection(&buf, he, slides, si, lang)
+			sections = append(sections, buf.String())
+			buf.Reset()
+		}
+		num++
+	}
+	he.LogUnknown(slides.Zid())
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sections)
+}
@@ -0,0 +1,103 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Configuration keys for resource limits.
+const (
+	KeyMaxSlides       = "max-slides"
+	KeyMaxResponseSize = "max-response-size"
+	KeyRenderTimeout   = "render-timeout"
+)
+
+// Default values of resource limits. A configured value of zero disables a
+// limit.
+const (
+	DefaultMaxSlides       = 1000
+	DefaultMaxResponseSize = 64 << 20
+	DefaultRenderTimeout   = 2 * time.Minute
+)
+
+// resourceLimits protect presenter against slide sets that would need too
+// many resources.
+type resourceLimits struct {
+	maxSlides       int           // maximum number of slides of a slide set
+	maxResponseSize int           // maximum size of a response in bytes
+	renderTimeout   time.Duration // maximum duration to produce a response
+}
+
+func getResourceLimits(m map[string]string) resourceLimits {
+	result := resourceLimits{
+		maxSlides:       DefaultMaxSlides,
+		maxResponseSize: DefaultMaxResponseSize,
+		renderTimeout:   DefaultRenderTimeout,
+	}
+	if val, found := m[KeyMaxSlides]; found {
+		if n, err := strconv.Atoi(val); err == nil && n >= 0 {
+			result.maxSlides = n
+		} else {
+			log.Println("LIMI", KeyMaxSlides, val)
+		}
+	}
+	if val, found := m[KeyMaxResponseSize]; found {
+		if n, err := strconv.Atoi(val); err == nil && n >= 0 {
+			result.maxResponseSize = n
+		} else {
+			log.Println("LIMI", KeyMaxResponseSize, val)
+		}
+	}
+	if val, found := m[KeyRenderTimeout]; found {
+		if d, err := time.ParseDuration(val); err == nil && d >= 0 {
+			result.renderTimeout = d
+		} else {
+			log.Println("LIMI", KeyRenderTimeout, val)
+		}
+	}
+	return result
+}
+
+// tooManySlides returns true, if a slide set with the given number of slides
+// must not be processed.
+func (rl *resourceLimits) tooManySlides(n int) bool {
+	return rl.maxSlides > 0 && n > rl.maxSlides
+}
+
+// writeLimitPage writes a page that explains which limit was exceeded.
+func writeLimitPage(w http.ResponseWriter, lang, msg string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	if lang == "" {
+		io.WriteString(w, "<!DOCTYPE html>\n<html>\n")
+	} else {
+		fmt.Fprintf(w, "<!DOCTYPE html>\n<html lang=\"%s\">\n", lang)
+	}
+	title := html.EscapeString(translate(lang, "Limit exceeded"))
+	fmt.Fprintf(w, "<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n</head>\n<body>\n<h1>%s</h1>\n", title, title)
+	fmt.Fprintf(w, "<p>%s</p>\n", html.EscapeString(msg))
+	fmt.Fprintf(w, "<p>%s</p>\n", html.EscapeString(translate(lang, "Please ask the operator of zettel presenter to change the limit.")))
+	io.WriteString(w, "</body>\n</html>\n")
+}
+
+// reportTooManySlides writes a page that the slide set is too large.
+func (cfg *slidesConfig) reportTooManySlides(w http.ResponseWriter, n int) {
+	writeLimitPage(w, cfg.lang, fmt.Sprintf(
+		translate(cfg.lang, "The slide set contains %d slides, but at most %d slides are allowed."),
+		n, cfg.limits.maxSlides))
+}
@@ -0,0 +1,202 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"image"
+	_ "image/gif"  // register decoder to retrieve the size of an image
+	_ "image/jpeg" // register decoder to retrieve the size of an image
+	_ "image/png"  // register decoder to retrieve the size of an image
+	"io"
+	"log"
+	"strconv"
+	"strings"
+
+	"codeberg.org/t73fde/sxpf"
+	"zettelstore.de/c/api"
+	"zettelstore.de/c/sexpr"
+	"zettelstore.de/contrib/presenter/deck"
+	"zettelstore.de/contrib/presenter/render"
+)
+
+// Configuration keys of the rules of slide linting.
+const (
+	KeyLintMaxWords       = "lint-max-words"
+	KeyLintMaxListDepth   = "lint-max-list-depth"
+	KeyLintSlideTitle     = "lint-slide-title"
+	KeyLintMaxImageWidth  = "lint-max-image-width"
+	KeyLintMaxImageHeight = "lint-max-image-height"
+	KeyLintMaxCodeLines   = "lint-max-code-lines"
+)
+
+// Default values of the linting rules. A configured value of zero disables a
+// rule.
+const (
+	DefaultLintMaxWords     = 80
+	DefaultLintMaxListDepth = 2
+	DefaultLintMaxCodeLines = 20
+)
+
+// lintRules contains the thresholds of the heuristics that find slides,
+// which are probably hard to follow for the audience.
+type lintRules struct {
+	maxWords       int  // words per slide
+	maxListDepth   int  // levels of nested lists
+	slideTitle     bool // every slide needs a title
+	maxImageWidth  int  // pixel
+	maxImageHeight int  // pixel
+	maxCodeLines   int  // lines of a code block
+}
+
+func getLintRules(m map[string]string) lintRules {
+	result := lintRules{
+		maxWords:       DefaultLintMaxWords,
+		maxListDepth:   DefaultLintMaxListDepth,
+		slideTitle:     true,
+		maxImageWidth:  screenshotWidth,
+		maxImageHeight: screenshotHeight,
+		maxCodeLines:   DefaultLintMaxCodeLines,
+	}
+	for key, target := range map[string]*int{
+		KeyLintMaxWords:       &result.maxWords,
+		KeyLintMaxListDepth:   &result.maxListDepth,
+		KeyLintMaxImageWidth:  &result.maxImageWidth,
+		KeyLintMaxImageHeight: &result.maxImageHeight,
+		KeyLintMaxCodeLines:   &result.maxCodeLines,
+	} {
+		if val, found := m[key]; found {
+			if n, err := strconv.Atoi(val); err == nil && n >= 0 {
+				*target = n
+			} else {
+				log.Println("LINT", key, val)
+			}
+		}
+	}
+	if val, found := m[KeyLintSlideTitle]; found {
+		result.slideTitle = deck.IsTrue(val)
+	}
+	return result
+}
+
+// lintIssue is a slide that is probably hard to follow.
+type lintIssue struct {
+	slideNo int
+	zid     api.ZettelID
+	msg     string
+}
+
+// lintSlides applies the rules to every slide of the slide show.
+func lintSlides(slides *deck.SlideSet, rules lintRules, lang string) []lintIssue {
+	var issues []lintIssue
+	offset := 1
+	if !slides.Title().IsEmpty() {
+		offset++
+	}
+	checkedImages := make(map[api.ZettelID]bool)
+	for si := slides.Slides(deck.SlideRoleShow, offset); si != nil; si = si.Next() {
+		for sub := si.Child(); sub != nil; sub = sub.Next() {
+			sl := sub.Slide
+			add := func(msg string, args ...interface{}) {
+				issues = append(issues, lintIssue{sub.SlideNo, sl.Zid(), fmt.Sprintf(translate(lang, msg), args...)})
+			}
+			content := sl.Content()
+			if rules.slideTitle && sl.Title().IsEmpty() {
+				add("The slide has no title.")
+			}
+			if words := render.WordCount(content); rules.maxWords > 0 && words > rules.maxWords {
+				add("The slide contains %d words, at most %d are recommended.", words, rules.maxWords)
+			}
+			if depth := listDepth(content); rules.maxListDepth > 0 && depth > rules.maxListDepth {
+				add("The slide contains %d levels of lists, at most %d are recommended.", depth, rules.maxListDepth)
+			}
+			walkNodes(content, func(node *sxpf.Pair) {
+				switch node.GetFirst() {
+				case sexpr.SymVerbatimCode:
+					code, err := node.GetTail().GetTail().GetString()
+					if err != nil {
+						return
+					}
+					lines := strings.Count(strings.TrimRight(code, "\n"), "\n") + 1
+					if rules.maxCodeLines > 0 && lines > rules.maxCodeLines {
+						add("A code block contains %d lines, at most %d are recommended.", lines, rules.maxCodeLines)
+					}
+				case sexpr.SymEmbed:
+					ref, _ := node.GetTail().GetTail().GetPair()
+					src, _ := ref.GetTail().GetString()
+					zid := api.ZettelID(src)
+					if checkedImages[zid] {
+						return
+					}
+					checkedImages[zid] = true
+					img, found := slides.GetImage(zid)
+					if !found {
+						return
+					}
+					ic, _, err := image.DecodeConfig(bytes.NewReader(img.Data))
+					if err != nil {
+						return // e.g. SVG, which can be scaled
+					}
+					if (rules.maxImageWidth > 0 && ic.Width > rules.maxImageWidth) ||
+						(rules.maxImageHeight > 0 && ic.Height > rules.maxImageHeight) {
+						add("Image %s has %d×%d pixel, which is larger than %d×%d pixel.",
+							src, ic.Width, ic.Height, rules.maxImageWidth, rules.maxImageHeight)
+					}
+				}
+			})
+		}
+	}
+	return issues
+}
+
+// listDepth returns the maximum number of nested lists.
+func listDepth(list *sxpf.Pair) int {
+	result := 0
+	for elem := list; !elem.IsNil(); elem = elem.GetTail() {
+		node, err := elem.GetPair()
+		if err != nil || node.IsNil() {
+			continue
+		}
+		var depth int
+		switch node.GetFirst() {
+		case sexpr.SymListOrdered, sexpr.SymListUnordered, sexpr.SymListQuote:
+			depth = 1 + listDepth(node.GetTail())
+		default:
+			if _, isSym := node.GetFirst().(*sxpf.Symbol); isSym {
+				depth = listDepth(node.GetTail())
+			} else {
+				depth = listDepth(node) // e.g. a list item
+			}
+		}
+		if depth > result {
+			result = depth
+		}
+	}
+	return result
+}
+
+// writeLintIssues writes the result of slide linting.
+func writeLintIssues(w io.Writer, lang string, issues []lintIssue) {
+	fmt.Fprintf(w, "<h2>%s</h2>\n", translate(lang, "Slide design"))
+	if len(issues) == 0 {
+		fmt.Fprintf(w, "<p>%s</p>\n", translate(lang, "No design problems found."))
+		return
+	}
+	fmt.Fprintf(w, "<table>\n<tr><th>%s</th><th>%s</th><th>%s</th></tr>\n",
+		translate(lang, "Slide"), translate(lang, "Zettel"), translate(lang, "Message"))
+	for _, issue := range issues {
+		fmt.Fprintf(w, "<tr><td>%d</td><td><a href=\"%s\">%s</a></td><td>%s</td></tr>\n",
+			issue.slideNo, issue.zid, issue.zid, html.EscapeString(issue.msg))
+	}
+	io.WriteString(w, "</table>\n")
+}
@@ -0,0 +1,158 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"zettelstore.de/contrib/presenter/deck"
+)
+
+// KeyMinifyHTML is the configuration key to minify the generated HTML code.
+// It may be suffixed by "-" and an output type.
+const KeyMinifyHTML = "minify-html"
+
+// rawElements contain text, where whitespace is significant.
+var rawElements = map[string]bool{
+	"code": true, "pre": true, "script": true, "style": true, "textarea": true,
+}
+
+// blockElements are elements, where whitespace before and after them is not
+// significant.
+var blockElements = map[string]bool{
+	"!doctype": true, "aside": true, "blockquote": true, "body": true, "br": true,
+	"dd": true, "div": true, "dl": true, "dt": true, "figcaption": true,
+	"figure": true, "footer": true, "h1": true, "h2": true, "h3": true, "h4": true,
+	"h5": true, "h6": true, "head": true, "header": true, "hgroup": true, "hr": true,
+	"html": true, "li": true, "link": true, "meta": true, "nav": true, "ol": true,
+	"p": true, "section": true, "table": true, "tbody": true, "td": true,
+	"tfoot": true, "th": true, "thead": true, "title": true, "tr": true, "ul": true,
+	"pre": true, "script": true, "style": true,
+}
+
+// minifyHTML removes whitespace that is not significant: whitespace next to
+// block elements is removed, other whitespace is collapsed into one space.
+// The content of elements like "pre" and "code" is not changed.
+func minifyHTML(data []byte) []byte {
+	var out bytes.Buffer
+	out.Grow(len(data))
+	raw := ""          // name of the element with unchanged content
+	afterBlock := true // last written data was a tag of a block element
+	for i := 0; i < len(data); {
+		if raw != "" {
+			// Write everything up to the closing tag unchanged.
+			end := indexClosingTag(data[i:], raw)
+			if end < 0 {
+				out.Write(data[i:])
+				break
+			}
+			out.Write(data[i : i+end])
+			i += end
+			raw = ""
+		}
+		ch := data[i]
+		if ch == '<' {
+			end := bytes.IndexByte(data[i:], '>')
+			if end < 0 {
+				out.Write(data[i:])
+				break
+			}
+			tag := data[i : i+end+1]
+			name, closing := tagName(tag)
+			if !closing && rawElements[name] {
+				raw = name
+			}
+			out.Write(tag)
+			afterBlock = blockElements[name]
+			i += end + 1
+			continue
+		}
+		if isSpace(ch) {
+			j := i + 1
+			for j < len(data) && isSpace(data[j]) {
+				j++
+			}
+			beforeBlock := j >= len(data)
+			if !beforeBlock && data[j] == '<' {
+				name, _ := tagName(data[j:])
+				beforeBlock = blockElements[name]
+			}
+			if !afterBlock && !beforeBlock {
+				out.WriteByte(' ')
+			}
+			i = j
+			continue
+		}
+		out.WriteByte(ch)
+		afterBlock = false
+		i++
+	}
+	return out.Bytes()
+}
+
+// indexClosingTag returns the position of the closing tag of the named
+// element, or -1 if there is none.
+func indexClosingTag(data []byte, name string) int {
+	closing := []byte("</" + name)
+	for pos := 0; ; pos += 2 {
+		i := bytes.Index(data[pos:], []byte("</"))
+		if i < 0 {
+			return -1
+		}
+		pos += i
+		if end := pos + len(closing); end <= len(data) && bytes.EqualFold(data[pos:end], closing) &&
+			(end == len(data) || isSpace(data[end]) || data[end] == '>') {
+			return pos
+		}
+	}
+}
+
+// tagName returns the lower case name of the tag at the start of the data,
+// and whether it is a closing tag.
+func tagName(tag []byte) (string, bool) {
+	pos := 1
+	closing := pos < len(tag) && tag[pos] == '/'
+	if closing {
+		pos++
+	}
+	end := pos
+	for end < len(tag) && !isSpace(tag[end]) && tag[end] != '>' && tag[end] != '/' {
+		end++
+	}
+	return strings.ToLower(string(tag[pos:end])), closing
+}
+
+func isSpace(ch byte) bool { return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' }
+
+// minifyWriter stores the response, until it is minified by flush.
+type minifyWriter struct {
+	http.ResponseWriter
+	buf bytes.Buffer
+}
+
+func (mw *minifyWriter) Write(p []byte) (int, error) { return mw.buf.Write(p) }
+
+// flush writes the stored response, minified if it is HTML code.
+func (mw *minifyWriter) flush() {
+	data := mw.buf.Bytes()
+	if strings.HasPrefix(mw.Header().Get("Content-Type"), "text/html") {
+		data = minifyHTML(data)
+	}
+	mw.ResponseWriter.Write(data)
+}
+
+// getMinify returns true, if the HTML code of the given output type should be
+// minified.
+func getMinify(m map[string]string, output string) bool {
+	return deck.IsTrue(getOutputValue(m, KeyMinifyHTML, output))
+}
@@ -0,0 +1,84 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import "testing"
+
+func TestMinifyHTML(t *testing.T) {
+	testcases := []struct {
+		name string
+		src  string
+		exp  string
+	}{
+		{"empty", "", ""},
+		{"block elements", "<html>\n  <body>\n    <p>Text</p>\n  </body>\n</html>\n", "<html><body><p>Text</p></body></html>"},
+		{"collapse", "<p>one  \n two\tthree</p>", "<p>one two three</p>"},
+		{"inline elements", "<p>a <em>b</em> <strong>c</strong> d</p>", "<p>a <em>b</em> <strong>c</strong> d</p>"},
+		{"pre", "<pre>\n  a\n   b\n</pre>\n<p> x </p>", "<pre>\n  a\n   b\n</pre><p>x</p>"},
+		{"code", "<p><code>a  b</code>  c</p>", "<p><code>a  b</code> c</p>"},
+		{"script", "<script>\nif (a  <  b) { x(); }\n</script>", "<script>\nif (a  <  b) { x(); }\n</script>"},
+		{"closing tag case", "<PRE>a  b</Pre> <p>x</p>", "<PRE>a  b</Pre><p>x</p>"},
+		{"similar closing tag", "<code>a  </codex>  b</code>", "<code>a  </codex>  b</code>"},
+		{"unclosed raw", "<pre>a  b", "<pre>a  b"},
+		{"unclosed tag", "<p>a  <em", "<p>a <em"},
+		{"attributes", "<div  class=\"x\">\n<span>a</span>\n</div>", "<div  class=\"x\"><span>a</span></div>"},
+		{"doctype", "<!DOCTYPE html>\n<html>", "<!DOCTYPE html><html>"},
+		{"trailing space", "<span>a</span>  ", "<span>a</span>"},
+	}
+	for _, tc := range testcases {
+		if got := string(minifyHTML([]byte(tc.src))); got != tc.exp {
+			t.Errorf("%s: expected %q, but got %q", tc.name, tc.exp, got)
+		}
+	}
+}
+
+func TestIndexClosingTag(t *testing.T) {
+	testcases := []struct {
+		data string
+		name string
+		exp  int
+	}{
+		{"abc</pre>", "pre", 3},
+		{"a</b>c</PRE >", "pre", 6},
+		{"a</prefix></pre>", "pre", 10},
+		{"a</pr", "pre", -1},
+		{"", "pre", -1},
+		{"</pre", "pre", 0},
+	}
+	for _, tc := range testcases {
+		if got := indexClosingTag([]byte(tc.data), tc.name); got != tc.exp {
+			t.Errorf("indexClosingTag(%q, %q): expected %d, but got %d", tc.data, tc.name, tc.exp, got)
+		}
+	}
+}
+
+func TestGetMinify(t *testing.T) {
+	testcases := []struct {
+		m      map[string]string
+		output string
+		exp    bool
+	}{
+		{nil, "show", false},
+		{map[string]string{KeyMinifyHTML: "true"}, "show", true},
+		{map[string]string{KeyMinifyHTML: "on"}, "show", true},
+		{map[string]string{KeyMinifyHTML: " yes"}, "show", true},
+		{map[string]string{KeyMinifyHTML: "no"}, "show", false},
+		{map[string]string{KeyMinifyHTML: " false"}, "show", false},
+		{map[string]string{KeyMinifyHTML: "0"}, "show", false},
+		{map[string]string{KeyMinifyHTML: "1", KeyMinifyHTML + "-handout": "false"}, "handout", false},
+		{map[string]string{KeyMinifyHTML: "1", KeyMinifyHTML + "-handout": "false"}, "show", true},
+	}
+	for _, tc := range testcases {
+		if got := getMinify(tc.m, tc.output); got != tc.exp {
+			t.Errorf("getMinify(%v, %q): expected %v, but got %v", tc.m, tc.output, tc.exp, got)
+		}
+	}
+}
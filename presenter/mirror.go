@@ -0,0 +1,140 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// Limits for downloading external files.
+const (
+	mirrorTimeout     = 30 * time.Second
+	mirrorMaxSize     = 16 << 20 // bytes per file
+	mirrorMaxTotal    = 64 << 20 // bytes per bundle
+	mirrorMaxFiles    = 100
+	maxParallelMirror = 4
+)
+
+// reExternalResource matches the URLs of external images, fonts, and similar
+// resources, that are referenced by a "src" attribute or by a CSS "url()".
+var reExternalResource = regexp.MustCompile(`(?:src="|url\(["']?)(https?://[^"')\s]+)`)
+
+// mirrorExternal downloads all external resources of the given HTML document,
+// stores them in the bundle below "external/", and returns the document with
+// rewritten URLs. Resources that cannot be downloaded, e.g. because they are
+// on an internal host or exceed the limits, are still referenced by their
+// original URL.
+func (b *bundle) mirrorExternal(doc []byte) []byte {
+	urls := map[string]string{}
+	for _, match := range reExternalResource.FindAllSubmatch(doc, -1) {
+		urls[string(match[1])] = ""
+	}
+	if len(urls) == 0 {
+		return doc
+	}
+
+	var mx sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxParallelMirror)
+	total, count := 0, 0
+	for u := range urls {
+		if count >= mirrorMaxFiles {
+			log.Println("MIRR", u, "more than", mirrorMaxFiles, "files")
+			continue
+		}
+		count++
+		wg.Add(1)
+		go func(u string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			data, ext, err := download(b.ctx, externalClient, u)
+			if err != nil {
+				log.Println("MIRR", u, err)
+				return
+			}
+			h := sha256.Sum256([]byte(u))
+			file := "external/" + hex.EncodeToString(h[:8]) + ext
+			mx.Lock()
+			defer mx.Unlock()
+			if total+len(data) > mirrorMaxTotal {
+				log.Println("MIRR", u, "bundle larger than", mirrorMaxTotal, "bytes")
+				return
+			}
+			total += len(data)
+			b.files[file] = data
+			urls[u] = file
+		}(u)
+	}
+	wg.Wait()
+
+	return reExternalResource.ReplaceAllFunc(doc, func(match []byte) []byte {
+		sub := reExternalResource.FindSubmatchIndex(match)
+		if file := urls[string(match[sub[2]:sub[3]])]; file != "" {
+			return append(append([]byte{}, match[:sub[2]]...), file...)
+		}
+		return match
+	})
+}
+
+// download retrieves the given URL and returns its content, together with a
+// file extension that matches its content type.
+func download(ctx context.Context, client *http.Client, u string) ([]byte, string, error) {
+	pu, err := url.Parse(u)
+	if err != nil {
+		return nil, "", err
+	}
+	if err = checkExternalURL(pu.Scheme, pu.Hostname()); err != nil {
+		return nil, "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, mirrorTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("status %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, mirrorMaxSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > mirrorMaxSize {
+		return nil, "", fmt.Errorf("larger than %d bytes", mirrorMaxSize)
+	}
+	ext := path.Ext(pu.Path)
+	if ext == "" {
+		if mt, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil {
+			if exts, err := mime.ExtensionsByType(mt); err == nil && len(exts) > 0 {
+				ext = exts[0]
+			}
+		}
+	}
+	return data, ext, nil
+}
@@ -0,0 +1,40 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMirrorExternalInternal(t *testing.T) {
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.Write([]byte("secret"))
+	}))
+	defer srv.Close()
+
+	b := &bundle{ctx: context.Background(), files: map[string][]byte{}}
+	doc := []byte(`<img src="` + srv.URL + `/img.png"><div style="background:url('http://localhost/x.png')">`)
+	got := b.mirrorExternal(doc)
+	if string(got) != string(doc) {
+		t.Errorf("document was changed: %s", got)
+	}
+	if len(b.files) != 0 {
+		t.Errorf("files were stored: %v", b.files)
+	}
+	if called {
+		t.Error("internal server was called")
+	}
+}
@@ -0,0 +1,49 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"zettelstore.de/contrib/presenter/deck"
+)
+
+// mediaReducedMotion is the media query of users that prefer reduced motion.
+const mediaReducedMotion = "(prefers-reduced-motion: reduce)"
+
+// reduceMotionCSS stops all CSS animations and transitions, e.g. of mermaid
+// diagrams.
+const reduceMotionCSS = `.reveal *, .mermaid * { animation: none !important; transition: none !important; scroll-behavior: auto !important }`
+
+// writeMotionCSS writes the CSS to reduce motion, according to the given
+// value of the slide set.
+func writeMotionCSS(w io.Writer, motion string) {
+	switch motion {
+	case deck.MotionFull:
+	case deck.MotionReduce:
+		fmt.Fprintf(w, "<style type=\"text/css\">\n%s\n</style>\n", reduceMotionCSS)
+	default:
+		fmt.Fprintf(w, "<style type=\"text/css\">\n@media %s {\n%s\n}\n</style>\n", mediaReducedMotion, reduceMotionCSS)
+	}
+}
+
+// revealReduceMotion returns a JavaScript expression that is true, if the
+// slide show should not use transitions and automatic slide changes.
+func revealReduceMotion(motion string) string {
+	switch motion {
+	case deck.MotionFull:
+		return "false"
+	case deck.MotionReduce:
+		return "true"
+	}
+	return fmt.Sprintf("window.matchMedia(%q).matches", mediaReducedMotion)
+}
@@ -0,0 +1,117 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"zettelstore.de/c/api"
+	"zettelstore.de/contrib/presenter/deck"
+)
+
+// cmdNew is the name of the command that creates a new slide set.
+const cmdNew = "new"
+
+// starterSlides are the titles and the content of the slides that are
+// created together with a new slide set.
+var starterSlides = []struct{ title, content string }{
+	{"Introduction", "* What is this talk about?\n* Why is it important?\n"},
+	{"Main Part", "Replace this text with the content of the slide.\n"},
+	{"Summary", "* What should the audience remember?\n"},
+	{"Questions", "Thank you for your attention.\n"},
+}
+
+// runNew creates a slide set zettel and some starter slide zettel, and
+// returns the exit code of the command.
+func runNew(ctx context.Context, args []string) int {
+	fs := flag.NewFlagSet(cmdNew, flag.ContinueOnError)
+	numSlides := fs.Int("slides", 3, "Number of starter slides")
+	author := fs.String("author", "", "Author of the slide set")
+	lang := fs.String("lang", "", "Language of the slide set, e.g. en")
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "Usage of %s %s:\n", os.Args[0], cmdNew)
+		fs.PrintDefaults()
+		io.WriteString(out, "  TITLE title of the slide set\n")
+		io.WriteString(out, "  [URL] URL of Zettelstore (default: \"http://127.0.0.1:23123\")\n")
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 || fs.Arg(0) == "" {
+		fs.Usage()
+		return 2
+	}
+	if *numSlides < 0 || *numSlides > len(starterSlides) {
+		fmt.Fprintf(os.Stderr, "Number of starter slides must be between 0 and %d\n", len(starterSlides))
+		return 2
+	}
+	title := fs.Arg(0)
+
+	c, err := getClient(ctx, fs.Arg(1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to connect to zettelstore: %v\n", err)
+		return 1
+	}
+	role := deck.DefaultSlideSetRole
+	if m, err := c.GetMeta(ctx, zidConfig); err == nil {
+		if ssr, found := m[deck.KeySlideSetRole]; found && ssr != "" {
+			role = ssr
+		}
+	}
+
+	var content bytes.Buffer
+	for _, slide := range starterSlides[:*numSlides] {
+		zid, err := c.CreateZettel(ctx, newZettelData(map[string]string{
+			api.KeyTitle:  slide.title,
+			api.KeySyntax: api.ValueSyntaxZmk,
+			api.KeyLang:   *lang,
+		}, slide.content))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to create slide %q: %v\n", slide.title, err)
+			return 1
+		}
+		fmt.Println("Slide:", zid, slide.title)
+		fmt.Fprintf(&content, "# [[%s|%s]]\n", slide.title, zid)
+	}
+	zid, err := c.CreateZettel(ctx, newZettelData(map[string]string{
+		api.KeyTitle:   title,
+		api.KeyRole:    role,
+		api.KeySyntax:  api.ValueSyntaxZmk,
+		api.KeyLang:    *lang,
+		deck.KeyAuthor: *author,
+	}, content.String()))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to create slide set %q: %v\n", title, err)
+		return 1
+	}
+	fmt.Println("Slide set:", zid, title)
+	return 0
+}
+
+// newZettelData returns the data of a zettel to be created. Empty metadata
+// values are omitted.
+func newZettelData(m map[string]string, content string) []byte {
+	var buf bytes.Buffer
+	for _, key := range []string{api.KeyTitle, api.KeyRole, api.KeySyntax, api.KeyLang, deck.KeyAuthor} {
+		if val := m[key]; val != "" {
+			fmt.Fprintf(&buf, "%s: %s\n", key, val)
+		}
+	}
+	buf.WriteByte('\n')
+	buf.WriteString(content)
+	return buf.Bytes()
+}
@@ -0,0 +1,92 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+
+	"zettelstore.de/contrib/presenter/deck"
+	"zettelstore.de/contrib/presenter/render"
+)
+
+// OutputNotes is the output type of the printable notes of the speaker.
+const OutputNotes = "notes"
+
+// notesRenderer produces the notes of the speaker for printing: the title of
+// every slide of the slide show, together with the notes for this slide.
+type notesRenderer struct {
+	cfg  *slidesConfig
+	tmpl *template.Template
+}
+
+func (*notesRenderer) Role() string { return OutputNotes }
+func (nr *notesRenderer) Prepare(ctx context.Context, cfg *slidesConfig) {
+	nr.cfg = cfg
+	nr.tmpl = cfg.getTemplate(ctx, OutputNotes)
+}
+func (nr *notesRenderer) Render(w http.ResponseWriter, slides *deck.SlideSet, _ string) {
+	lang := slides.Lang()
+	page := nr.cfg.newHTMLPage(OutputNotes, lang, "", nr.tmpl)
+	writeHTMLHeader(w, page)
+	io.WriteString(w, `<style type="text/css">
+body { font-size: 14pt }
+div.slide-notes { break-inside: avoid; page-break-inside: avoid; border-bottom: 1px solid lightgray; margin-bottom: 1rem }
+div.slide-notes h2 { font-size: 16pt; margin-bottom: .25rem }
+div.slide-notes span.slide-no { display: inline-block; min-width: 3em; color: gray }
+p.no-notes { color: gray; font-style: italic }
+div.script { border-left: 3px solid lightgray; padding-left: .75rem }
+@media print { nav.breadcrumb { display: none } }
+</style>
+`)
+	nr.cfg.branding.writeCSS(w, OutputNotes)
+	title := slides.Title()
+	writeTitle(w, title)
+	writeHTMLBody(w, page)
+	writeNavigation(w, lang,
+		navItem{string(slides.Zid()), slides.HTMLTitle()},
+		navItem{"", translate(lang, "Speaker notes")})
+
+	offset := 1
+	if !title.IsEmpty() {
+		offset++
+		fmt.Fprintf(w, "<h1>%s</h1>\n", render.EvaluateInline(nil, title))
+	}
+	he := render.New(w, slides, deck.SlideRoleShow, nr.cfg.verbatim, nr.cfg.headingOffset(deck.SlideRoleShow)+1, nr.cfg.embedding[deck.SlideRoleShow], nr.cfg.zettelLinks(deck.SlideRoleShow))
+	he.SetEndnoteStyle(nr.cfg.noteStyles[deck.SlideRoleShow])
+	nr.cfg.setTypography(he, slides.Lang())
+	for si := slides.Slides(deck.SlideRoleShow, offset); si != nil; si = si.Next() {
+		he.SetCurrentSlide(si)
+		for sub := si.Child(); sub != nil; sub = sub.Next() {
+			he.SetUnique(render.SlideUnique(sub))
+			markZettel(w, sub.Slide.Zid())
+			fmt.Fprintf(w, "<div class=\"slide-notes\">\n<h2><span class=\"slide-no\">%d</span> %s</h2>\n",
+				sub.SlideNo, render.EvaluateInline(he, sub.Slide.Title()))
+			hasNotes := he.EvaluateNotes(sub.Slide.Content())
+			if script := slides.Script(sub.Slide.Zid()); script != nil && sub == si.Child() {
+				io.WriteString(w, "<div class=\"script\">\n")
+				he.EvaluateBlock(script)
+				io.WriteString(w, "</div>\n")
+				hasNotes = true
+			}
+			if !hasNotes {
+				fmt.Fprintf(w, "<p class=\"no-notes\">%s</p>\n", translate(lang, "No notes"))
+			}
+			he.WriteEndnotes()
+			io.WriteString(w, "</div>\n")
+		}
+	}
+	he.LogUnknown(slides.Zid())
+	writeHTMLFooter(w, page, nr.cfg.verbatim.Scripts(slides.Syntaxes()))
+}
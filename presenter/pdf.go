@@ -0,0 +1,68 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"zettelstore.de/c/api"
+)
+
+// OutputPDF is the output type of a slide show that is printed as a PDF
+// document.
+const OutputPDF = "pdf"
+
+// processPDF sends a PDF document with one page per slide. The headless
+// browser prints the slide show in the print mode of reveal.js. Therefore,
+// only the metadata of the slide set is retrieved here; the slide show
+// itself is rendered for the browser.
+func processPDF(w http.ResponseWriter, r *http.Request, cfg *slidesConfig, zid api.ZettelID) {
+	if _, err := cfg.c.GetMeta(r.Context(), zid); err != nil {
+		reportRetrieveError(w, zid, err, "zettel")
+		return
+	}
+	printSlideShow(w, r, cfg, string(zid), fmt.Sprintf("/%s.reveal", zid))
+}
+
+// printSlideShow sends the slide show of the given path as a PDF document.
+// All query parameters, e.g. "slides", are given to the slide show too.
+func printSlideShow(w http.ResponseWriter, r *http.Request, cfg *slidesConfig, name, path string) {
+	if cfg.screenshots == nil {
+		http.Error(w, "PDF export needs a headless browser, see option -browser", http.StatusNotImplemented)
+		return
+	}
+	if isPreviewRequest(r) {
+		// The browser has no credentials of the preview mode.
+		http.Error(w, "PDF export is not available in the preview mode", http.StatusBadRequest)
+		return
+	}
+	query := r.URL.Query()
+	// Reveal.js overwrites its configuration with the query parameters.
+	query.Set("print-pdf", "")
+	query.Set("pdfSeparateFragments", "false")
+	data, err := cfg.screenshots.printPDF(r.Context(), requestOrigin(r), path+"?"+query.Encode())
+	if err != nil {
+		log.Println("PRNT", name, err)
+		status := http.StatusInternalServerError
+		var pse *pageStatusError
+		if errors.As(err, &pse) && pse.status >= 400 && pse.status < 500 {
+			status = pse.status
+		}
+		http.Error(w, fmt.Sprintf("Unable to print slide show of %s", name), status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.pdf\"", name))
+	w.Write(data)
+}
@@ -0,0 +1,43 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// preloadAsset is a file that a page will need for sure.
+type preloadAsset struct {
+	path string
+	as   string // type of the file, as needed by the "as" parameter
+}
+
+// revealAssets lists all reveal.js files that are referenced by a slide show,
+// relative to assetPrefix, in the order of their reference.
+var revealAssets = []preloadAsset{
+	{"reveal.css", "style"},
+	{"theme/white.css", "style"},
+	{"plugin/highlight/default.css", "style"},
+	{"plugin/highlight/highlight.js", "script"},
+	{"plugin/notes/notes.js", "script"},
+	{"reveal.js", "script"},
+}
+
+// writePreloadHeader adds a "Link" header for every given asset, so that a
+// browser is able to retrieve them before it has parsed the page. Together
+// with HTTP/2, all assets are retrieved concurrently over one connection.
+func writePreloadHeader(w http.ResponseWriter, assets []preloadAsset) {
+	h := w.Header()
+	for _, asset := range assets {
+		h.Add("Link", fmt.Sprintf("<%s%s>; rel=preload; as=%s", assetPrefix, asset.path, asset.as))
+	}
+}
@@ -18,19 +18,24 @@ import (
 	"flag"
 	"fmt"
 	"html"
+	"html/template"
 	"io"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"codeberg.org/t73fde/sxpf"
 	"golang.org/x/term"
-
 	"zettelstore.de/c/api"
 	"zettelstore.de/c/client"
 	"zettelstore.de/c/sexpr"
 	"zettelstore.de/c/text"
+	"zettelstore.de/contrib/presenter/deck"
+	"zettelstore.de/contrib/presenter/render"
 )
 
 // Constants for minimum required version.
@@ -48,29 +53,127 @@ func hasVersion(major, minor int) bool {
 
 func main() {
 	listenAddress := flag.String("l", ":23120", "Listen address")
+	timeout := flag.Duration("t", DefaultTimeout, "Timeout for every call to Zettelstore")
+	validate := flag.Bool("validate-html", false, "Validate generated HTML and log violations")
+	cacheDir := flag.String("cache", "", "Directory to cache retrieved zettel across restarts")
+	tlsCert := flag.String("tls-cert", "", "TLS certificate file, enables HTTPS and HTTP/2")
+	tlsKey := flag.String("tls-key", "", "TLS key file")
+	useHTTP3 := flag.Bool("http3", false, "Listen for HTTP/3 on the same UDP port, needs -tls-cert and a build with -tags http3")
+	debugAddr := flag.String("debug-addr", "", "Listen address for pprof and expvar diagnostics")
+	refresh := flag.Duration("refresh", 0, "Interval to read the configuration zettel again")
+	browser := flag.String("browser", "", "Headless browser, e.g. chromium, to export slides as PNG images or PDF")
+	assetDir := flag.String("assets", "", "Directory with reveal.js and mermaid files that replace the built-in files")
+	sharedCache := flag.String("shared-cache", "", "Directory or redis:// URL of a cache shared by several instances")
+	admin := flag.String("admin", "", "Credentials of the admin page, as user:password")
+	preview := flag.String("preview", "", "Credentials of the preview mode, as user:password")
+	history := flag.String("history", "", "Directory to keep rendered slide shows and handouts, for ?asof=YYYYMMDD")
+	configFile := flag.String("config", "", "File with command line options, read again on SIGHUP")
+	otlp := flag.String("otlp", "", "Endpoint of an OpenTelemetry collector, e.g. http://localhost:4318, to export traces")
+	snapshot := flag.String("snapshot", "", "Directory with exported slide shows, served before asking Zettelstore")
+	var tenants tenantList
+	flag.Var(&tenants, "tenant", "Zettelstore served below a path prefix, as name=URL; may be repeated")
 	flag.Usage = func() {
 		out := flag.CommandLine.Output()
 		fmt.Fprintf(out, "Usage of %s:\n", os.Args[0])
 		flag.PrintDefaults()
-		io.WriteString(out, "  [URL] URL of Zettelstore (default: \"http://127.0.0.1:23123\")\n")
+		io.WriteString(out, "  [URL] URL of Zettelstore, if no tenant is given (default: \"http://127.0.0.1:23123\")\n")
+		fmt.Fprintf(out, "Usage of %s %s, to create a new slide set:\n", os.Args[0], cmdNew)
+		fmt.Fprintf(out, "  %s %s [-slides n] [-author name] [-lang code] TITLE [URL]\n", os.Args[0], cmdNew)
 	}
 	flag.Parse()
+	if flag.Arg(0) == cmdNew {
+		os.Exit(runNew(context.Background(), flag.Args()[1:]))
+	}
+	flags := newFlagFile(*configFile, flag.CommandLine)
+	if err := flags.apply(true); err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to read options: %v\n", err)
+		os.Exit(2)
+	}
+	if *assetDir != "" {
+		if err := useAssetDir(*assetDir); err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to use asset directory: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	if *otlp != "" {
+		tracer = newTraceExporter(*otlp)
+	}
 	ctx := context.Background()
-	c, err := getClient(ctx, flag.Arg(0))
+	opts := runOptions{
+		timeout:      timeout,
+		validateHTML: validate,
+		cacheDir:     cacheDir,
+		browser:      browser,
+		admin:        *admin,
+		preview:      *preview,
+		history:      *history,
+		sharedCache:  *sharedCache,
+	}
+	mux := http.NewServeMux()
+	rl := &reloader{flags: flags}
+	if len(tenants) == 0 {
+		mux.Handle("/", serveTenant(ctx, tenant{url: flag.Arg(0)}, opts, rl, *refresh, *snapshot))
+	} else {
+		for _, t := range tenants {
+			mux.Handle(t.prefix()+"/", http.StripPrefix(t.prefix(), serveTenant(ctx, t, opts, rl, *refresh, *snapshot)))
+		}
+		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) { processTenants(w, r, tenants) })
+	}
+	rl.watch()
+
+	if *debugAddr != "" {
+		serveDebug(*debugAddr)
+	}
+	mux.Handle(assetPrefix, makeAssetHandler())
+	mux.Handle("/revealjs/", makeUncachedAssetHandler())
+	fmt.Println("Listening:", *listenAddress)
+	handler := traceHandler(mux)
+	if *useHTTP3 {
+		h3, err := withHTTP3(*listenAddress, *tlsCert, *tlsKey, handler)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to serve HTTP/3: %v\n", err)
+			os.Exit(2)
+		}
+		handler = h3
+	}
+	var err error
+	if *tlsCert != "" && *tlsKey != "" {
+		// HTTP/2 is used automatically by net/http, if the client supports it.
+		err = http.ListenAndServeTLS(*listenAddress, *tlsCert, *tlsKey, handler)
+	} else {
+		err = http.ListenAndServe(*listenAddress, handler)
+	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Unable to connect to zettelstore: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Unable to serve: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// serveTenant returns the handler of the given tenant. If a snapshot
+// directory is given, a Zettelstore that cannot be reached on start is not an
+// error: then only the snapshot is served.
+func serveTenant(ctx context.Context, t tenant, opts runOptions, rl *reloader, refresh time.Duration, snapshot string) http.Handler {
+	cs, err := newTenantStore(ctx, t, opts)
+	if err != nil && snapshot == "" {
+		if t.name == "" {
+			fmt.Fprintf(os.Stderr, "Unable to start: %v\n", err)
+		} else {
+			fmt.Fprintf(os.Stderr, "Unable to start tenant %q: %v\n", t.name, err)
+		}
 		os.Exit(2)
 	}
-	cfg, err := getConfig(ctx, c)
+	dir := snapshotDir(snapshot, t)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Unable to retrieve presenter config: %v\n", err)
-		os.Exit(2)
+		log.Println("SNAP", dir, "serving snapshot only:", err)
+		return newSnapshotHandler(dir, nil)
 	}
-
-	http.HandleFunc("/", makeHandler(&cfg))
-	http.Handle("/revealjs/", http.FileServer(http.FS(revealjs)))
-	fmt.Println("Listening:", *listenAddress)
-	http.ListenAndServe(*listenAddress, nil)
+	rl.add(cs)
+	cs.watch(refresh)
+	if snapshot == "" {
+		return makeHandler(cs)
+	}
+	log.Println("SNAP", dir)
+	return newSnapshotHandler(dir, makeHandler(cs))
 }
 
 func getClient(ctx context.Context, base string) (*client.Client, error) {
@@ -136,60 +239,357 @@ func getClient(ctx context.Context, base string) (*client.Client, error) {
 	return c, nil
 }
 
+// Constants for configuration keys that are not used as zettel metadata.
+const (
+	KeyCSSDefault        = "css-default"
+	KeyCSSDefaultReplace = "css-default-replace"
+	KeyHandoutTitleLevel = "handout-title-level"
+	KeyListLimit         = "list-limit"
+)
+
+// DefaultHeadingOffset is the default offset added to the level of headings
+// within zettel content. It must be at least 1, because level 1 is reserved
+// for the title of a slide / zettel.
+const DefaultHeadingOffset = 1
+
+// maxHeadingOffset is the maximum heading offset, so that a heading of level 1
+// is still rendered as a HTML heading.
+const maxHeadingOffset = 5
+
+// DefaultHandoutTitleLevel is the default HTML heading level of slide titles
+// within a handout. Level 1 is reserved for the title of the slide set.
+const DefaultHandoutTitleLevel = 2
+
+// maxHandoutTitleLevel is the maximum HTML heading level of slide titles.
+const maxHandoutTitleLevel = 5
+
+// DefaultListLimit is the default number of zettel shown on one list page.
+const DefaultListLimit = 100
+
 const (
 	zidConfig   = api.ZettelID("00009000001000")
 	zidSlideCSS = api.ZettelID("00009000001005")
 )
 
 type slidesConfig struct {
-	c            *client.Client
-	slideSetRole string
-	author       string
+	c             *zsClient
+	slideSetRole  string
+	author        string
+	lang          string
+	listLimit     int
+	templates     map[string]api.ZettelID
+	cssZids       map[string]api.ZettelID
+	offsets       map[string]int
+	titleLevel    int // heading level of slide titles in a handout
+	readingSpeed  int // words per minute
+	linkPolicies  map[string]string
+	embedding     map[string]render.ImageEmbedding
+	noteStyles    map[string]render.EndnoteStyle
+	defaultCSS    []string
+	verbatim      render.VerbatimRegistry
+	branding      branding
+	thumbs        *thumbCache
+	recent        *recentList
+	slideCounts   *slideCounts
+	audit         *auditLog
+	auditCfg      auditConfig
+	allow         *allowList
+	lastGood      *lastGoodCache
+	disk          *diskCache
+	limits        resourceLimits
+	lint          lintRules
+	lazySlides    int
+	minify        map[string]bool
+	imageAltTitle bool
+	typography    bool
+	emojis        render.EmojiMap
+	screenshots   *screenshotter
+	validateHTML  bool
+	history       string // directory of rendered pages, if not empty
+	resume        *resumePositions
+	external      *externalCache
 }
 
-func getConfig(ctx context.Context, c *client.Client) (slidesConfig, error) {
+func getConfig(ctx context.Context, c *zsClient) (slidesConfig, error) {
 	result := slidesConfig{
 		c:            c,
-		slideSetRole: DefaultSlideSetRole,
+		slideSetRole: deck.DefaultSlideSetRole,
+		listLimit:    DefaultListLimit,
+		thumbs:       &thumbCache{},
+		recent:       &recentList{},
+		slideCounts:  &slideCounts{},
+		audit:        &auditLog{},
+		resume:       &resumePositions{},
+		external:     &externalCache{},
+		lastGood:     &lastGoodCache{},
+		templates:    make(map[string]api.ZettelID),
+		cssZids:      map[string]api.ZettelID{deck.SlideRoleShow: zidSlideCSS},
+		offsets:      make(map[string]int),
+		linkPolicies: make(map[string]string),
+		embedding:    make(map[string]render.ImageEmbedding),
+		noteStyles:   make(map[string]render.EndnoteStyle),
+		minify:       make(map[string]bool),
 	}
 	m, err := c.GetMeta(ctx, zidConfig)
 	if err != nil {
 		return slidesConfig{}, err
 	}
-	if ssr, ok := m[KeySlideSetRole]; ok {
+	if ssr, ok := m[deck.KeySlideSetRole]; ok {
 		result.slideSetRole = ssr
 	}
-	if author, ok := m[KeyAuthor]; ok {
+	if author, ok := m[deck.KeyAuthor]; ok {
 		result.author = author
 	}
+	result.lang = m[api.KeyLang]
+	result.allow = newAllowList(m)
+	result.auditCfg = getAuditConfig(m)
+	result.lint = getLintRules(m)
+	result.titleLevel = DefaultHandoutTitleLevel
+	if level, err := strconv.Atoi(m[KeyHandoutTitleLevel]); err == nil && level >= 1 {
+		if level > maxHandoutTitleLevel {
+			level = maxHandoutTitleLevel
+		}
+		result.titleLevel = level
+	}
+	if limit, err := strconv.Atoi(m[KeyListLimit]); err == nil && limit >= 0 {
+		result.listLimit = limit
+	}
+	for _, output := range templateOutputs {
+		if zid := api.ZettelID(m[keyTemplate(output)]); zid.IsValid() {
+			result.templates[output] = zid
+		}
+	}
+	for _, output := range cssOutputs {
+		if zid := api.ZettelID(m[keyCSS(output)]); zid.IsValid() {
+			result.cssZids[output] = zid
+		}
+	}
+	for _, output := range offsetOutputs {
+		if offset, err := strconv.Atoi(m[keyHeadingOffset(output)]); err == nil {
+			result.offsets[output] = offset
+		}
+	}
+	for _, output := range offsetOutputs {
+		if policy, ok := getLinkPolicy(m, output); ok {
+			result.linkPolicies[output] = policy
+		}
+	}
+	for _, output := range offsetOutputs {
+		result.noteStyles[output] = getEndnoteStyle(m, output)
+		result.minify[output] = getMinify(m, output)
+	}
+	for _, output := range embedOutputs {
+		result.embedding[output] = getImageEmbedding(m, output)
+	}
+	if lazy, err := strconv.Atoi(m[KeyLazySlides]); err == nil && lazy >= 0 {
+		result.lazySlides = lazy
+	}
+	result.limits = getResourceLimits(m)
+	result.imageAltTitle = getImageAltTitle(m)
+	result.readingSpeed = getReadingSpeed(m)
+	result.defaultCSS = getDefaultCSS(ctx, c, m)
+	result.typography = getTypography(m)
+	if result.typography {
+		// defaultCSS may be the global variable, which must not be changed.
+		result.defaultCSS = append(append([]string(nil), result.defaultCSS...), typographyCSS...)
+	}
+	result.emojis = getEmojis(ctx, c, m)
+	result.verbatim = getVerbatimRegistry(ctx, c, m)
+	result.branding = getBranding(ctx, c)
 	return result, nil
 }
 
-func makeHandler(cfg *slidesConfig) http.HandlerFunc {
+// cssPrint is used instead of an output type to specify CSS for printing.
+const cssPrint = "print"
+
+var cssOutputs = []string{deck.SlideRoleShow, deck.SlideRoleHandout, OutputZettel, cssPrint}
+
+// keyCSS returns the configuration key for the CSS zettel of the given output
+// type.
+func keyCSS(output string) string { return "css-" + output }
+
+var offsetOutputs = []string{deck.SlideRoleShow, deck.SlideRoleHandout, OutputZettel}
+
+// keyHeadingOffset returns the configuration key for the heading offset of the
+// given output type.
+func keyHeadingOffset(output string) string { return "heading-offset-" + output }
+
+// headingOffset returns the offset for headings within zettel content for the
+// given output type. A heading of level 1 is never rendered as a HTML heading
+// of level 1, because that level is used for the slide / zettel title.
+func (cfg *slidesConfig) headingOffset(output string) int {
+	offset, found := cfg.offsets[output]
+	if !found {
+		return DefaultHeadingOffset
+	}
+	if offset < 1 {
+		return 1
+	}
+	if offset > maxHeadingOffset {
+		return maxHeadingOffset
+	}
+	return offset
+}
+
+// handoutHeadingOffset returns the offset for headings within the content of
+// a handout. Headings are placed below the level of the slide titles.
+func (cfg *slidesConfig) handoutHeadingOffset() int {
+	offset := cfg.headingOffset(deck.SlideRoleHandout) + cfg.titleLevel - 1
+	if offset > maxHeadingOffset {
+		return maxHeadingOffset
+	}
+	return offset
+}
+
+// KeyZettelLinks is the configuration key for the policy of links to zettel
+// that are not part of a slide set.
+const KeyZettelLinks = "zettel-links"
+
+// defaultLinkPolicies specifies the link policy if none is configured.
+var defaultLinkPolicies = map[string]string{
+	deck.SlideRoleShow:    render.LinksPresenter,
+	deck.SlideRoleHandout: render.LinksAnchor,
+	OutputZettel:          render.LinksPresenter,
+}
+
+// getLinkPolicy returns the configured link policy for the given output type.
+// A value for a specific output type overwrites the general value.
+func getLinkPolicy(m map[string]string, output string) (string, bool) {
+	for _, key := range []string{KeyZettelLinks + "-" + output, KeyZettelLinks} {
+		switch policy := m[key]; policy {
+		case render.LinksAnchor, render.LinksPresenter, render.LinksZettelstore, render.LinksNone:
+			return policy, true
+		case "":
+		default:
+			log.Println("LINK", key, policy)
+		}
+	}
+	return "", false
+}
+
+// zettelLinks returns how links to other zettel are generated for the given
+// output type.
+func (cfg *slidesConfig) zettelLinks(output string) render.ZettelLinks {
+	policy, found := cfg.linkPolicies[output]
+	if !found {
+		policy = defaultLinkPolicies[output]
+	}
+	return render.ZettelLinks{Policy: policy, Base: cfg.c.Base()}
+}
+
+// getUserCSS retrieves the user-defined CSS for the given output type.
+func (cfg *slidesConfig) getUserCSS(ctx context.Context, output string) []byte {
+	if zid, found := cfg.cssZids[output]; found {
+		if data, err := cfg.c.GetZettel(ctx, zid, api.PartContent); err == nil {
+			return data
+		}
+	}
+	return nil
+}
+
+func makeHandler(cs *configStore) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		cs.clients.add(r)
+		if strings.HasPrefix(r.URL.Path, adminPath) {
+			processAdmin(w, r, cs)
+			return
+		}
+		cfg := cs.get()
+		if r.URL.Path == "/audit" {
+			processAudit(w, r, cfg, cs.opts.admin)
+			return
+		}
+		if cfg.screenshots != nil {
+			w, r = cfg.screenshots.observe(w, r)
+		}
+		handle := makeRequestHandler(cfg)
+		if timeout := cfg.limits.renderTimeout; timeout > 0 {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			r = r.WithContext(ctx)
+		}
+		if isPreviewRequest(r) {
+			servePreview(w, r, cs.opts.preview, handle)
+			return
+		}
+		cfg.lastGood.serve(w, r, cfg.lang, cfg.limits, handle)
+	}
+}
+
+func makeRequestHandler(cfg *slidesConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-cache")
 		path := r.URL.Path
+		if path == "/" {
+			processIndex(w, r, cfg)
+			return
+		}
 		if zid, suffix := retrieveZidAndSuffix(path); zid != api.InvalidZID {
+			if !cfg.allow.allows(r.Context(), cfg.c, zid) {
+				reportNotAllowed(w, zid)
+				return
+			}
+			if r.URL.Query().Has("asof") {
+				processHistory(w, r, cfg, zid, suffix)
+				return
+			}
 			switch suffix {
 			case "reveal", "slide":
-				processSlideSet(w, r, cfg, zid, &revealRenderer{})
+				processSlideSet(w, r, cfg, zid, newRevealRenderer(r))
 			case "html":
-				processSlideSet(w, r, cfg, zid, &handoutRenderer{})
+				processSlideSet(w, r, cfg, zid, newHandoutRenderer(r))
+			case OutputPDF:
+				processPDF(w, r, cfg, zid)
+			case OutputNotes:
+				processSlideSet(w, r, cfg, zid, &notesRenderer{})
+			case OutputPrompter:
+				processSlideSet(w, r, cfg, zid, &prompterRenderer{})
+			case OutputAsciiDoc:
+				processSlideSet(w, r, cfg, zid, &asciiDocRenderer{})
+			case OutputGemtext:
+				processSlideSet(w, r, cfg, zid, &gemtextRenderer{})
+			case OutputBundle:
+				processSlideSet(w, r, cfg, zid, &bundleRenderer{})
+			case OutputContact:
+				processSlideSet(w, r, cfg, zid, &contactRenderer{})
+			case OutputFlashcards:
+				processSlideSet(w, r, cfg, zid, &flashcardsRenderer{})
+			case suffixSections:
+				processSlideSet(w, r, cfg, zid, newSectionsRenderer(r))
+			case suffixResume:
+				processResume(w, r, cfg, zid)
 			case "content":
 				if content := retrieveContent(w, r, cfg.c, zid); len(content) > 0 {
 					w.Write(content)
 				}
+			case "check":
+				processCheck(w, r, cfg, zid)
+			case OutputDiff:
+				processDiff(w, r, cfg, zid)
+			case "png":
+				processSlidePNG(w, r, cfg, zid)
+			case "thumb":
+				processThumbnail(w, r, cfg, zid)
+			case "font":
+				processFont(w, r, cfg.c, zid)
+			case deck.SyntaxWebVTT:
+				processVTT(w, r, cfg.c, zid)
 			case "svg":
 				if content := retrieveContent(w, r, cfg.c, zid); len(content) > 0 {
 					io.WriteString(w, `<?xml version='1.0' encoding='utf-8'?>`)
 					w.Write(content)
 				}
 			default:
-				processZettel(w, r, cfg.c, zid, cfg.slideSetRole)
+				processZettel(w, r, cfg, zid)
 			}
 			return
 		}
-		if len(path) == 2 && ' ' < path[1] && path[1] <= 'z' {
-			processList(w, r, cfg.c)
+		if path == "/list" {
+			processList(w, r, cfg)
+			return
+		}
+		if strings.HasPrefix(path, combinePath) {
+			processCombine(w, r, cfg)
 			return
 		}
 		log.Println("NOTF", path)
@@ -201,9 +601,6 @@ func retrieveZidAndSuffix(path string) (api.ZettelID, string) {
 	if path == "" {
 		return api.InvalidZID, ""
 	}
-	if path == "/" {
-		return api.ZidDefaultHome, ""
-	}
 	if path[0] == '/' {
 		path = path[1:]
 	}
@@ -226,7 +623,7 @@ func retrieveZidAndSuffix(path string) (api.ZettelID, string) {
 	return api.InvalidZID, ""
 }
 
-func retrieveContent(w http.ResponseWriter, r *http.Request, c *client.Client, zid api.ZettelID) []byte {
+func retrieveContent(w http.ResponseWriter, r *http.Request, c *zsClient, zid api.ZettelID) []byte {
 	content, err := c.GetZettel(r.Context(), zid, api.PartContent)
 	if err != nil {
 		reportRetrieveError(w, zid, err, "content")
@@ -244,8 +641,19 @@ func reportRetrieveError(w http.ResponseWriter, zid api.ZettelID, err error, obj
 	}
 }
 
-func processZettel(w http.ResponseWriter, r *http.Request, c *client.Client, zid api.ZettelID, slidesSetRole string) {
+func processZettel(w http.ResponseWriter, r *http.Request, cfg *slidesConfig, zid api.ZettelID) {
+	if cfg.minify[OutputZettel] {
+		mw := &minifyWriter{ResponseWriter: w}
+		defer mw.flush()
+		w = mw
+	}
+	if cfg.validateHTML {
+		iw := &idWriter{ResponseWriter: w}
+		defer iw.logViolations(zid)
+		w = iw
+	}
 	ctx := r.Context()
+	c := cfg.c
 	sxZettel, err := c.GetEvaluatedSexpr(ctx, zid, api.PartZettel)
 	if err != nil {
 		reportRetrieveError(w, zid, err, "zettel")
@@ -254,19 +662,29 @@ func processZettel(w http.ResponseWriter, r *http.Request, c *client.Client, zid
 	sxMeta, sxContent := sexpr.GetMetaContent(sxZettel)
 
 	role := sxMeta.GetString(api.KeyRole)
-	if role == slidesSetRole {
-		if slides := processSlideTOC(ctx, c, zid, sxMeta); slides != nil {
-			renderSlideTOC(w, slides)
+	tmpl := cfg.getTemplate(ctx, OutputZettel)
+	userCSS := cfg.getUserCSS(ctx, OutputZettel)
+	if role == cfg.slideSetRole {
+		if slides := processSlideTOC(ctx, c, cfg.disk, cfg.limits, cfg.author, zid, sxMeta); slides != nil {
+			cfg.allow.grant(slides)
+			page := cfg.newHTMLPage(OutputZettel, slides.Lang(), "", tmpl)
+			renderLandingPage(w, slides, page, userCSS, slides.Author(cfg.author))
 			return
 		}
 	}
 
-	title := getSlideTitleZid(sxMeta, zid)
-	writeHTMLHeader(w, sxMeta.GetString(api.KeyLang), "")
+	title := deck.SlideTitleZid(sxMeta, zid)
+	page := cfg.newHTMLPage(OutputZettel, sxMeta.GetString(api.KeyLang), "", tmpl)
+	writeHTMLHeader(w, page)
+	writeCSS(w, userCSS)
 	fmt.Fprintf(w, "<title>%s</title>\n", text.EvaluateInlineString(title))
-	writeHTMLBody(w)
-	he := htmlNew(w, nil, nil, 1, false, true)
-	fmt.Fprintf(w, "<h1>%s</h1>\n", evaluateInline(he, title))
+	writeHTMLBody(w, page)
+	he := render.New(w, nil, OutputZettel, cfg.verbatim, cfg.headingOffset(OutputZettel), render.ImageEmbedding{}, cfg.zettelLinks(OutputZettel))
+	he.SetEndnoteStyle(cfg.noteStyles[OutputZettel])
+	cfg.setTypography(he, page.Lang)
+	htmlTitle := render.EvaluateInline(he, title)
+	writeNavigation(w, page.Lang, navItem{"", htmlTitle})
+	fmt.Fprintf(w, "<h1>%s</h1>\n", htmlTitle)
 	hasHeader := false
 	for k, v := range sxMeta {
 		if v.Type != api.MetaURL {
@@ -288,58 +706,26 @@ func processZettel(w http.ResponseWriter, r *http.Request, c *client.Client, zid
 
 	he.EvaluateBlock(sxContent)
 	he.WriteEndnotes()
+	he.LogUnknown(zid)
 	fmt.Fprintf(w, "<p><a href=\"%sh/%s\">&#9838;</a></p>\n", c.Base(), zid)
-	writeHTMLFooter(w, he.hasMermaid)
+	writeHTMLFooter(w, page, cfg.verbatim.Scripts(he.Syntaxes()))
 }
 
-func processSlideTOC(ctx context.Context, c *client.Client, zid api.ZettelID, sxMeta sexpr.Meta) *slideSet {
+func processSlideTOC(ctx context.Context, c *zsClient, disk *diskCache, limits resourceLimits, author string, zid api.ZettelID, sxMeta sexpr.Meta) *deck.SlideSet {
 	o, err := c.GetZettelOrder(ctx, zid)
-	if err != nil {
+	if err != nil || limits.tooManySlides(len(o.List)) {
 		return nil
 	}
-	slides := newSlideSetMeta(zid, sxMeta)
+	slides := deck.NewMeta(zid, sxMeta)
 	getZettel := func(zid api.ZettelID) ([]byte, error) { return c.GetZettel(ctx, zid, api.PartContent) }
 	sGetZettel := func(zid api.ZettelID) (sxpf.Value, error) {
 		return c.GetEvaluatedSexpr(ctx, zid, api.PartZettel)
 	}
-	setupSlideSet(slides, o.List, getZettel, sGetZettel)
+	getZettel, sGetZettel = disk.wrap(ctx, c, o.List, getZettel, sGetZettel)
+	setupSlideSet(slides, author, o.List, getZettel, sGetZettel)
 	return slides
 }
 
-func renderSlideTOC(w http.ResponseWriter, slides *slideSet) {
-	offset, title, htmlTitle, subtitle := 1, slides.Title(), "", slides.Subtitle()
-	if !title.IsEmpty() {
-		offset++
-		htmlTitle = evaluateInline(nil, title)
-	}
-
-	writeHTMLHeader(w, slides.Lang(), "")
-	writeTitle(w, title)
-	writeHTMLBody(w)
-	if !title.IsEmpty() {
-		fmt.Fprintf(w, "<h1>%s</h1>\n", htmlTitle)
-		if !subtitle.IsEmpty() {
-			fmt.Fprintf(w, "<h2>%s</h2>\n", evaluateInline(nil, subtitle))
-		}
-	}
-	io.WriteString(w, "<ol>\n")
-	if !title.IsEmpty() {
-		fmt.Fprintf(w, "<li><a href=\"/%s.slide#(1)\">%s</a></li>\n", slides.zid, htmlTitle)
-	}
-	for si := slides.Slides(SlideRoleShow, offset); si != nil; si = si.Next() {
-		var slideTitle string
-		if t := si.Slide.title; !t.IsEmpty() {
-			slideTitle = evaluateInline(nil, t)
-		} else {
-			slideTitle = string(si.Slide.zid)
-		}
-		fmt.Fprintf(w, "<li><a href=\"/%s.slide#(%d)\">%s</a></li>\n", slides.zid, si.Number, slideTitle)
-	}
-	io.WriteString(w, "</ol>\n")
-	fmt.Fprintf(w, "<p><a href=\"/%s.reveal\">Reveal</a>, <a href=\"/%s.html\">Handout</a>, <a href=\"\">Zettel</a></p>\n", slides.zid, slides.zid)
-	writeHTMLFooter(w, false)
-}
-
 func processSlideSet(w http.ResponseWriter, r *http.Request, cfg *slidesConfig, zid api.ZettelID, ren renderer) {
 	ctx := r.Context()
 	o, err := cfg.c.GetZettelOrder(ctx, zid)
@@ -347,106 +733,322 @@ func processSlideSet(w http.ResponseWriter, r *http.Request, cfg *slidesConfig,
 		reportRetrieveError(w, zid, err, "zettel")
 		return
 	}
+	spec := r.URL.Query().Get("slides")
+	if spec != "" {
+		if o.List, err = selectSlides(o.List, spec); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if cfg.limits.tooManySlides(len(o.List)) {
+		cfg.reportTooManySlides(w, len(o.List))
+		return
+	}
+	numSlides := len(o.List)
+	o.List = slidesForRole(o.List, ren.Role())
 	sMeta, err := cfg.c.GetEvaluatedSexpr(ctx, zid, api.PartMeta)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Unable to read zettel %s: %v", zid, err), http.StatusBadRequest)
 		return
 	}
-	slides := newSlideSet(zid, sexpr.MakeMeta(sMeta))
+	slides := deck.New(zid, sexpr.MakeMeta(sMeta))
+	preview := isPreview(ctx)
+	slides.SetPreview(preview)
 	getZettel := func(zid api.ZettelID) ([]byte, error) { return cfg.c.GetZettel(ctx, zid, api.PartContent) }
 	sGetZettel := func(zid api.ZettelID) (sxpf.Value, error) {
 		return cfg.c.GetEvaluatedSexpr(ctx, zid, api.PartZettel)
 	}
-	setupSlideSet(slides, o.List, getZettel, sGetZettel)
+	getZettel, sGetZettel = cfg.disk.wrap(ctx, cfg.c, o.List, getZettel, sGetZettel)
+	addExternalContent(ctx, cfg, slides, o.List)
+	setupSlideSet(slides, cfg.author, o.List, getZettel, sGetZettel)
+	renderSlides(w, r, cfg, slides, getZettel, ren)
+	if !preview {
+		// A preview may contain zettel that are not public yet.
+		if spec == "" {
+			cfg.slideCounts.Set(zid, slides.Meta().GetString(KeyPublished), numSlides)
+		}
+		cfg.recent.Add(zid, slides.HTMLTitle())
+	}
+	cfg.recordAudit(r, zid, text.EvaluateInlineString(slides.Title()), ren.Role())
+}
+
+// renderSlides adds all resources of a completed slide set, e.g. images and
+// CSS definitions, and renders it.
+func renderSlides(w http.ResponseWriter, r *http.Request, cfg *slidesConfig, slides *deck.SlideSet, getZettel deck.GetZettelContentFunc, ren renderer) {
+	ctx := r.Context()
+	zid, preview := slides.Zid(), slides.Preview()
+	addImageTitles(ctx, cfg, slides)
+	addVideoTracks(ctx, cfg, slides)
+	if preview {
+		slides.AddCSS(previewCSS(slides.Lang()))
+	} else {
+		// Zettel that are only part of a preview must not become available.
+		cfg.allow.grant(slides)
+	}
+	if fontZids := slides.FontZids(); len(fontZids) > 0 {
+		slides.AddCSS(fontFaceCSS(ctx, cfg.c, fontZids))
+	}
+	if cssZid := slides.CSSZid(); cssZid != api.InvalidZID {
+		if data, err := getZettel(cssZid); err == nil {
+			slides.AddCSS(data)
+		} else {
+			log.Println("SCSS", cssZid, err)
+		}
+	}
+	slides.AddPrintCSS(cfg.getUserCSS(ctx, cssPrint))
+	if cssZid := slides.PrintCSSZid(); cssZid != api.InvalidZID {
+		if data, err := getZettel(cssZid); err == nil {
+			slides.AddPrintCSS(data)
+		} else {
+			log.Println("PCSS", cssZid, err)
+		}
+	}
 	ren.Prepare(ctx, cfg)
-	ren.Render(w, slides, slides.Author(cfg))
+	if _, suffix := retrieveZidAndSuffix(r.URL.Path); cfg.history != "" && historySuffix(suffix) != "" && !preview && isDefaultQuery(r) {
+		if full := completeRenderer(ren); full != nil {
+			// The history must contain all slides, but the response
+			// should still load some of them lazily.
+			defer func() {
+				hw := &historyWriter{}
+				full.Render(hw, slides, slides.Author(cfg.author))
+				hw.store(cfg.history, zid, historySuffix(suffix))
+			}()
+		} else {
+			hw := &historyWriter{ResponseWriter: w}
+			defer hw.store(cfg.history, zid, historySuffix(suffix))
+			w = hw
+		}
+	}
+	if cfg.minify[ren.Role()] {
+		mw := &minifyWriter{ResponseWriter: w}
+		defer mw.flush()
+		w = mw
+	}
+	iw := idWriter{ResponseWriter: w}
+	_, sp := startSpan(ctx, "render "+ren.Role(), spanInternal)
+	sp.set("zettel.id", string(zid))
+	ren.Render(&iw, slides, slides.Author(cfg.author))
+	sp.end(nil)
+	if cfg.validateHTML {
+		iw.logViolations(zid)
+	} else {
+		iw.logDuplicates(zid)
+	}
 }
 
 type renderer interface {
 	Role() string
 	Prepare(context.Context, *slidesConfig)
-	Render(w http.ResponseWriter, slides *slideSet, author string)
+	Render(w http.ResponseWriter, slides *deck.SlideSet, author string)
 }
 
 type revealRenderer struct {
+	cfg     *slidesConfig
 	userCSS []byte
+	tmpl    *template.Template
+	bundle  *bundle // not nil, if the slide show is exported as a bundle
+	eager   bool    // all slides are written, even if lazy-slides is configured
 }
 
-func (*revealRenderer) Role() string { return SlideRoleShow }
-func (rr *revealRenderer) Prepare(ctx context.Context, cfg *slidesConfig) {
-	if data, err := cfg.c.GetZettel(ctx, zidSlideCSS, api.PartContent); err == nil {
-		rr.userCSS = data
+// newRevealRenderer creates a renderer for a slide show. If it is printed,
+// i.e. with the query parameter "print-pdf" of reveal.js, all slides are
+// written.
+func newRevealRenderer(r *http.Request) *revealRenderer {
+	return &revealRenderer{eager: r.URL.Query().Has("print-pdf")}
+}
+
+// completeRenderer returns a renderer that writes all slides, if the given
+// renderer loads some of them lazily. Otherwise it returns nil.
+func completeRenderer(ren renderer) renderer {
+	rr, ok := ren.(*revealRenderer)
+	if !ok || rr.bundle != nil || rr.eager || rr.cfg.lazySlides <= 0 {
+		return nil
 	}
+	full := *rr
+	full.eager = true
+	return &full
+}
+
+func (*revealRenderer) Role() string { return deck.SlideRoleShow }
+func (rr *revealRenderer) Prepare(ctx context.Context, cfg *slidesConfig) {
+	rr.userCSS = cfg.getUserCSS(ctx, deck.SlideRoleShow)
+	rr.cfg = cfg
+	rr.tmpl = cfg.getTemplate(ctx, deck.SlideRoleShow)
 }
-func (rr *revealRenderer) Render(w http.ResponseWriter, slides *slideSet, author string) {
+func (rr *revealRenderer) Render(w http.ResponseWriter, slides *deck.SlideSet, author string) {
 	lang := slides.Lang()
-	writeHTMLHeader(w, lang, ".reveal ")
-	if len(rr.userCSS) > 0 {
-		io.WriteString(w, `<style type="text/css">`)
-		w.Write(rr.userCSS)
-		io.WriteString(w, "</style>\n")
+	page := rr.cfg.newHTMLPage(deck.SlideRoleShow, lang, ".reveal ", rr.tmpl)
+	if rr.bundle == nil {
+		writePreloadHeader(w, revealAssets)
 	}
+	writeHTMLHeader(w, page)
+	rr.cfg.branding.writeCSS(w, deck.SlideRoleShow)
+	writeCSS(w, rr.userCSS)
+	writeCSS(w, rr.bundle.rewriteCSS(slides.CSS()))
+	writeMotionCSS(w, slides.Motion())
+	writePrintCSS(w, slides.PrintCSS())
 
 	title := slides.Title()
 	writeTitle(w, title)
-	io.WriteString(w, `<link rel="stylesheet" href="revealjs/reveal.css">
-<link rel="stylesheet" href="revealjs/theme/white.css">
-<link rel="stylesheet" href="revealjs/plugin/highlight/default.css">
-`)
-	writeHTMLBody(w)
+	for _, asset := range revealAssets {
+		if asset.as == "style" {
+			fmt.Fprintf(w, "<link rel=\"stylesheet\" href=\"%s\">\n", rr.bundle.assetURL(asset.path))
+		}
+	}
+	io.WriteString(w, helpCSS)
+	io.WriteString(w, speakerHead)
+	writeContrastHead(w, deck.SlideRoleShow)
+	writeHTMLBody(w, page)
 
 	io.WriteString(w, "<div class=\"reveal\">\n<div class=\"slides\">\n")
 	offset := 1
 	if !title.IsEmpty() {
 		offset++
-		fmt.Fprintf(w, "<section>\n<h1 class=\"title\">%s</h1>", evaluateInline(nil, title))
-		if subtitle := slides.Subtitle(); !subtitle.IsEmpty() {
-			fmt.Fprintf(w, "\n<p class=\"subtitle\">%s</p>", evaluateInline(nil, subtitle))
-		}
-		if author != "" {
-			fmt.Fprintf(w, "\n<p class=\"author\">%s</p>", html.EscapeString(author))
-		}
-		io.WriteString(w, "\n</section>\n")
+		rr.renderTitleSlide(w, slides, title, author)
 	}
-	he := htmlNew(w, slides, rr, 1, false, true)
-	for si := slides.Slides(SlideRoleShow, offset); si != nil; si = si.Next() {
-		he.SetCurrentSlide(si)
-		main := si.Child()
-		sub := main.Next()
-		if sub != nil {
-			io.WriteString(w, "<section>\n")
+	he := rr.newGenerator(w, slides)
+	numSections, lazySlides, hasMore := 0, rr.cfg.lazySlides, false
+	if rr.bundle != nil || rr.eager {
+		// A bundle and a combined slide show must contain all slides.
+		lazySlides = 0
+	}
+	for si := slides.Slides(deck.SlideRoleShow, offset); si != nil; si = si.Next() {
+		if lazySlides > 0 && numSections >= lazySlides {
+			hasMore = true
+			break
 		}
-		fmt.Fprintf(w, `<section id="(%d)"`, main.SlideNo)
-		if slLang := main.Slide.lang; slLang != "" && slLang != lang {
-			fmt.Fprintf(w, ` lang="%s"`, slLang)
+		rr.renderSection(w, he, slides, si, lang)
+		numSections++
+	}
+	he.LogUnknown(slides.Zid())
+	io.WriteString(w, "</div>\n</div>\n")
+	for _, asset := range revealAssets {
+		if asset.as == "script" {
+			fmt.Fprintf(w, "<script src=\"%s\"></script>\n", rr.bundle.assetURL(asset.path))
 		}
-		io.WriteString(w, ">\n")
-		renderRevealSlide(w, he, main)
-		io.WriteString(w, "</section>\n")
+	}
+	fmt.Fprintf(w, `<script>Reveal.initialize({width: 1920, height: 1024, center: true,
+slideNumber: "c", hash: true, help: false,
+plugins: [ RevealHighlight, RevealNotes ]}).then(function() {
+if (%s) { Reveal.configure({transition: "none", backgroundTransition: "none", autoAnimate: false, autoSlide: 0}); }
+if (new URLSearchParams(window.location.search).has("speaker")) { Reveal.getPlugin("notes").open(); }
+});</script>
+`, revealReduceMotion(slides.Motion()))
+	if hasMore {
+		writeLazyLoader(w, slides.Zid(), numSections)
+	}
+	writeCountdownScript(w)
+	if slides.HasAgenda() {
+		writeAgendaScript(w)
+	}
+	if slides.HasProgress() {
+		writeProgressFooter(w, lang, lastSlideNo(slides.Slides(deck.SlideRoleShow, offset)))
+	}
+	if rr.bundle == nil {
+		writeResumePrompt(w, slides.Zid(), lang)
+	}
+	writeShortcutHelp(w, lang, revealShortcuts(slides))
+	writeContrastToggle(w, lang, deck.SlideRoleShow)
+	writeHTMLFooter(w, page, rr.cfg.verbatim.Scripts(slides.Syntaxes()))
+}
 
-		if sub != nil {
-			for {
-				fmt.Fprintf(w, "<section id=\"(%d)\">\n", sub.SlideNo)
-				renderRevealSlide(w, he, sub)
-				io.WriteString(w, "</section>\n")
-				sub = sub.Next()
-				if sub == nil {
-					break
-				}
+// newGenerator creates a HTML generator for the slides of a slide show.
+func (rr *revealRenderer) newGenerator(w io.Writer, slides *deck.SlideSet) *render.Generator {
+	he := render.New(w, slides, rr.Role(), rr.cfg.verbatim, rr.cfg.headingOffset(deck.SlideRoleShow), rr.cfg.embedding[deck.SlideRoleShow], rr.cfg.zettelLinks(deck.SlideRoleShow))
+	he.SetEndnoteStyle(rr.cfg.noteStyles[deck.SlideRoleShow])
+	rr.cfg.setTypography(he, slides.Lang())
+	if rr.bundle != nil {
+		he.SetImageURL(bundleImageURL)
+	}
+	return he
+}
+
+// renderSection writes the reveal section of a slide, including the sections
+// of its sub-slides.
+func (rr *revealRenderer) renderSection(w io.Writer, he *render.Generator, slides *deck.SlideSet, si *deck.SlideInfo, lang string) {
+	he.SetCurrentSlide(si)
+	if si.Slide.IsAgenda() {
+		rr.renderAgenda(w, he, si)
+		return
+	}
+	main := si.Child()
+	sub := main.Next()
+	if sub != nil {
+		io.WriteString(w, "<section>\n")
+	}
+	fmt.Fprintf(w, `<section id="(%d)"`, main.SlideNo)
+	writeClass(w, main.Slide.Class())
+	if slLang := main.Slide.Lang(); slLang != "" && slLang != lang {
+		fmt.Fprintf(w, ` lang="%s"`, slLang)
+	}
+	if slides.HasProgress() {
+		writeSectionAttr(w, si)
+	}
+	countdown := main.Slide.Countdown()
+	if countdown > 0 {
+		fmt.Fprintf(w, ` data-countdown="%d"`, int(countdown/time.Second))
+	}
+	io.WriteString(w, ">\n")
+	renderRevealSlide(w, he, main)
+	if countdown > 0 {
+		writeCountdownClock(w, countdown)
+	}
+	if script := slides.Script(main.Slide.Zid()); script != nil {
+		// The script of the speaker belongs to the first slide of a zettel.
+		io.WriteString(w, "<aside class=\"notes\">")
+		he.EvaluateBlock(script)
+		io.WriteString(w, "</aside>\n")
+	}
+	io.WriteString(w, "</section>\n")
+
+	if sub != nil {
+		for {
+			fmt.Fprintf(w, `<section id="(%d)"`, sub.SlideNo)
+			writeClass(w, sub.Slide.Class())
+			if slides.HasProgress() {
+				writeSectionAttr(w, si)
 			}
+			io.WriteString(w, ">\n")
+			renderRevealSlide(w, he, sub)
 			io.WriteString(w, "</section>\n")
+			sub = sub.Next()
+			if sub == nil {
+				break
+			}
 		}
+		io.WriteString(w, "</section>\n")
 	}
-	io.WriteString(w, `</div>
-</div>
-<script src="revealjs/plugin/highlight/highlight.js"></script>
-<script src="revealjs/plugin/notes/notes.js"></script>
-<script src="revealjs/reveal.js"></script>
-<script>Reveal.initialize({width: 1920, height: 1024, center: true,
-slideNumber: "c", hash: true,
-plugins: [ RevealHighlight, RevealNotes ]});</script>
-`)
-	writeHTMLFooter(w, slides.hasMermaid)
+}
+
+func (rr *revealRenderer) renderTitleSlide(w http.ResponseWriter, slides *deck.SlideSet, title *sxpf.Pair, author string) {
+	layout := slides.TitleLayout()
+	fmt.Fprintf(w, "<section class=\"title-%s\">\n", layout)
+	if layout == deck.TitleLayoutMinimal {
+		fmt.Fprintf(w, "<h1 class=\"title\">%s</h1>\n</section>\n", render.EvaluateInline(nil, title))
+		return
+	}
+	if layout == deck.TitleLayoutSplit {
+		io.WriteString(w, "<div class=\"split\">\n<div class=\"title-text\">\n")
+	}
+	rr.cfg.branding.writeLogo(w, rr.bundle.imageURL)
+	io.WriteString(w, "<hgroup>\n")
+	fmt.Fprintf(w, "<h1 class=\"title\">%s</h1>\n", render.EvaluateInline(nil, title))
+	if subtitle := slides.Subtitle(); !subtitle.IsEmpty() {
+		fmt.Fprintf(w, "<p class=\"subtitle\">%s</p>\n", render.EvaluateInline(nil, subtitle))
+	}
+	io.WriteString(w, "</hgroup>\n")
+	if author != "" {
+		fmt.Fprintf(w, "<p class=\"author\">%s</p>\n", html.EscapeString(author))
+	}
+	rr.cfg.branding.writeFooter(w)
+	if layout == deck.TitleLayoutSplit {
+		io.WriteString(w, "</div>\n")
+		if imgZid := slides.TitleImage(); imgZid != api.InvalidZID {
+			fmt.Fprintf(w, "<div class=\"title-image\"><img src=\"%s\" alt=\"\"></div>\n", rr.bundle.imageURL(imgZid))
+		}
+		io.WriteString(w, "</div>\n")
+	}
+	io.WriteString(w, "</section>\n")
 }
 
 func writeTitle(w http.ResponseWriter, title *sxpf.Pair) {
@@ -455,23 +1057,48 @@ func writeTitle(w http.ResponseWriter, title *sxpf.Pair) {
 	}
 }
 
-func renderRevealSlide(w http.ResponseWriter, he *htmlV, si *slideInfo) {
-	if title := si.Slide.title; !title.IsEmpty() {
-		fmt.Fprintf(w, "<h1>%s</h1>", evaluateInline(he, title))
+func renderRevealSlide(w io.Writer, he *render.Generator, si *deck.SlideInfo) {
+	markZettel(w, si.Slide.Zid())
+	he.SetUnique(render.SlideUnique(si))
+	if title := si.Slide.Title(); !title.IsEmpty() {
+		fmt.Fprintf(w, "<h1>%s</h1>", render.EvaluateInline(he, title))
 	}
-	he.SetUnique(fmt.Sprintf("%d:", si.Number))
-	he.EvaluateBlock(si.Slide.content)
+	he.EvaluateBlock(si.Slide.Content())
 	he.WriteEndnotes()
-	fmt.Fprintf(w, "\n<p><a href=\"%s\" target=\"_blank\">&#9838;</a></p>\n", si.Slide.zid)
+	if zid := si.Slide.Zid(); zid.IsValid() {
+		fmt.Fprintf(w, "\n<p><a href=\"%s\" target=\"_blank\">&#9838;</a></p>\n", zid)
+	}
 }
 
-type handoutRenderer struct{}
+type handoutRenderer struct {
+	cfg       *slidesConfig
+	userCSS   []byte
+	tmpl      *template.Template
+	solutions bool // show the solutions of exercises
+	speaker   bool // show the text for the speaker
+}
+
+// newHandoutRenderer creates a renderer for a handout. The solutions of
+// exercises are only shown, if the query parameter "solutions=1" is given.
+// Text for the speaker is only shown with "speaker=1".
+func newHandoutRenderer(r *http.Request) *handoutRenderer {
+	query := r.URL.Query()
+	return &handoutRenderer{
+		solutions: query.Get("solutions") == "1",
+		speaker:   query.Get("speaker") == "1",
+	}
+}
 
-func (*handoutRenderer) Role() string                           { return SlideRoleHandout }
-func (*handoutRenderer) Prepare(context.Context, *slidesConfig) {}
-func (hr *handoutRenderer) Render(w http.ResponseWriter, slides *slideSet, author string) {
+func (*handoutRenderer) Role() string { return deck.SlideRoleHandout }
+func (hr *handoutRenderer) Prepare(ctx context.Context, cfg *slidesConfig) {
+	hr.cfg = cfg
+	hr.userCSS = cfg.getUserCSS(ctx, deck.SlideRoleHandout)
+	hr.tmpl = cfg.getTemplate(ctx, deck.SlideRoleHandout)
+}
+func (hr *handoutRenderer) Render(w http.ResponseWriter, slides *deck.SlideSet, author string) {
 	lang := slides.Lang()
-	writeHTMLHeader(w, lang, "")
+	page := hr.cfg.newHTMLPage(deck.SlideRoleHandout, lang, "", hr.tmpl)
+	writeHTMLHeader(w, page)
 	io.WriteString(w, `<style type="text/css">
 blockquote {
   border-left: 0.5rem solid lightgray;
@@ -482,8 +1109,16 @@ blockquote {
 }
 blockquote p { margin-bottom: .5rem }
 blockquote cite { font-style: normal }
+p.subtitle { font-size: 1.5em; font-weight: bold; margin-top: 0 }
+nav.toc { border-top: 1px solid lightgray; border-bottom: 1px solid lightgray; margin-bottom: 1rem }
+@media print { nav.breadcrumb { display: none } }
 </style>
 `)
+	hr.cfg.branding.writeCSS(w, deck.SlideRoleHandout)
+	writeCSS(w, hr.userCSS)
+	writeCSS(w, slides.CSS())
+	writeMotionCSS(w, slides.Motion())
+	writePrintCSS(w, slides.PrintCSS())
 
 	title := slides.Title()
 	writeTitle(w, title)
@@ -492,41 +1127,95 @@ blockquote cite { font-style: normal }
 	writeMeta(w, "copyright", copyright)
 	license := slides.License()
 	writeMeta(w, "license", license)
-	writeHTMLBody(w)
+	writeContrastHead(w, deck.SlideRoleHandout)
+	writeTextSizeHead(w)
+	writeHTMLBody(w, page)
+	writeContrastToggle(w, lang, deck.SlideRoleHandout)
+	writeTextSizeControls(w, lang)
+	writeNavigation(w, lang,
+		navItem{string(slides.Zid()), slides.HTMLTitle()},
+		navItem{"", translate(lang, "Handout")})
+	hr.cfg.branding.writeLogo(w, contentURL)
 
+	titleLevel := hr.cfg.titleLevel
 	offset := 1
 	if !title.IsEmpty() {
 		offset++
-		fmt.Fprintf(w, "<h1 id=\"(1)\">%s</h1>\n", evaluateInline(nil, title))
+		fmt.Fprintf(w, "<h1 id=\"(1)\">%s</h1>\n", render.EvaluateInline(nil, title))
 		if subtitle := slides.Subtitle(); !subtitle.IsEmpty() {
-			fmt.Fprintf(w, "<h2>%s</h2>\n", evaluateInline(nil, subtitle))
+			if titleLevel == 1 {
+				fmt.Fprintf(w, "<h2>%s</h2>\n", render.EvaluateInline(nil, subtitle))
+			} else {
+				fmt.Fprintf(w, "<p class=\"subtitle\">%s</p>\n", render.EvaluateInline(nil, subtitle))
+			}
 		}
 		writeEscapedString(w, author)
 		writeEscapedString(w, copyright)
 		writeEscapedString(w, license)
+	} else if titleLevel > 1 {
+		// There must be a single heading of level 1, for navigation.
+		fmt.Fprintf(w, "<h1>%s</h1>\n", slides.HTMLTitle())
 	}
-	he := htmlNew(w, slides, hr, 1, true, false)
-	for si := slides.Slides(SlideRoleHandout, offset); si != nil; si = si.Next() {
+	writeHandoutTOC(w, lang, slides, offset, hr.cfg.readingSpeed)
+	he := render.New(w, slides, hr.Role(), hr.cfg.verbatim, hr.cfg.handoutHeadingOffset(), hr.cfg.embedding[deck.SlideRoleHandout], hr.cfg.zettelLinks(deck.SlideRoleHandout))
+	he.SetEndnoteStyle(hr.cfg.noteStyles[deck.SlideRoleHandout])
+	he.SetHideSolutions(!hr.solutions)
+	he.SetHideSpeaker(!hr.speaker)
+	hr.cfg.setTypography(he, slides.Lang())
+	for si := slides.Slides(deck.SlideRoleHandout, offset); si != nil; si = si.Next() {
 		he.SetCurrentSlide(si)
+		he.SetUnique(render.SlideUnique(si))
 		sl := si.Slide
-		if title := sl.title; !title.IsEmpty() {
-			fmt.Fprintf(w, "<h1 id=\"(%d)\"> %s%s</h1>\n", si.Number, evaluateInline(he, title), slideNoRange(si))
+		markZettel(w, sl.Zid())
+		if title := sl.Title(); !title.IsEmpty() {
+			fmt.Fprintf(w, "<h%d id=\"(%d)\"> %s%s</h%d>\n", titleLevel, si.Number, render.EvaluateInline(he, title), slideNoRange(lang, si), titleLevel)
 		} else {
 			fmt.Fprintf(w, "<a id=\"(%d)\"></a>", si.Number)
 		}
-		slLang := sl.lang
-		if slLang != "" && slLang != lang {
-			fmt.Fprintf(w, `<div lang="%s">`, slLang)
+		slLang := sl.Lang()
+		hasLang := slLang != "" && slLang != lang
+		class := sl.Class()
+		if hasLang || class != "" {
+			io.WriteString(w, "<div")
+			writeClass(w, class)
+			if hasLang {
+				fmt.Fprintf(w, ` lang="%s"`, slLang)
+			}
+			io.WriteString(w, ">")
 		}
 
-		he.SetUnique(fmt.Sprintf("%d:", si.Number))
-		he.EvaluateBlock(sl.content)
-		if slLang != "" && slLang != lang {
+		he.EvaluateBlock(sl.Content())
+		if hasLang || class != "" {
 			io.WriteString(w, "</div>")
 		}
 	}
 	he.WriteEndnotes()
-	writeHTMLFooter(w, slides.hasMermaid)
+	he.LogUnknown(slides.Zid())
+	hr.cfg.branding.writeFooter(w)
+	writeHTMLFooter(w, page, hr.cfg.verbatim.Scripts(slides.Syntaxes()))
+}
+
+// writeClass writes a class attribute, if there are some classes.
+func writeClass(w io.Writer, class string) {
+	if class != "" {
+		fmt.Fprintf(w, ` class="%s"`, class)
+	}
+}
+
+func writeCSS(w http.ResponseWriter, css []byte) {
+	if len(css) > 0 {
+		io.WriteString(w, `<style type="text/css">`)
+		w.Write(css)
+		io.WriteString(w, "</style>\n")
+	}
+}
+
+func writePrintCSS(w http.ResponseWriter, css []byte) {
+	if len(css) > 0 {
+		io.WriteString(w, `<style type="text/css" media="print">`)
+		w.Write(css)
+		io.WriteString(w, "</style>\n")
+	}
 }
 
 func writeEscapedString(w http.ResponseWriter, s string) {
@@ -535,77 +1224,229 @@ func writeEscapedString(w http.ResponseWriter, s string) {
 	}
 }
 
-func slideNoRange(si *slideInfo) string {
+func slideNoRange(lang string, si *deck.SlideInfo) string {
 	if fromSlideNo := si.SlideNo; fromSlideNo > 0 {
 		toSlideNo := si.LastChild().SlideNo
 		if fromSlideNo >= toSlideNo {
-			return fmt.Sprintf(" <small>(S.%d)</small>", fromSlideNo)
+			return fmt.Sprintf(" <small>(%s%d)</small>", translate(lang, "S."), fromSlideNo)
 		}
-		return fmt.Sprintf(" <small>(S.%d&ndash;%d)</small>", fromSlideNo, toSlideNo)
+		return fmt.Sprintf(" <small>(%s%d&ndash;%d)</small>", translate(lang, "S."), fromSlideNo, toSlideNo)
 	}
 	return ""
 }
 
-func setupSlideSet(slides *slideSet, l []api.ZidMetaJSON, getZettel getZettelContentFunc, sGetZettel sGetZettelFunc) {
+func setupSlideSet(slides *deck.SlideSet, author string, l []api.ZidMetaJSON, getZettel deck.GetZettelContentFunc, sGetZettel deck.SGetZettelFunc) {
+	sGetZettel = prefetchZettel(l, sGetZettel)
 	for _, sl := range l {
 		slides.AddSlide(sl.ID, sGetZettel)
 	}
 	slides.Completion(getZettel, sGetZettel)
+	addAgenda(slides)
+	addClosing(slides, slides.Author(author))
 }
 
-func processList(w http.ResponseWriter, r *http.Request, c *client.Client) {
+func processList(w http.ResponseWriter, r *http.Request, cfg *slidesConfig) {
 	ctx := r.Context()
-	zQuery, zl, err := c.ListZettelJSON(ctx, r.URL.Query())
+	c := cfg.c
+	query := r.URL.Query()
+	offset, limit := pageQuery(query, cfg.listLimit)
+	zQuery, zl, err := c.ListZettelJSON(ctx, sortQuery(query))
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Error retrieving zettel list %s: %s\n", r.URL.Query(), err), http.StatusBadRequest)
 		return
 	}
+	if cfg.allow.enabled() {
+		allowed := make([]api.ZidMetaJSON, 0, len(zl))
+		for _, jm := range zl {
+			if cfg.allow.allowsMeta(jm.ID, jm.Meta) {
+				allowed = append(allowed, jm)
+			}
+		}
+		zl = allowed
+	}
 	titles := make([]string, len(zl))
 	for i, jm := range zl {
 		if sMeta, err := c.GetEvaluatedSexpr(ctx, jm.ID, api.PartMeta); err == nil {
-			titles[i] = evaluateInline(nil, getZettelTitleZid(sexpr.MakeMeta(sMeta), jm.ID))
+			titles[i] = render.EvaluateInline(nil, deck.ZettelTitleZid(sexpr.MakeMeta(sMeta), jm.ID))
 		}
 	}
 
 	var title string
 	if zQuery == "" {
-		title = "All zettel"
+		title = translate(cfg.lang, "All zettel")
 		zQuery = title
 	} else {
-		title = "Selected zettel"
-		zQuery = "Search: " + zQuery
+		title = translate(cfg.lang, "Selected zettel")
+		zQuery = translate(cfg.lang, "Search: ") + zQuery
 	}
-	writeHTMLHeader(w, "", "")
+	page := cfg.newHTMLPage(OutputList, cfg.lang, "", cfg.getTemplate(ctx, OutputList))
+	writeHTMLHeader(w, page)
+	cfg.branding.writeCSS(w, OutputList)
 	fmt.Fprintf(w, "<title>%s</title>\n", title)
-	writeHTMLBody(w)
+	writeHTMLBody(w, page)
+	writeSkipLink(w, cfg.lang)
+	writeNavigation(w, cfg.lang, navItem{"", html.EscapeString(title)})
+	cfg.branding.writeLogo(w, contentURL)
+	writeMainStart(w)
 	fmt.Fprintf(w, "<h1>%s</h1>\n", html.EscapeString(zQuery))
-	io.WriteString(w, "<ul>\n")
+	writeSearchForm(w, cfg.lang, query.Get(api.QueryKeySearch))
+	writeSortLinks(w, cfg.lang, query)
+	io.WriteString(w, "<ul data-keynav>\n")
 	for i, jm := range zl {
 		fmt.Fprintf(
 			w,
-			"<li><a href=\"%s\">%s</a></li>\n",
+			"<li><a href=\"%s\">%s</a>",
 			jm.ID,
 			titles[i],
 		)
+		writeTagFilters(w, query, jm.Meta[api.KeyTags])
+		io.WriteString(w, "</li>\n")
 	}
 	io.WriteString(w, "</ul>\n")
-	writeHTMLFooter(w, false)
+	writePageLinks(w, cfg.lang, query, offset, limit, len(zl))
+	writeMainEnd(w)
+	cfg.branding.writeFooter(w)
+	writeHTMLFooter(w, page, keyNavScript)
 }
 
-func writeHTMLHeader(w http.ResponseWriter, lang, prefix string) {
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	io.WriteString(w, "<!DOCTYPE html>\n")
-	if lang == "" {
-		io.WriteString(w, "<html>\n")
-	} else {
-		fmt.Fprintf(w, "<html lang=\"%s\">\n", lang)
+func cloneQuery(query url.Values) url.Values {
+	result := make(url.Values, len(query)+1)
+	for key, values := range query {
+		result[key] = append([]string(nil), values...)
 	}
-	io.WriteString(w, `<head>
-<meta charset="utf-8">
-<meta name="viewport" content="width=device-width, initial-scale=1.0, maximum-scale=1.0, user-scalable=no">
-<meta name="generator" content="Zettel Presenter">
-`)
-	writeDefaultCSS(w, prefix)
+	return result
+}
+
+// pageQuery determines offset and limit of the list page. If no limit is
+// given, the default limit is added to the query.
+func pageQuery(query url.Values, defLimit int) (int, int) {
+	offset, err := strconv.Atoi(query.Get(api.QueryKeyOffset))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	limit, err := strconv.Atoi(query.Get(api.QueryKeyLimit))
+	if err != nil || limit <= 0 {
+		limit = defLimit
+		if limit > 0 {
+			query.Set(api.QueryKeyLimit, strconv.Itoa(limit))
+		}
+	}
+	return offset, limit
+}
+
+func writePageLinks(w http.ResponseWriter, lang string, query url.Values, offset, limit, count int) {
+	if limit <= 0 || (offset == 0 && count < limit) {
+		return
+	}
+	io.WriteString(w, "<p class=\"pages\">")
+	if offset > 0 {
+		q := cloneQuery(query)
+		if prev := offset - limit; prev > 0 {
+			q.Set(api.QueryKeyOffset, strconv.Itoa(prev))
+		} else {
+			q.Del(api.QueryKeyOffset)
+		}
+		fmt.Fprintf(w, "<a href=\"list?%s\" rel=\"prev\">&larr; %s</a>", html.EscapeString(q.Encode()), translate(lang, "Previous"))
+	}
+	if count >= limit {
+		if offset > 0 {
+			io.WriteString(w, " | ")
+		}
+		q := cloneQuery(query)
+		q.Set(api.QueryKeyOffset, strconv.Itoa(offset+limit))
+		fmt.Fprintf(w, "<a href=\"list?%s\" rel=\"next\">%s &rarr;</a>", html.EscapeString(q.Encode()), translate(lang, "Next"))
+	}
+	io.WriteString(w, "</p>\n")
+}
+
+// Constants for the query parameter to sort the list page.
+const (
+	queryKeySort     = "sort"
+	sortTitle        = "title"
+	sortCreated      = "created"
+	sortModified     = "modified"
+	keyCreatedSortBy = "id" // The zettel identifier reflects the creation time
+)
+
+// sortQuery translates the sort query parameter into the parameters of
+// Zettelstore. Titles are sorted ascending, timestamps descending.
+func sortQuery(query url.Values) url.Values {
+	sortVal := query.Get(queryKeySort)
+	if sortVal == "" {
+		return query
+	}
+	result := cloneQuery(query)
+	result.Del(queryKeySort)
+	switch sortVal {
+	case sortTitle:
+		result.Set(api.QueryKeySort, api.KeyTitle)
+	case sortCreated:
+		result.Set(api.QueryKeyOrder, keyCreatedSortBy)
+	case sortModified:
+		result.Set(api.QueryKeyOrder, api.KeyModified)
+	}
+	return result
+}
+
+func writeSortLinks(w http.ResponseWriter, lang string, query url.Values) {
+	current := query.Get(queryKeySort)
+	fmt.Fprintf(w, "<p class=\"sort\">%s", translate(lang, "Sort: "))
+	for i, sortVal := range []string{sortTitle, sortCreated, sortModified} {
+		if i > 0 {
+			io.WriteString(w, " | ")
+		}
+		label := translate(lang, sortLabels[sortVal])
+		if sortVal == current {
+			fmt.Fprintf(w, "<strong>%s</strong>", label)
+			continue
+		}
+		q := cloneQuery(query)
+		q.Set(queryKeySort, sortVal)
+		fmt.Fprintf(w, "<a href=\"list?%s\">%s</a>", html.EscapeString(q.Encode()), label)
+	}
+	io.WriteString(w, "</p>\n")
+}
+
+var sortLabels = map[string]string{
+	sortTitle:    "Title",
+	sortCreated:  "Created",
+	sortModified: "Modified",
+}
+
+// writeTagFilters writes all tags as links that refine the given query.
+func writeTagFilters(w http.ResponseWriter, query url.Values, tags string) {
+	for _, tag := range strings.Fields(tags) {
+		q := cloneQuery(query)
+		q.Add(api.KeyTags, tag)
+		fmt.Fprintf(w, " <a class=\"tag\" href=\"list?%s\">%s</a>", html.EscapeString(q.Encode()), html.EscapeString(tag))
+	}
+}
+
+// navItem is one element of the navigation bar. An empty href denotes the
+// current page. The text is already HTML-encoded.
+type navItem struct {
+	href string
+	text string
+}
+
+// writeNavigation writes a navigation bar, which always starts with a link
+// to the index page.
+func writeNavigation(w http.ResponseWriter, lang string, items ...navItem) {
+	fmt.Fprintf(w, "<nav class=\"breadcrumb\" aria-label=\"Breadcrumb\"><a href=\"./\">%s</a>", translate(lang, "Home"))
+	for _, item := range items {
+		io.WriteString(w, " &rsaquo; ")
+		if item.href == "" {
+			fmt.Fprintf(w, "<span aria-current=\"page\">%s</span>", item.text)
+		} else {
+			fmt.Fprintf(w, "<a href=\"%s\">%s</a>", item.href, item.text)
+		}
+	}
+	io.WriteString(w, "</nav>\n")
+}
+
+func writeSearchForm(w http.ResponseWriter, lang, value string) {
+	fmt.Fprintf(w, "<form action=\"list\" method=\"get\"><input type=\"search\" name=\"%s\" value=\"%s\" aria-label=\"%s\"> <input type=\"submit\" value=\"%s\"></form>\n",
+		api.QueryKeySearch, html.EscapeString(value), translate(lang, "Search"), translate(lang, "Search"))
 }
 
 var defaultCSS = []string{
@@ -616,12 +1457,49 @@ var defaultCSS = []string{
 	"td.right,",
 	"th.right { text-align: right }",
 	"ol.zs-endnotes { padding-top: .5rem; border-top: 1px solid; font-size: smaller; margin-left: 2em; }",
+	"li.zs-endnote[data-label] { list-style: none }",
+	"li.zs-endnote[data-label]::before { content: attr(data-label); margin-left: -1.5em; display: inline-block; width: 1.5em }",
+	"p.zs-endnotes-heading { font-weight: bold; margin-bottom: 0 }",
+	"p.zs-endnotes-source { font-size: smaller; font-style: italic; margin-bottom: 0 }",
 	"a.broken { text-decoration: line-through }",
+	"a.tag { font-size: smaller }",
+	"a.skip-link { position: absolute; left: -10000px }",
+	"a.skip-link:focus { position: static }",
+	":focus-visible { outline: 3px solid #2a76dd; outline-offset: 2px }",
+	"main:focus { outline: none }",
+	"span.unsupported { font-family: monospace; color: #b00; background: #fee; border: 1px dashed #b00; padding: 0 .25em }",
+	"a.button { display: inline-block; padding: .25rem .75rem; border: 1px solid; border-radius: .25rem; text-decoration: none }",
+	"dl.summary dt { float: left; clear: left; width: 8rem; font-weight: bold }",
+	"nav.breadcrumb { font-size: smaller; border-bottom: 1px solid lightgray; padding-bottom: .25rem }",
+	".split { display: flex; align-items: center; gap: 2em }",
+	".split > div { flex: 1 }",
+	".title-image img { max-width: 100%; max-height: 80vh }",
+}
+
+// getDefaultCSS returns the lines of the default CSS, possibly changed by the
+// zettel named in the configuration.
+func getDefaultCSS(ctx context.Context, c *zsClient, m map[string]string) []string {
+	zid := api.ZettelID(m[KeyCSSDefault])
+	if !zid.IsValid() {
+		return defaultCSS
+	}
+	data, err := c.GetZettel(ctx, zid, api.PartContent)
+	if err != nil {
+		log.Println("DCSS", zid, err)
+		return defaultCSS
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if deck.IsTrue(m[KeyCSSDefaultReplace]) {
+		return lines
+	}
+	result := make([]string, 0, len(defaultCSS)+len(lines))
+	result = append(result, defaultCSS...)
+	return append(result, lines...)
 }
 
-func writeDefaultCSS(w http.ResponseWriter, prefix string) {
+func writeDefaultCSS(w io.Writer, lines []string, prefix string) {
 	io.WriteString(w, "<style type=\"text/css\">\n")
-	for _, line := range defaultCSS {
+	for _, line := range lines {
 		io.WriteString(w, prefix)
 		io.WriteString(w, line)
 		io.WriteString(w, "\n")
@@ -629,14 +1507,6 @@ func writeDefaultCSS(w http.ResponseWriter, prefix string) {
 	io.WriteString(w, "</style>\n")
 }
 
-func writeHTMLBody(w http.ResponseWriter) { io.WriteString(w, "</head>\n<body>\n") }
-func writeHTMLFooter(w http.ResponseWriter, hasMermaid bool) {
-	if hasMermaid {
-		fmt.Fprintf(w, "<script type=\"text/javascript\">\n//<![CDATA[\n%s//]]>\n</script>\n", mermaid)
-		io.WriteString(w, "<script>mermaid.initialize({startOnLoad:true});</script>\n")
-	}
-	io.WriteString(w, "</body>\n</html>\n")
-}
 func writeMeta(w http.ResponseWriter, key, val string) {
 	if val != "" {
 		fmt.Fprintf(w, "<meta name=\"%s\" content=\"%s\" />\n", key, html.EscapeString(val))
@@ -647,4 +1517,4 @@ func writeMeta(w http.ResponseWriter, key, val string) {
 var mermaid string
 
 //go:embed revealjs
-var revealjs embed.FS
+var embeddedRevealjs embed.FS
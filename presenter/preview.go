@@ -0,0 +1,84 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// The preview mode allows authors to rehearse a slide set, before the
+// visibility of its linked zettel is changed to "public". It is requested by
+// the query parameter "preview=1" and is only available, if credentials are
+// given by the option "-preview". Previews are not remembered as last good
+// responses, not stored by the browser, and not kept in the history; they are
+// neither listed as recently shown nor counted on the index page. Zettel are
+// still cached as usual. Every page is watermarked.
+
+// previewKey is the context key that marks an authorized preview request.
+type previewKey struct{}
+
+// isPreviewRequest returns true, if the request asks for the preview mode.
+func isPreviewRequest(r *http.Request) bool {
+	return r.URL.Query().Get("preview") == "1"
+}
+
+// isPreview returns true, if the preview mode was authorized for the context.
+func isPreview(ctx context.Context) bool {
+	preview, _ := ctx.Value(previewKey{}).(bool)
+	return preview
+}
+
+// servePreview checks the credentials of a preview request and calls the
+// handler without the last-good cache.
+func servePreview(w http.ResponseWriter, r *http.Request, credentials string, fn http.HandlerFunc) {
+	if credentials == "" {
+		http.Error(w, "Preview mode not available", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	if !adminAuthorized(r, credentials) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="Zettel Presenter Preview", charset="UTF-8"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	log.Println("PRVW", r.URL.Path)
+	fn(&noStoreWriter{w}, r.WithContext(context.WithValue(r.Context(), previewKey{}, true)))
+}
+
+// noStoreWriter keeps the header "Cache-Control: no-store", even if the
+// handler sets another value.
+type noStoreWriter struct {
+	http.ResponseWriter
+}
+
+func (nw *noStoreWriter) WriteHeader(status int) {
+	nw.Header().Set("Cache-Control", "no-store")
+	nw.ResponseWriter.WriteHeader(status)
+}
+
+func (nw *noStoreWriter) Write(data []byte) (int, error) {
+	nw.Header().Set("Cache-Control", "no-store")
+	return nw.ResponseWriter.Write(data)
+}
+
+// previewCSS returns CSS definitions that watermark every page of a preview.
+func previewCSS(lang string) []byte {
+	return []byte(fmt.Sprintf(`
+body::after { content: %s; position: fixed; top: 40%%; left: 0; right: 0; text-align: center;
+font: bold 12vw sans-serif; color: rgba(204, 0, 0, .2); transform: rotate(-20deg);
+pointer-events: none; z-index: 10000 }
+@media print { body::after { position: absolute } }
+`, strconv.Quote(translate(lang, "Preview"))))
+}
@@ -0,0 +1,70 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+
+	"zettelstore.de/c/text"
+	"zettelstore.de/contrib/presenter/deck"
+)
+
+// If the metadata key "slide-progress" of a slide set is true, the slide show
+// has a footer with the name of the current section and the position of the
+// current slide. A section is a part of combined slide sets, or a slide
+// zettel together with its sub-slides.
+
+// writeSectionAttr writes the name of the section of the slide as an
+// attribute of its reveal section.
+func writeSectionAttr(w io.Writer, si *deck.SlideInfo) {
+	if title := si.Section().Slide.Title(); !title.IsEmpty() {
+		fmt.Fprintf(w, ` data-section="%s"`, html.EscapeString(text.EvaluateInlineString(title)))
+	}
+}
+
+// lastSlideNo returns the number of the last slide of the slide show.
+func lastSlideNo(si *deck.SlideInfo) int {
+	result := 0
+	for ; si != nil; si = si.Next() {
+		result = si.LastChild().SlideNo
+	}
+	return result
+}
+
+// writeProgressFooter writes the footer, together with a script that updates
+// it, when another slide is shown. The footer is not shown on the title slide.
+func writeProgressFooter(w io.Writer, lang string, numSlides int) {
+	fmt.Fprintf(w, `<style type="text/css">
+div.zs-progress { position: fixed; z-index: 30; left: 50%%; bottom: .5rem; transform: translateX(-50%%); font-family: sans-serif; font-size: 1rem; color: var(--r-main-color); opacity: .7; white-space: nowrap }
+div.zs-progress[hidden] { display: none }
+div.zs-progress span.section::after { content: " · " }
+div.zs-progress span.section:empty::after { content: none }
+</style>
+<div class="zs-progress" id="zs-progress" aria-hidden="true" hidden><span class="section"></span><span class="position"></span></div>
+<script>
+(function() {
+var footer = document.getElementById("zs-progress");
+function update(ev) {
+var slide = ev.currentSlide;
+var m = slide ? /^\((\d+)\)$/.exec(slide.id) : null;
+if (!m) { footer.hidden = true; return; }
+footer.querySelector("span.section").textContent = slide.getAttribute("data-section") || "";
+footer.querySelector("span.position").textContent = %q.replace("%%d", m[1]).replace("%%d", "%d");
+footer.hidden = false;
+}
+Reveal.on("slidechanged", update);
+if (Reveal.isReady()) { update({currentSlide: Reveal.getCurrentSlide()}); } else { Reveal.on("ready", update); }
+})();
+</script>
+`, translate(lang, "Slide %d of %d"), numSlides)
+}
@@ -0,0 +1,107 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+
+	"zettelstore.de/c/text"
+	"zettelstore.de/contrib/presenter/deck"
+	"zettelstore.de/contrib/presenter/render"
+)
+
+// OutputPrompter is the output type of the teleprompter page.
+const OutputPrompter = "prompter"
+
+// prompterRenderer produces a page with the titles of all slides and the
+// notes of the speaker as plain text, which scrolls automatically. It is
+// intended to be shown on a secondary screen.
+type prompterRenderer struct {
+	cfg *slidesConfig
+}
+
+func (*prompterRenderer) Role() string { return OutputPrompter }
+func (pr *prompterRenderer) Prepare(_ context.Context, cfg *slidesConfig) {
+	pr.cfg = cfg
+}
+func (pr *prompterRenderer) Render(w http.ResponseWriter, slides *deck.SlideSet, _ string) {
+	lang := slides.Lang()
+	page := pr.cfg.newHTMLPage(OutputPrompter, lang, "", nil)
+	writeHTMLHeader(w, page)
+	io.WriteString(w, `<style type="text/css">
+body { background: black; color: white; font-family: sans-serif; font-size: 5vw; line-height: 1.4; margin: 0 5vw 100vh 5vw }
+body.mirror { transform: scaleX(-1) }
+h1, h2 { font-size: 1em; color: #ff8 }
+h2 span { color: gray }
+p { margin: 0 0 1em 0 }
+div.prompter-controls { position: fixed; bottom: 0; right: 0; font-size: 1rem; background: #333; padding: .5rem; opacity: .7 }
+</style>
+`)
+	title := slides.Title()
+	writeTitle(w, title)
+	writeHTMLBody(w, page)
+
+	offset := 1
+	if !title.IsEmpty() {
+		offset++
+		fmt.Fprintf(w, "<h1>%s</h1>\n", html.EscapeString(text.EvaluateInlineString(title)))
+	}
+	for si := slides.Slides(deck.SlideRoleShow, offset); si != nil; si = si.Next() {
+		for sub := si.Child(); sub != nil; sub = sub.Next() {
+			fmt.Fprintf(w, "<h2><span>%d</span> %s</h2>\n", sub.SlideNo, html.EscapeString(text.EvaluateInlineString(sub.Slide.Title())))
+			for _, para := range render.NotesText(sub.Slide.Content()) {
+				fmt.Fprintf(w, "<p>%s</p>\n", html.EscapeString(para))
+			}
+		}
+	}
+	fmt.Fprintf(w, `<div class="prompter-controls">%s: <span id="speed"></span> (+ / &minus;) &middot; %s (%s) &middot; %s (m)</div>
+<script>
+(function() {
+var params = new URLSearchParams(window.location.search);
+var speed = parseInt(params.get("speed") || "30", 10);
+var running = false, last = 0, pos = 0;
+var display = document.getElementById("speed");
+function show() { display.textContent = speed; }
+function step(ts) {
+if (!running) { return; }
+if (last !== 0) {
+pos += speed * (ts - last) / 1000;
+window.scrollTo(0, pos);
+}
+last = ts;
+window.requestAnimationFrame(step);
+}
+document.addEventListener("keydown", function(ev) {
+if (ev.key === " ") {
+running = !running;
+last = 0;
+pos = window.scrollY;
+if (running) { window.requestAnimationFrame(step); }
+ev.preventDefault();
+} else if (ev.key === "+") {
+speed += 10;
+} else if (ev.key === "-" && speed > 10) {
+speed -= 10;
+} else if (ev.key === "m") {
+document.body.classList.toggle("mirror");
+}
+show();
+});
+show();
+})();
+</script>
+`, translate(lang, "Speed"), translate(lang, "Start / stop"), translate(lang, "Space"), translate(lang, "Mirror"))
+	writeHTMLFooter(w, page, "")
+}
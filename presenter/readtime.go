@@ -0,0 +1,90 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"zettelstore.de/c/text"
+	"zettelstore.de/contrib/presenter/deck"
+	"zettelstore.de/contrib/presenter/render"
+)
+
+// KeyReadingSpeed is the configuration key for the number of words a reader
+// reads per minute. It is used to estimate the reading time of a handout.
+const KeyReadingSpeed = "reading-speed"
+
+// DefaultReadingSpeed is the default number of words read per minute.
+const DefaultReadingSpeed = 200
+
+// getReadingSpeed returns the value of KeyReadingSpeed.
+func getReadingSpeed(m map[string]string) int {
+	if speed, err := strconv.Atoi(m[KeyReadingSpeed]); err == nil && speed > 0 {
+		return speed
+	}
+	return DefaultReadingSpeed
+}
+
+// readingMinutes returns the estimated reading time of the given number of
+// words, rounded up to full minutes.
+func readingMinutes(words, speed int) int {
+	return (words + speed - 1) / speed
+}
+
+// slideWords returns the number of words of a slide, including its title.
+func slideWords(sl *deck.Slide) int {
+	return len(strings.Fields(text.EvaluateInlineString(sl.Title()))) + render.WordCount(sl.Content())
+}
+
+// writeHandoutTOC writes the table of contents of a handout, with the
+// estimated reading time of every section and of the whole handout.
+func writeHandoutTOC(w io.Writer, lang string, slides *deck.SlideSet, offset, speed int) {
+	type tocEntry struct {
+		number int
+		title  string
+		words  int
+	}
+	var toc []tocEntry
+	total := 0
+	for si := slides.Slides(deck.SlideRoleHandout, offset); si != nil; si = si.Next() {
+		sl := si.Slide
+		words := slideWords(sl)
+		total += words
+		if title := sl.Title(); !title.IsEmpty() {
+			toc = append(toc, tocEntry{si.Number, render.EvaluateInline(nil, title), words})
+		} else if len(toc) > 0 {
+			// Slides without a title belong to the previous section.
+			toc[len(toc)-1].words += words
+		}
+	}
+	if len(toc) == 0 {
+		return
+	}
+	tocTitle := translate(lang, "Table of contents")
+	fmt.Fprintf(w, "<nav class=\"toc\" aria-label=\"%s\">\n<h2>%s</h2>\n", tocTitle, tocTitle)
+	fmt.Fprintf(w, "<p>%s: %s</p>\n", translate(lang, "Reading time"), formatMinutes(readingMinutes(total, speed)))
+	io.WriteString(w, "<ol>\n")
+	for _, entry := range toc {
+		fmt.Fprintf(w, "<li><a href=\"#(%d)\">%s</a> <small>(%s)</small></li>\n",
+			entry.number, entry.title, formatMinutes(readingMinutes(entry.words, speed)))
+	}
+	io.WriteString(w, "</ol>\n</nav>\n")
+}
+
+func formatMinutes(minutes int) string {
+	if minutes < 1 {
+		return "&lt; 1 min"
+	}
+	return fmt.Sprintf("%d min", minutes)
+}
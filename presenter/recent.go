@@ -0,0 +1,54 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	"zettelstore.de/c/api"
+)
+
+// maxRecent is the maximum number of recently viewed slide sets to remember.
+const maxRecent = 10
+
+// recentList stores the recently viewed slide sets, most recent first.
+type recentList struct {
+	mx      sync.Mutex
+	entries []recentEntry
+}
+
+type recentEntry struct {
+	zid    api.ZettelID
+	title  string // HTML-encoded title
+	viewed time.Time
+}
+
+// Add records that the given slide set was viewed.
+func (rl *recentList) Add(zid api.ZettelID, title string) {
+	rl.mx.Lock()
+	defer rl.mx.Unlock()
+	entries := make([]recentEntry, 0, maxRecent)
+	entries = append(entries, recentEntry{zid: zid, title: title, viewed: time.Now()})
+	for _, entry := range rl.entries {
+		if entry.zid != zid && len(entries) < maxRecent {
+			entries = append(entries, entry)
+		}
+	}
+	rl.entries = entries
+}
+
+// Entries returns a copy of all recently viewed slide sets.
+func (rl *recentList) Entries() []recentEntry {
+	rl.mx.Lock()
+	defer rl.mx.Unlock()
+	return append([]recentEntry(nil), rl.entries...)
+}
@@ -0,0 +1,174 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// configStore holds the current configuration. The configuration is replaced,
+// when the configuration zettel is read again. Requests that already started
+// continue to use the previous configuration.
+type configStore struct {
+	mx         sync.RWMutex
+	reloading  sync.Mutex // serializes reload
+	cfg        *slidesConfig
+	opts       *runOptions
+	clients    *clientTracker
+	adminToken string
+}
+
+// runOptions are the command line options that may change while zettel
+// presenter is running, if they are read from a file.
+type runOptions struct {
+	timeout      *time.Duration
+	validateHTML *bool
+	cacheDir     *string
+	browser      *string
+	prefix       string       // path prefix of a tenant
+	cacheSub     string       // sub-directory of the cache directory of a tenant
+	sharedCache  string       // location of the shared cache
+	shared       *sharedCache // shared cache of a tenant
+	admin        string       // credentials of the admin page, as "user:password"
+	preview      string       // credentials of the preview mode, as "user:password"
+	history      string       // directory of rendered pages
+}
+
+// apply sets the configuration according to the options.
+func (ro *runOptions) apply(cfg *slidesConfig) error {
+	cfg.validateHTML = *ro.validateHTML
+	cacheDir := *ro.cacheDir
+	if cacheDir != "" && ro.cacheSub != "" {
+		cacheDir = filepath.Join(cacheDir, ro.cacheSub)
+	}
+	if cfg.disk == nil || cfg.disk.dir != cacheDir {
+		disk, err := newDiskCache(cacheDir, ro.shared)
+		if err != nil {
+			return err
+		}
+		cfg.disk = disk
+	}
+	cfg.screenshots = newScreenshotter(*ro.browser, ro.prefix)
+	cfg.history = ro.history
+	if cfg.history != "" && ro.cacheSub != "" {
+		cfg.history = filepath.Join(cfg.history, ro.cacheSub)
+	}
+	return nil
+}
+
+func (cs *configStore) get() *slidesConfig {
+	cs.mx.RLock()
+	defer cs.mx.RUnlock()
+	return cs.cfg
+}
+
+// reload reads the configuration zettel again. If this fails, the current
+// configuration is still used. If requested, the changed command line options
+// are applied, and all caches are flushed.
+func (cs *configStore) reload(ctx context.Context, withOptions bool) {
+	cs.reloading.Lock()
+	defer cs.reloading.Unlock()
+	old := cs.get()
+	c := old.c
+	if withOptions {
+		if *cs.opts.timeout != c.timeout {
+			c = newZsClient(c.c, *cs.opts.timeout)
+		}
+	}
+	cfg, err := getConfig(ctx, c)
+	if err != nil {
+		log.Println("CONF", err)
+		return
+	}
+	cfg.inheritState(old)
+	if withOptions {
+		cfg.thumbs = &thumbCache{}
+		cfg.lastGood = &lastGoodCache{shared: cs.opts.shared}
+		if err = cs.opts.apply(&cfg); err != nil {
+			log.Println("CONF", err)
+		}
+	}
+	cs.mx.Lock()
+	cs.cfg = &cfg
+	cs.mx.Unlock()
+	log.Println("CONF reloaded")
+}
+
+// inheritState takes all data from the old configuration that are not read
+// from the configuration zettel, e.g. caches and command line options.
+func (cfg *slidesConfig) inheritState(old *slidesConfig) {
+	cfg.thumbs = old.thumbs
+	cfg.recent = old.recent
+	cfg.slideCounts = old.slideCounts
+	cfg.allow.inherit(old.allow)
+	cfg.audit = old.audit
+	cfg.resume = old.resume
+	cfg.external = old.external
+	cfg.lastGood = old.lastGood
+	cfg.disk = old.disk
+	cfg.screenshots = old.screenshots
+	cfg.validateHTML = old.validateHTML
+	cfg.history = old.history
+}
+
+// watch reloads the configuration after every interval, if it is positive.
+func (cs *configStore) watch(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		for range time.NewTicker(interval).C {
+			cs.reload(context.Background(), false)
+		}
+	}()
+}
+
+// reloader reloads the configuration of all tenants on signal SIGHUP.
+type reloader struct {
+	mx     sync.Mutex
+	flags  *flagFile
+	stores []*configStore
+}
+
+// add registers the configuration of a tenant.
+func (rl *reloader) add(cs *configStore) {
+	rl.mx.Lock()
+	rl.stores = append(rl.stores, cs)
+	rl.mx.Unlock()
+}
+
+// watch waits for signal SIGHUP. Then the file with command line options is
+// read again, and the configuration of every tenant is reloaded in turn,
+// with all caches flushed, while the listener stays open.
+func (rl *reloader) watch() {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGHUP)
+	go func() {
+		for range sigs {
+			if err := rl.flags.apply(false); err != nil {
+				log.Println("CONF", err)
+			}
+			rl.mx.Lock()
+			stores := rl.stores
+			rl.mx.Unlock()
+			for _, cs := range stores {
+				cs.reload(context.Background(), true)
+			}
+		}
+	}()
+}
@@ -0,0 +1,294 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"codeberg.org/t73fde/sxpf"
+	"zettelstore.de/c/api"
+	"zettelstore.de/c/sexpr"
+	"zettelstore.de/contrib/presenter/deck"
+)
+
+// AsciiDocWriter writes zettel content as AsciiDoc, e.g. to process it with
+// Asciidoctor or Antora.
+type AsciiDocWriter struct {
+	w     io.Writer
+	s     *deck.SlideSet
+	links ZettelLinks
+}
+
+// NewAsciiDoc creates a new writer for AsciiDoc.
+func NewAsciiDoc(w io.Writer, s *deck.SlideSet, links ZettelLinks) *AsciiDocWriter {
+	return &AsciiDocWriter{w: w, s: s, links: links}
+}
+
+// AsciiDocAnchor returns the anchor of the given zettel.
+func AsciiDocAnchor(zid api.ZettelID) string { return "zettel-" + string(zid) }
+
+// WriteHeading writes a section title of the given level, where level 0 is
+// the document title. AsciiDoc knows only five section levels, all deeper
+// headings are written with level five.
+func (aw *AsciiDocWriter) WriteHeading(level int, anchor string, in *sxpf.Pair) {
+	if level > 5 {
+		level = 5
+	}
+	if anchor != "" {
+		fmt.Fprintf(aw.w, "[[%s]]\n", anchor)
+	}
+	fmt.Fprintf(aw.w, "%s %s\n", strings.Repeat("=", level+1), aw.inlineText(in))
+	if level > 0 {
+		io.WriteString(aw.w, "\n")
+	}
+}
+
+// WriteParagraph writes the given inline nodes as a paragraph.
+func (aw *AsciiDocWriter) WriteParagraph(in *sxpf.Pair) {
+	fmt.Fprintf(aw.w, "%s\n\n", aw.inlineText(in))
+}
+
+// WriteBlock writes the given block nodes of a handout. Headings are shifted
+// by the given offset.
+func (aw *AsciiDocWriter) WriteBlock(bn *sxpf.Pair, headingOffset int) {
+	forEachNode(bn, func(node *sxpf.Pair) { aw.writeBlockNode(node, headingOffset) })
+}
+
+func (aw *AsciiDocWriter) writeBlockNode(node *sxpf.Pair, headingOffset int) {
+	args := node.GetTail()
+	switch node.GetFirst() {
+	case sexpr.SymPara:
+		aw.WriteParagraph(args)
+	case sexpr.SymHeading:
+		if level, err := args.GetInteger(); err == nil {
+			aw.WriteHeading(int(level)+headingOffset, "", args.GetTail().GetTail().GetTail().GetTail())
+		}
+	case sexpr.SymVerbatimCode:
+		attrs := "[source]"
+		if syntax := deck.VerbatimSyntax(args); syntax != "" {
+			attrs = "[source," + syntax + "]"
+		}
+		aw.writeDelimited(attrs, "----", args)
+	case sexpr.SymVerbatimEval:
+		aw.writeDelimited(fmt.Sprintf("[%s]", deck.VerbatimSyntax(args)), "....", args)
+	case sexpr.SymVerbatimMath:
+		aw.writeDelimited("[stem]", "++++", args)
+	case sexpr.SymVerbatimHTML:
+		aw.writeDelimited("", "++++", args)
+	case sexpr.SymThematic:
+		io.WriteString(aw.w, "'''\n\n")
+	case sexpr.SymListUnordered, sexpr.SymListOrdered:
+		aw.writeList(node.GetFirst(), args, 1)
+		io.WriteString(aw.w, "\n")
+	case sexpr.SymListQuote:
+		io.WriteString(aw.w, "____\n")
+		forEachNode(args, func(n *sxpf.Pair) { aw.writeBlockNode(n, headingOffset) })
+		io.WriteString(aw.w, "____\n\n")
+	case sexpr.SymRegionBlock:
+		role := ""
+		if val, found := sexpr.GetAttributes(aw.getPair(args)).Get(""); found {
+			switch val {
+			case "show":
+				return
+			case "handout", "both":
+				role = "[.handout]\n"
+			}
+		}
+		fmt.Fprintf(aw.w, "%s--\n", role)
+		forEachNode(aw.getPair(args.GetTail()), func(n *sxpf.Pair) { aw.writeBlockNode(n, headingOffset) })
+		io.WriteString(aw.w, "--\n\n")
+	case sexpr.SymRegionQuote, sexpr.SymRegionVerse:
+		style := "quote"
+		if node.GetFirst() == sexpr.SymRegionVerse {
+			style = "verse"
+		}
+		if cite := aw.inlineText(aw.getPair(args.GetTail().GetTail())); cite != "" {
+			fmt.Fprintf(aw.w, "[%s, %s]\n", style, cite)
+		} else {
+			fmt.Fprintf(aw.w, "[%s]\n", style)
+		}
+		io.WriteString(aw.w, "____\n")
+		forEachNode(aw.getPair(args.GetTail()), func(n *sxpf.Pair) { aw.writeBlockNode(n, headingOffset) })
+		io.WriteString(aw.w, "____\n\n")
+	case sexpr.SymTable:
+		if header := aw.getPair(args); !header.IsEmpty() {
+			io.WriteString(aw.w, "[%header]\n")
+		}
+		io.WriteString(aw.w, "|===\n")
+		forEachRow(args, func(cells []string) {
+			for i, cell := range cells {
+				cells[i] = "| " + strings.ReplaceAll(cell, "|", "\\|")
+			}
+			fmt.Fprintf(aw.w, "%s\n", strings.Join(cells, " "))
+		}, aw.inlineText)
+		io.WriteString(aw.w, "|===\n\n")
+	case sexpr.SymEmbed:
+		if target, alt := aw.image(args); target != "" {
+			fmt.Fprintf(aw.w, "image::%s[%s]\n\n", target, alt)
+		}
+	}
+}
+
+// writeDelimited writes the content of a verbatim node as a delimited block.
+func (aw *AsciiDocWriter) writeDelimited(attrs, delim string, args *sxpf.Pair) {
+	content, err := args.GetTail().GetString()
+	if err != nil {
+		return
+	}
+	if attrs != "" {
+		fmt.Fprintf(aw.w, "%s\n", attrs)
+	}
+	fmt.Fprintf(aw.w, "%s\n%s\n%s\n\n", delim, strings.TrimRight(content, "\n"), delim)
+}
+
+func (aw *AsciiDocWriter) writeList(sym sxpf.Value, items *sxpf.Pair, depth int) {
+	marker := strings.Repeat("*", depth)
+	if sym == sexpr.SymListOrdered {
+		marker = strings.Repeat(".", depth)
+	}
+	for elem := items; !elem.IsNil(); elem = elem.GetTail() {
+		item, err := elem.GetPair()
+		if err != nil {
+			continue
+		}
+		first := true
+		forEachNode(item, func(n *sxpf.Pair) {
+			switch n.GetFirst() {
+			case sexpr.SymListOrdered, sexpr.SymListUnordered:
+				aw.writeList(n.GetFirst(), n.GetTail(), depth+1)
+			case sexpr.SymPara:
+				if first {
+					fmt.Fprintf(aw.w, "%s %s\n", marker, aw.inlineText(n.GetTail()))
+				} else {
+					fmt.Fprintf(aw.w, "+\n%s\n", aw.inlineText(n.GetTail()))
+				}
+			default:
+				if first {
+					fmt.Fprintf(aw.w, "%s {empty}\n", marker)
+				}
+				io.WriteString(aw.w, "+\n")
+				aw.writeBlockNode(n, 0)
+			}
+			first = false
+		})
+	}
+}
+
+func (*AsciiDocWriter) getPair(p *sxpf.Pair) *sxpf.Pair {
+	if p == nil {
+		return nil
+	}
+	result, _ := p.GetPair()
+	return result
+}
+
+// asciiDocFormat maps the name of a formatting node to the delimiters of the
+// formatted text.
+var asciiDocFormat = map[string][2]string{
+	"FORMAT-EMPH":    {"__", "__"},
+	"FORMAT-STRONG":  {"**", "**"},
+	"FORMAT-INSERT":  {"[.underline]##", "##"},
+	"FORMAT-DELETE":  {"[.line-through]##", "##"},
+	"FORMAT-SUPER":   {"^", "^"},
+	"FORMAT-SUB":     {"~", "~"},
+	"FORMAT-QUOTE":   {"\"`", "`\""},
+	"FORMAT-MARK":    {"##", "##"},
+	"FORMAT-SPAN":    {"", ""},
+	"LITERAL-CODE":   {"``+", "+``"},
+	"LITERAL-INPUT":  {"``+", "+``"},
+	"LITERAL-OUTPUT": {"``+", "+``"},
+	"LITERAL-MATH":   {"stem:[", "]"},
+}
+
+// inlineText returns the given inline nodes as AsciiDoc.
+func (aw *AsciiDocWriter) inlineText(in *sxpf.Pair) string {
+	var sb strings.Builder
+	forEachNode(in, func(node *sxpf.Pair) { aw.writeInline(&sb, node) })
+	return sb.String()
+}
+
+func (aw *AsciiDocWriter) writeInline(sb *strings.Builder, node *sxpf.Pair) {
+	args := node.GetTail()
+	switch sym := node.GetFirst(); sym {
+	case sexpr.SymText:
+		if s, err := args.GetString(); err == nil {
+			sb.WriteString(s)
+		}
+	case sexpr.SymSpace, sexpr.SymSoft:
+		sb.WriteByte(' ')
+	case sexpr.SymHard:
+		sb.WriteString(" +\n")
+	case sexpr.SymLiteralComment, sexpr.SymVerbatimComment:
+	case sexpr.SymLinkExternal:
+		if ref, err := args.GetTail().GetString(); err == nil {
+			fmt.Fprintf(sb, "%s[%s]", ref, aw.inlineText(args.GetTail().GetTail()))
+		}
+	case sexpr.SymLinkZettel:
+		text := aw.inlineText(args.GetTail().GetTail())
+		ref, err := args.GetTail().GetString()
+		if err != nil {
+			sb.WriteString(text)
+			return
+		}
+		zidVal, _, _ := strings.Cut(ref, "#")
+		zid := api.ZettelID(zidVal)
+		switch {
+		case aw.links.Policy == LinksNone:
+			sb.WriteString(text)
+		case aw.s != nil && aw.s.GetSlide(zid) != nil:
+			fmt.Fprintf(sb, "<<%s,%s>>", AsciiDocAnchor(zid), text)
+		case aw.links.Policy == LinksZettelstore:
+			fmt.Fprintf(sb, "%sh/%s[%s]", aw.links.Base, ref, text)
+		default:
+			sb.WriteString(text)
+		}
+	case sexpr.SymEmbed:
+		if target, alt := aw.image(args); target != "" {
+			fmt.Fprintf(sb, "image:%s[%s]", target, alt)
+		}
+	case sexpr.SymFootnote:
+		fmt.Fprintf(sb, "footnote:[%s]", strings.ReplaceAll(aw.inlineText(args.GetTail()), "]", "\\]"))
+	default:
+		s, ok := sym.(*sxpf.Symbol)
+		if !ok {
+			return
+		}
+		delims, found := asciiDocFormat[s.GetValue()]
+		if strings.HasPrefix(s.GetValue(), "LITERAL-") {
+			if content, err := args.GetTail().GetString(); err == nil {
+				sb.WriteString(delims[0] + content + delims[1])
+			}
+			return
+		}
+		sb.WriteString(delims[0])
+		forEachNode(args, func(n *sxpf.Pair) { aw.writeInline(sb, n) })
+		if found {
+			sb.WriteString(delims[1])
+		}
+	}
+}
+
+// image returns the target and the alternative text of an embedded image.
+// Images of zettel are referenced relative to the attribute "imagesdir".
+func (aw *AsciiDocWriter) image(args *sxpf.Pair) (string, string) {
+	ref := aw.getPair(args.GetTail())
+	target, err := ref.GetTail().GetString()
+	if err != nil {
+		return "", ""
+	}
+	if api.ZettelID(target).IsValid() {
+		target += ".content"
+	}
+	alt := strings.ReplaceAll(aw.inlineText(args.GetTail().GetTail().GetTail()), "]", "\\]")
+	return target, alt
+}
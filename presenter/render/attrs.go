@@ -0,0 +1,102 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"strings"
+
+	"codeberg.org/t73fde/sxpf"
+	"zettelstore.de/c/sexpr"
+)
+
+// hasPassThrough returns true, if the attributes contain an explicit id or a
+// data-* attribute. Both are written unchanged, e.g. to be used by scripts.
+func hasPassThrough(a sexpr.Attributes) bool {
+	for key := range a {
+		if key == "id" || strings.HasPrefix(key, "data-") {
+			return true
+		}
+	}
+	return false
+}
+
+// isSolution returns true, if the attributes mark the solution of an
+// exercise, i.e. the generic attribute or one of the classes is "solution".
+func isSolution(a sexpr.Attributes) bool { return hasClass(a, "solution") }
+
+// isSpeaker returns true, if the attributes mark text that is only for the
+// speaker, e.g. a pronunciation hint, i.e. the generic attribute or one of
+// the classes is "speaker".
+func isSpeaker(a sexpr.Attributes) bool { return hasClass(a, "speaker") }
+
+// hasClass returns true, if the generic attribute or one of the classes is
+// the given name.
+func hasClass(a sexpr.Attributes, name string) bool {
+	if val, found := a.Get(""); found && val == name {
+		return true
+	}
+	if val, found := a.Get("class"); found {
+		for _, class := range strings.Fields(val) {
+			if class == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// writeStartTag writes the start tag of an element with the given attributes.
+// The generic attribute is written as a class.
+func (v *Generator) writeStartTag(tag string, a sexpr.Attributes) {
+	if val, found := a.Get(""); found {
+		a = a.Clone().Remove("")
+		if val != "" {
+			a = a.AddClass(val)
+		}
+	}
+	v.WriteString("<")
+	v.WriteString(tag)
+	v.env.WriteAttributes(a)
+	v.WriteString(">")
+}
+
+// makeEvaluateFormatSpan returns a form for spans that keep an explicit id
+// and data-* attributes. Spans for the speaker are omitted, if requested.
+func (v *Generator) makeEvaluateFormatSpan(oldForm sxpf.Form) sxpf.Form {
+	return sxpf.NewBuiltin(
+		"span", true, 1, -1,
+		func(env sxpf.Environment, args *sxpf.Pair, _ int) (sxpf.Value, error) {
+			a := sexpr.GetAttributes(v.env.GetPair(args))
+			if v.hideSpeaker && isSpeaker(a) {
+				return nil, nil
+			}
+			if !hasPassThrough(a) && !isSpeaker(a) {
+				return oldForm.Call(env, args)
+			}
+			v.writeStartTag("span", a)
+			v.EvaluateBlock(args.GetTail())
+			v.WriteString("</span>")
+			return nil, nil
+		})
+}
+
+// evaluateRegionPassThrough writes a region block that has an explicit id or
+// data-* attributes.
+func (v *Generator) evaluateRegionPassThrough(a sexpr.Attributes, args *sxpf.Pair) {
+	v.writeStartTag("div", a)
+	v.EvaluateBlock(v.env.GetPair(args.GetTail()))
+	if cite := v.env.GetPair(args.GetTail().GetTail()); !cite.IsEmpty() {
+		v.WriteString("<cite>")
+		v.EvaluateBlock(cite)
+		v.WriteString("</cite>")
+	}
+	v.WriteString("</div>\n")
+}
@@ -0,0 +1,129 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"strings"
+
+	"codeberg.org/t73fde/sxpf"
+	"zettelstore.de/c/sexpr"
+)
+
+// EmojiMap maps a shortcode, e.g. "smile" for ":smile:", to its emoji.
+type EmojiMap map[string]string
+
+// NewEmojiMap returns a map with some often used shortcodes, named like the
+// shortcodes of GitHub and others.
+func NewEmojiMap() EmojiMap {
+	return EmojiMap{
+		"+1": "👍", "-1": "👎", "thumbsup": "👍", "thumbsdown": "👎", "clap": "👏", "wave": "👋",
+		"smile": "😄", "smiley": "😃", "grin": "😁", "laughing": "😆", "wink": "😉", "blush": "😊",
+		"joy": "😂", "thinking": "🤔", "confused": "😕", "cry": "😢", "scream": "😱", "sunglasses": "😎",
+		"heart": "❤️", "star": "⭐", "sparkles": "✨", "fire": "🔥", "tada": "🎉", "rocket": "🚀",
+		"bulb": "💡", "warning": "⚠️", "x": "❌", "white_check_mark": "✅", "heavy_check_mark": "✔️",
+		"question": "❓", "exclamation": "❗", "no_entry": "⛔", "stop_sign": "🛑", "construction": "🚧",
+		"bug": "🐛", "lock": "🔒", "unlock": "🔓", "key": "🔑", "memo": "📝", "book": "📖",
+		"books": "📚", "calendar": "📅", "clock": "🕐", "hourglass": "⌛", "email": "📧", "phone": "📞",
+		"computer": "💻", "gear": "⚙️", "wrench": "🔧", "hammer": "🔨", "mag": "🔍", "link": "🔗",
+		"pushpin": "📌", "chart_with_upwards_trend": "📈", "chart_with_downwards_trend": "📉",
+		"arrow_right": "➡️", "arrow_left": "⬅️", "arrow_up": "⬆️", "arrow_down": "⬇️",
+		"coffee": "☕", "pizza": "🍕", "beer": "🍺", "trophy": "🏆", "muscle": "💪", "eyes": "👀",
+		"100": "💯", "zzz": "💤", "sun": "☀️", "cloud": "☁️", "zap": "⚡", "snowflake": "❄️",
+	}
+}
+
+// ReplaceEmojis returns the given nodes, where all shortcodes like ":smile:"
+// within text are replaced by their emoji. The given list is not changed; if
+// no shortcode is found, it is returned unchanged. Verbatim nodes, e.g. code,
+// are not changed.
+func ReplaceEmojis(nodes *sxpf.Pair, emojis EmojiMap) *sxpf.Pair {
+	if len(emojis) == 0 || nodes.IsNil() {
+		return nodes
+	}
+	return emojis.list(nodes)
+}
+
+// list replaces the shortcodes of all elements of a list.
+func (em EmojiMap) list(lst *sxpf.Pair) *sxpf.Pair {
+	var elems []sxpf.Value
+	changed := false
+	for elem := lst; !elem.IsNil(); elem = elem.GetTail() {
+		val := elem.GetFirst()
+		if node, isPair := val.(*sxpf.Pair); isPair && !node.IsNil() {
+			if newNode := em.node(node); newNode != node {
+				val = newNode
+				changed = true
+			}
+		}
+		elems = append(elems, val)
+		if elem.GetTail() == nil {
+			break
+		}
+	}
+	if !changed {
+		return lst
+	}
+	return sxpf.NewPairFromSlice(elems)
+}
+
+// node replaces the shortcodes of a single node.
+func (em EmojiMap) node(node *sxpf.Pair) *sxpf.Pair {
+	sym, isSym := node.GetFirst().(*sxpf.Symbol)
+	if !isSym {
+		return em.list(node) // e.g. a list item, or a table row
+	}
+	name := sym.GetValue()
+	if strings.HasPrefix(name, "VERBATIM-") || strings.HasPrefix(name, "LITERAL-") {
+		return node
+	}
+	if sym == sexpr.SymText {
+		if s, ok := nodeText(node); ok {
+			if replaced := em.text(s); replaced != s {
+				return makeText(replaced)
+			}
+		}
+		return node
+	}
+	args := node.GetTail()
+	if newArgs := em.list(args); newArgs != args {
+		return sxpf.NewPair(sym, newArgs)
+	}
+	return node
+}
+
+// text replaces all known shortcodes of the given string.
+func (em EmojiMap) text(s string) string {
+	var sb strings.Builder
+	for {
+		start := strings.IndexByte(s, ':')
+		if start < 0 {
+			break
+		}
+		length := strings.IndexByte(s[start+1:], ':')
+		if length < 0 {
+			break
+		}
+		if emoji, found := em[s[start+1:start+1+length]]; found && length > 0 {
+			sb.WriteString(s[:start])
+			sb.WriteString(emoji)
+			s = s[start+length+2:]
+			continue
+		}
+		// The second colon may start a shortcode.
+		sb.WriteString(s[:start+1+length])
+		s = s[start+1+length:]
+	}
+	if sb.Len() == 0 {
+		return s
+	}
+	sb.WriteString(s)
+	return sb.String()
+}
@@ -0,0 +1,189 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"codeberg.org/t73fde/sxpf"
+	"zettelstore.de/c/api"
+	"zettelstore.de/c/sexpr"
+)
+
+// Numbering styles of endnotes.
+const (
+	NumberingDecimal    = "decimal"     // 1, 2, 3, ...
+	NumberingLowerAlpha = "lower-alpha" // a, b, c, ...
+	NumberingUpperAlpha = "upper-alpha" // A, B, C, ...
+	NumberingLowerRoman = "lower-roman" // i, ii, iii, ...
+	NumberingUpperRoman = "upper-roman" // I, II, III, ...
+	NumberingSymbols    = "symbols"     // *, †, ‡, ...
+)
+
+// EndnoteStyle specifies how endnotes are numbered and placed.
+type EndnoteStyle struct {
+	Numbering string // One of the Numbering* constants; NumberingDecimal if empty
+	Heading   string // Text of a heading before the endnotes; no heading if empty
+	PerSource bool   // Keep endnotes per source zettel, instead of merging them
+}
+
+// endnote stores a footnote until it is written as an endnote.
+type endnote struct {
+	id      string // unique id of the endnote, without prefix "fn:" / "fnref:"
+	label   string
+	source  api.ZettelID
+	title   *sxpf.Pair // title of the source zettel
+	attrs   sexpr.Attributes
+	content *sxpf.Pair
+}
+
+// SetEndnoteStyle changes the style of endnotes.
+func (v *Generator) SetEndnoteStyle(style EndnoteStyle) { v.noteStyle = style }
+
+// generateFootnote writes a reference to an endnote and stores the footnote
+// content, so that it can be written by WriteEndnotes.
+func (v *Generator) generateFootnote(_ sxpf.Environment, args *sxpf.Pair, _ int) (sxpf.Value, error) {
+	note := endnote{
+		attrs:   sexpr.GetAttributes(v.env.GetPair(args)),
+		content: args.GetTail(),
+	}
+	if si := v.curSlide; si != nil {
+		note.source = si.Slide.Zid()
+		note.title = si.Slide.Title()
+	}
+	num := 1
+	if v.noteStyle.PerSource {
+		for i := len(v.notes) - 1; i >= 0 && v.notes[i].source == note.source; i-- {
+			num++
+		}
+	} else {
+		num += len(v.notes)
+	}
+	v.noteCount++
+	note.id = v.unique + strconv.Itoa(v.noteCount)
+	note.label = endnoteLabel(v.noteStyle.Numbering, num)
+	v.notes = append(v.notes, note)
+	fmt.Fprintf(v, "<sup id=\"fnref:%s\"><a class=\"zs-noteref\" href=\"#fn:%s\" role=\"doc-noteref\">%s</a></sup>",
+		note.id, note.id, note.label)
+	return nil, nil
+}
+
+// WriteEndnotes writes all stored endnotes and removes them afterwards.
+func (v *Generator) WriteEndnotes() {
+	if len(v.notes) == 0 {
+		return
+	}
+	if heading := v.noteStyle.Heading; heading != "" {
+		v.WriteString("<p class=\"zs-endnotes-heading\">")
+		v.env.WriteEscaped(heading)
+		v.WriteString("</p>\n")
+	}
+	notes := v.notes
+	v.notes = nil
+	for len(notes) > 0 {
+		n := len(notes)
+		if v.noteStyle.PerSource {
+			n = 1
+			for n < len(notes) && notes[n].source == notes[0].source {
+				n++
+			}
+			if title := notes[0].title; !title.IsEmpty() {
+				fmt.Fprintf(v, "<p class=\"zs-endnotes-source\">%s</p>\n", EvaluateInline(v, title))
+			}
+		}
+		v.writeEndnoteList(notes[:n])
+		notes = notes[n:]
+	}
+}
+
+func (v *Generator) writeEndnoteList(notes []endnote) {
+	v.WriteString("<ol class=\"zs-endnotes\"")
+	if typ := listType(v.noteStyle.Numbering); typ != "" {
+		fmt.Fprintf(v, " type=\"%s\"", typ)
+	}
+	v.WriteString(">\n")
+	for i, note := range notes {
+		a := note.attrs.Clone().AddClass("zs-endnote").Set("id", "fn:"+note.id).Set("role", "doc-endnote")
+		if v.noteStyle.Numbering == NumberingSymbols {
+			a = a.Set("data-label", note.label)
+		} else {
+			a = a.Set("value", strconv.Itoa(i+1))
+		}
+		v.WriteString("<li")
+		v.env.WriteAttributes(a)
+		v.WriteString(">")
+		v.EvaluateBlock(note.content)
+		fmt.Fprintf(v, " <a class=\"zs-endnote-backref\" href=\"#fnref:%s\" role=\"doc-backlink\">&#x21a9;&#xfe0e;</a></li>\n", note.id)
+	}
+	v.WriteString("</ol>\n")
+}
+
+// listType returns the value of the HTML type attribute of an ordered list
+// for the given numbering style.
+func listType(numbering string) string {
+	switch numbering {
+	case NumberingLowerAlpha:
+		return "a"
+	case NumberingUpperAlpha:
+		return "A"
+	case NumberingLowerRoman:
+		return "i"
+	case NumberingUpperRoman:
+		return "I"
+	}
+	return ""
+}
+
+// endnoteLabel returns the label of the n-th endnote (n >= 1).
+func endnoteLabel(numbering string, n int) string {
+	switch numbering {
+	case NumberingLowerAlpha:
+		return alphaLabel(n)
+	case NumberingUpperAlpha:
+		return strings.ToUpper(alphaLabel(n))
+	case NumberingLowerRoman:
+		return strings.ToLower(romanLabel(n))
+	case NumberingUpperRoman:
+		return romanLabel(n)
+	case NumberingSymbols:
+		symbols := []string{"*", "†", "‡", "§", "‖", "¶"}
+		return strings.Repeat(symbols[(n-1)%len(symbols)], (n-1)/len(symbols)+1)
+	}
+	return strconv.Itoa(n)
+}
+
+func alphaLabel(n int) string {
+	var buf []byte
+	for ; n > 0; n = (n - 1) / 26 {
+		buf = append([]byte{byte('a' + (n-1)%26)}, buf...)
+	}
+	return string(buf)
+}
+
+var romanNumerals = []struct {
+	value  int
+	symbol string
+}{
+	{1000, "M"}, {900, "CM"}, {500, "D"}, {400, "CD"}, {100, "C"}, {90, "XC"},
+	{50, "L"}, {40, "XL"}, {10, "X"}, {9, "IX"}, {5, "V"}, {4, "IV"}, {1, "I"},
+}
+
+func romanLabel(n int) string {
+	var sb strings.Builder
+	for _, rn := range romanNumerals {
+		for ; n >= rn.value; n -= rn.value {
+			sb.WriteString(rn.symbol)
+		}
+	}
+	return sb.String()
+}
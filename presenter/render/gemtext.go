@@ -0,0 +1,307 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"codeberg.org/t73fde/sxpf"
+	"zettelstore.de/c/api"
+	"zettelstore.de/c/sexpr"
+	"zettelstore.de/contrib/presenter/deck"
+)
+
+// GemtextWriter writes zettel content as gemtext, the markup language of the
+// Gemini protocol. Gemtext is line oriented and knows only headings, text
+// lines, links lines, list items, quotes, and preformatted text. Therefore,
+// all inline formatting is removed, and links are written as link lines after
+// the text line that contains them.
+type GemtextWriter struct {
+	w       io.Writer
+	s       *deck.SlideSet
+	links   ZettelLinks
+	pending []gemLink // links of the current text line
+	notes   []string  // footnotes of the current slide
+}
+
+type gemLink struct {
+	url  string
+	text string
+}
+
+// NewGemtext creates a new writer for gemtext.
+func NewGemtext(w io.Writer, s *deck.SlideSet, links ZettelLinks) *GemtextWriter {
+	return &GemtextWriter{w: w, s: s, links: links}
+}
+
+// WriteHeading writes a heading of the given level. Gemtext knows only three
+// levels, all deeper headings are written with level three.
+func (gw *GemtextWriter) WriteHeading(level int, in *sxpf.Pair) {
+	if level > 3 {
+		level = 3
+	}
+	fmt.Fprintf(gw.w, "%s %s\n\n", strings.Repeat("#", level), gw.inlineText(in))
+	gw.writeLinks()
+}
+
+// WriteText writes a line of text, followed by an empty line.
+func (gw *GemtextWriter) WriteText(s string) {
+	fmt.Fprintf(gw.w, "%s\n\n", s)
+}
+
+// WriteBlock writes the given block nodes of a handout. Headings are shifted
+// by the given offset.
+func (gw *GemtextWriter) WriteBlock(bn *sxpf.Pair, headingOffset int) {
+	forEachNode(bn, func(node *sxpf.Pair) { gw.writeBlockNode(node, headingOffset, "") })
+}
+
+// WriteNotes writes all collected footnotes and removes them afterwards.
+func (gw *GemtextWriter) WriteNotes() {
+	for i, note := range gw.notes {
+		fmt.Fprintf(gw.w, "[%d] %s\n", i+1, note)
+	}
+	if len(gw.notes) > 0 {
+		io.WriteString(gw.w, "\n")
+		gw.notes = nil
+		gw.writeLinks()
+	}
+}
+
+func (gw *GemtextWriter) writeBlockNode(node *sxpf.Pair, headingOffset int, prefix string) {
+	args := node.GetTail()
+	switch node.GetFirst() {
+	case sexpr.SymPara:
+		gw.writeLines(prefix, gw.inlineText(args))
+	case sexpr.SymHeading:
+		if level, err := args.GetInteger(); err == nil {
+			gw.WriteHeading(int(level)+headingOffset, args.GetTail().GetTail().GetTail().GetTail())
+		}
+	case sexpr.SymVerbatimCode, sexpr.SymVerbatimEval, sexpr.SymVerbatimHTML, sexpr.SymVerbatimMath:
+		if content, err := args.GetTail().GetString(); err == nil {
+			fmt.Fprintf(gw.w, "```%s\n%s\n```\n\n", deck.VerbatimSyntax(args), strings.TrimRight(content, "\n"))
+		}
+	case sexpr.SymListUnordered, sexpr.SymListOrdered, sexpr.SymListQuote:
+		gw.writeList(node.GetFirst(), args)
+	case sexpr.SymRegionBlock:
+		if val, found := sexpr.GetAttributes(gw.getPair(args)).Get(""); found && val == "show" {
+			return
+		}
+		forEachNode(gw.getPair(args.GetTail()), func(n *sxpf.Pair) { gw.writeBlockNode(n, headingOffset, prefix) })
+	case sexpr.SymRegionQuote:
+		forEachNode(gw.getPair(args.GetTail()), func(n *sxpf.Pair) { gw.writeBlockNode(n, headingOffset, "> ") })
+		if cite := gw.inlineText(gw.getPair(args.GetTail().GetTail())); cite != "" {
+			gw.writeLines("> — ", cite)
+		}
+	case sexpr.SymRegionVerse:
+		forEachNode(gw.getPair(args.GetTail()), func(n *sxpf.Pair) { gw.writeBlockNode(n, headingOffset, prefix) })
+	case sexpr.SymTable:
+		io.WriteString(gw.w, "```\n")
+		forEachRow(args, func(cells []string) { fmt.Fprintf(gw.w, "| %s |\n", strings.Join(cells, " | ")) }, gw.inlineText)
+		io.WriteString(gw.w, "```\n\n")
+		gw.writeLinks()
+	case sexpr.SymEmbed, sexpr.SymTransclude:
+		gw.writeLines(prefix, gw.inlineText(sxpf.NewPair(node, nil)))
+	}
+}
+
+func (gw *GemtextWriter) writeList(sym sxpf.Value, items *sxpf.Pair) {
+	gw.writeListItems(sym, items)
+	io.WriteString(gw.w, "\n")
+	gw.writeLinks()
+}
+
+// writeListItems writes all items of a list. Gemtext has no nested lists,
+// therefore the items of a nested list are written after the item that
+// contains the nested list.
+func (gw *GemtextWriter) writeListItems(sym sxpf.Value, items *sxpf.Pair) {
+	num := 0
+	for elem := items; !elem.IsNil(); elem = elem.GetTail() {
+		item, err := elem.GetPair()
+		if err != nil {
+			continue
+		}
+		num++
+		var sb strings.Builder
+		var nested []*sxpf.Pair
+		forEachNode(item, func(n *sxpf.Pair) {
+			switch n.GetFirst() {
+			case sexpr.SymListOrdered, sexpr.SymListUnordered, sexpr.SymListQuote:
+				nested = append(nested, n)
+			default:
+				if sb.Len() > 0 {
+					sb.WriteByte(' ')
+				}
+				sb.WriteString(strings.ReplaceAll(gw.inlineText(n.GetTail()), "\n", " "))
+			}
+		})
+		switch sym {
+		case sexpr.SymListOrdered:
+			fmt.Fprintf(gw.w, "* %d. %s\n", num, sb.String())
+		case sexpr.SymListQuote:
+			fmt.Fprintf(gw.w, "> %s\n", sb.String())
+		default:
+			fmt.Fprintf(gw.w, "* %s\n", sb.String())
+		}
+		for _, n := range nested {
+			gw.writeListItems(n.GetFirst(), n.GetTail())
+		}
+	}
+}
+
+// writeLines writes every line of the given text, with the given prefix,
+// followed by the collected links.
+func (gw *GemtextWriter) writeLines(prefix, text string) {
+	if text == "" {
+		gw.writeLinks()
+		return
+	}
+	for _, line := range strings.Split(text, "\n") {
+		fmt.Fprintf(gw.w, "%s%s\n", prefix, strings.TrimSpace(line))
+	}
+	io.WriteString(gw.w, "\n")
+	gw.writeLinks()
+}
+
+func (gw *GemtextWriter) writeLinks() {
+	for _, l := range gw.pending {
+		if l.text == "" {
+			fmt.Fprintf(gw.w, "=> %s\n", l.url)
+		} else {
+			fmt.Fprintf(gw.w, "=> %s %s\n", l.url, l.text)
+		}
+	}
+	if len(gw.pending) > 0 {
+		io.WriteString(gw.w, "\n")
+		gw.pending = nil
+	}
+}
+
+func (*GemtextWriter) getPair(p *sxpf.Pair) *sxpf.Pair {
+	if p == nil {
+		return nil
+	}
+	result, _ := p.GetPair()
+	return result
+}
+
+// inlineText returns the text of the given inline nodes. Hard line breaks are
+// returned as new lines, all links are collected to be written later.
+func (gw *GemtextWriter) inlineText(in *sxpf.Pair) string {
+	var sb strings.Builder
+	forEachNode(in, func(node *sxpf.Pair) { gw.writeInline(&sb, node) })
+	return strings.Join(strings.FieldsFunc(sb.String(), func(r rune) bool { return r == ' ' || r == '\t' }), " ")
+}
+
+func (gw *GemtextWriter) writeInline(sb *strings.Builder, node *sxpf.Pair) {
+	args := node.GetTail()
+	switch sym := node.GetFirst(); sym {
+	case sexpr.SymText:
+		if s, err := args.GetString(); err == nil {
+			sb.WriteString(s)
+		}
+	case sexpr.SymSpace, sexpr.SymSoft:
+		sb.WriteByte(' ')
+	case sexpr.SymHard:
+		sb.WriteByte('\n')
+	case sexpr.SymLiteralComment, sexpr.SymVerbatimComment:
+	case sexpr.SymLinkExternal:
+		text := gw.inlineText(args.GetTail().GetTail())
+		sb.WriteString(text)
+		if ref, err := args.GetTail().GetString(); err == nil {
+			gw.pending = append(gw.pending, gemLink{ref, text})
+		}
+	case sexpr.SymLinkZettel:
+		text := gw.inlineText(args.GetTail().GetTail())
+		sb.WriteString(text)
+		if ref, err := args.GetTail().GetString(); err == nil {
+			if url := gw.zettelURL(ref); url != "" {
+				gw.pending = append(gw.pending, gemLink{url, text})
+			}
+		}
+	case sexpr.SymEmbed:
+		text := gw.inlineText(args.GetTail().GetTail().GetTail())
+		if ref, err := gw.getPair(args.GetTail()).GetTail().GetString(); err == nil {
+			if url := gw.zettelURL(ref); url != "" {
+				gw.pending = append(gw.pending, gemLink{url, text})
+			}
+		}
+	case sexpr.SymFootnote:
+		gw.notes = append(gw.notes, gw.inlineText(args.GetTail()))
+		sb.WriteString("[" + strconv.Itoa(len(gw.notes)) + "]")
+	default:
+		if s, ok := sym.(*sxpf.Symbol); ok && strings.HasPrefix(s.GetValue(), "LITERAL-") {
+			if content, err := args.GetTail().GetString(); err == nil {
+				sb.WriteString(content)
+			}
+			return
+		}
+		forEachNode(args, func(n *sxpf.Pair) { gw.writeInline(sb, n) })
+	}
+}
+
+// zettelURL returns the URL of a linked zettel. Zettel of the slide set and
+// zettel that should not be linked result in an empty string.
+func (gw *GemtextWriter) zettelURL(ref string) string {
+	zidVal, _, _ := strings.Cut(ref, "#")
+	zid := api.ZettelID(zidVal)
+	if !zid.IsValid() {
+		return ref
+	}
+	if gw.s != nil && gw.s.GetSlide(zid) != nil {
+		return ""
+	}
+	if gw.links.Policy == LinksZettelstore {
+		return gw.links.Base + "h/" + ref
+	}
+	return ""
+}
+
+// forEachNode calls the function for every node of the given list. Elements
+// that are lists of nodes themselves, e.g. the items of a list, are traversed
+// too.
+func forEachNode(list *sxpf.Pair, fn func(*sxpf.Pair)) {
+	for elem := list; !elem.IsNil(); elem = elem.GetTail() {
+		node, err := elem.GetPair()
+		if err != nil || node.IsNil() {
+			continue
+		}
+		if _, isSym := node.GetFirst().(*sxpf.Symbol); isSym {
+			fn(node)
+		} else {
+			forEachNode(node, fn)
+		}
+	}
+}
+
+// forEachRow calls the function with the text of all cells of every row of a
+// table.
+func forEachRow(rows *sxpf.Pair, fn func([]string), text func(*sxpf.Pair) string) {
+	for elem := rows; !elem.IsNil(); elem = elem.GetTail() {
+		row, err := elem.GetPair()
+		if err != nil || row.IsNil() {
+			continue
+		}
+		var cells []string
+		for c := row; !c.IsNil(); c = c.GetTail() {
+			if cell, err := c.GetPair(); err == nil {
+				if _, isSym := cell.GetFirst().(*sxpf.Symbol); isSym {
+					cells = append(cells, text(cell.GetTail()))
+				}
+			}
+		}
+		if len(cells) > 0 {
+			fn(cells)
+		}
+	}
+}
@@ -0,0 +1,481 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+// Package render generates HTML code for the content of slides and zettel.
+package render
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+
+	"codeberg.org/t73fde/sxpf"
+	"zettelstore.de/c/api"
+	"zettelstore.de/c/html"
+	"zettelstore.de/c/sexpr"
+	"zettelstore.de/contrib/presenter/deck"
+)
+
+// New creates a new generator that writes HTML code to the given writer. The
+// role specifies the renderer, i.e. the output type, for which HTML code is
+// generated.
+func New(w io.Writer, s *deck.SlideSet, role string, vr VerbatimRegistry, headingOffset int, embed ImageEmbedding, links ZettelLinks) *Generator {
+	env := html.NewEncEnvironment(w, headingOffset)
+	v := &Generator{
+		env:      env,
+		s:        s,
+		role:     role,
+		embed:    embed,
+		links:    links,
+		verbatim: vr,
+		syntaxes: make(map[string]bool),
+		unknown:  make(map[string]int),
+	}
+	v.Rebind(defaultRebindings...)
+	return v
+}
+
+// Rebind applies the given rebindings to the HTML encoder of this generator,
+// in the given order, after the rebindings of zettel presenter. Other tools
+// may use it to change the generated HTML code. Other generators are not
+// affected.
+func (v *Generator) Rebind(rbs ...Rebinding) {
+	builtins := v.env.Builtins
+	for _, rb := range rbs {
+		if rb.appliesTo(v.role) {
+			oldForm, _ := builtins.LookupForm(rb.Sym)
+			builtins.Set(rb.Sym, rb.Bind(v, oldForm))
+		}
+	}
+}
+
+// Rebinding replaces the form that the HTML encoder uses for a node symbol.
+type Rebinding struct {
+	Sym   *sxpf.Symbol
+	Roles []string // Renderer roles the rebinding applies to; all roles if empty
+	Bind  func(g *Generator, oldForm sxpf.Form) sxpf.Form
+}
+
+func (rb *Rebinding) appliesTo(role string) bool {
+	if len(rb.Roles) == 0 {
+		return true
+	}
+	for _, r := range rb.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultRebindings lists all changes to the HTML encoder. The entries are
+// applied in the given order, so that a later entry may wrap the form of an
+// earlier one.
+var defaultRebindings = []Rebinding{
+	{sexpr.SymRegionBlock, nil, (*Generator).makeEvaluateBlock},
+	{sexpr.SymFormatSpan, nil, (*Generator).makeEvaluateFormatSpan},
+	{sexpr.SymVerbatimEval, nil, (*Generator).makeEvaluateVerbatimEval},
+	{sexpr.SymVerbatimComment, nil, bindNothing("verb-comm", 1)},
+	{sexpr.SymLinkZettel, nil, bindBuiltin("linkZ", 2, (*Generator).generateLinkZettel)},
+	{sexpr.SymLinkExternal, nil, bindBuiltin("linkE", 2, (*Generator).generateLinkExternal)},
+	{sexpr.SymEmbed, nil, bindBuiltin("embed", 3, (*Generator).generateEmbed)},
+	{sexpr.SymFootnote, nil, bindBuiltin("footnote", 1, (*Generator).generateFootnote)},
+	{sexpr.SymLiteralComment, nil, bindNothing("lit-comm", 1)},
+}
+
+// bindBuiltin returns a binding function for a special form that is
+// implemented by a method of Generator.
+func bindBuiltin(name string, minArgs int, fn func(*Generator, sxpf.Environment, *sxpf.Pair, int) (sxpf.Value, error)) func(*Generator, sxpf.Form) sxpf.Form {
+	return func(v *Generator, _ sxpf.Form) sxpf.Form {
+		return sxpf.NewBuiltin(name, true, minArgs, -1,
+			func(env sxpf.Environment, args *sxpf.Pair, numArgs int) (sxpf.Value, error) {
+				return fn(v, env, args, numArgs)
+			})
+	}
+}
+
+// bindNothing returns a binding function for a special form that ignores the
+// node.
+func bindNothing(name string, minArgs int) func(*Generator, sxpf.Form) sxpf.Form {
+	return func(*Generator, sxpf.Form) sxpf.Form { return sxpf.NewBuiltin(name, true, minArgs, -1, formNothing) }
+}
+
+func formNothing(sxpf.Environment, *sxpf.Pair, int) (sxpf.Value, error) { return nil, nil }
+
+func (v *Generator) SetUnique(s string) {
+	v.unique = s
+	v.env.SetUnique(s)
+}
+
+func (v *Generator) SetCurrentSlide(si *deck.SlideInfo) { v.curSlide = si }
+
+// SetTypography enables language-specific typography. The given language is
+// used for all content that is not part of a slide with its own language.
+func (v *Generator) SetTypography(lang string) {
+	v.typography = true
+	v.lang = lang
+}
+
+// SetEmojis enables the replacement of shortcodes like ":smile:" by the emoji
+// of the given map.
+func (v *Generator) SetEmojis(emojis EmojiMap) { v.emojis = emojis }
+
+// applyTypography returns the nodes with the content of embedded text zettel,
+// with replaced emoji shortcodes, and with the typography of the current
+// language, if enabled.
+func (v *Generator) applyTypography(nodes *sxpf.Pair) *sxpf.Pair {
+	nodes = v.transclude(nodes, 0)
+	nodes = ReplaceEmojis(nodes, v.emojis)
+	if !v.typography {
+		return nodes
+	}
+	lang := v.lang
+	if v.curSlide != nil && v.curSlide.Slide.Lang() != "" {
+		lang = v.curSlide.Slide.Lang()
+	}
+	return ApplyTypography(nodes, lang)
+}
+
+// SlideUnique returns the prefix for all ids generated for the given slide.
+func SlideUnique(si *deck.SlideInfo) string { return fmt.Sprintf("%d:", si.Number) }
+
+// EvaluateInline returns the HTML code of the given inline nodes.
+func EvaluateInline(baseV *Generator, in *sxpf.Pair) string {
+	if baseV == nil {
+		return html.EvaluateInline(nil, in, false, false)
+	}
+	return html.EvaluateInline(baseV.env, baseV.applyTypography(in), true, true)
+}
+
+// EvaluateBlock writes the HTML of all given block nodes. If a node type is
+// not supported, a placeholder is written instead, so that authors will notice
+// content that could not be presented.
+func (v *Generator) EvaluateBlock(bn *sxpf.Pair) {
+	for elem := v.applyTypography(bn); !elem.IsNil(); elem = elem.GetTail() {
+		node, err := elem.GetPair()
+		if err != nil {
+			continue
+		}
+		if _, err = v.env.EvalPair(node); err != nil {
+			var nfbErr *sxpf.NotFormBoundError
+			if errors.As(err, &nfbErr) {
+				v.writeUnsupported(nfbErr.Sym)
+			} else {
+				log.Println("EVAL", err)
+			}
+		}
+	}
+}
+
+func (v *Generator) writeUnsupported(sym *sxpf.Symbol) {
+	v.unknown[sym.String()]++
+	v.WriteString("<span class=\"unsupported\">[unsupported: ")
+	v.env.WriteEscaped(sym.String())
+	v.WriteString("]</span>")
+}
+
+type Generator struct {
+	env      *html.EncEnvironment
+	s        *deck.SlideSet
+	curSlide *deck.SlideInfo
+	role     string
+	embed    ImageEmbedding
+	imageURL ImageURLFunc
+	links    ZettelLinks
+	verbatim VerbatimRegistry
+	syntaxes map[string]bool // syntaxes of all rendered verbatim-eval nodes
+	unknown  map[string]int  // number of occurrences of unsupported nodes
+
+	unique    string // prefix of all generated ids
+	noteStyle EndnoteStyle
+	notes     []endnote // footnotes that are not written yet
+	noteCount int       // number of all footnotes, used for unique ids
+
+	typography bool   // apply language-specific typography
+	lang       string // language of the content, if a slide specifies none
+	emojis     EmojiMap
+
+	hideSolutions bool // omit regions with the class "solution"
+	hideSpeaker   bool // omit spans with the class "speaker"
+
+	transcluding map[api.ZettelID]bool // embedded text zettel that are currently replaced
+}
+
+// ImageURLFunc returns the URL of an image of the slide set that is not
+// embedded.
+type ImageURLFunc func(zid api.ZettelID, syntax string) string
+
+// SetHideSolutions specifies whether regions that contain the solution of an
+// exercise are omitted, e.g. for a handout for students.
+func (v *Generator) SetHideSolutions(hide bool) { v.hideSolutions = hide }
+
+// SetHideSpeaker specifies whether spans with text for the speaker are
+// omitted, e.g. for a handout. Otherwise they are written with the class
+// "speaker".
+func (v *Generator) SetHideSpeaker(hide bool) { v.hideSpeaker = hide }
+
+// SetImageURL changes the URLs of images that are not embedded. By default,
+// images are retrieved from presenter.
+func (v *Generator) SetImageURL(f ImageURLFunc) { v.imageURL = f }
+
+// imageSource returns the URL of the image with the given zettel identifier.
+func (v *Generator) imageSource(zid api.ZettelID, suffix string) string {
+	if v.imageURL != nil && v.s != nil {
+		if img, found := v.s.GetImage(zid); found {
+			return v.imageURL(zid, img.Syntax)
+		}
+	}
+	return string(zid) + "." + suffix
+}
+
+// Special values for the maximum size of embedded images.
+const (
+	EmbedNone = 0  // No image is embedded
+	EmbedAll  = -1 // All images are embedded, regardless of their size
+)
+
+// ImageEmbedding specifies which images are embedded into the generated HTML
+// code, e.g. as a data URI. All other images are referenced by an URL.
+type ImageEmbedding struct {
+	MaxSize int            // Maximum size of an embedded image in bytes
+	Syntax  map[string]int // Maximum size for a specific image syntax
+}
+
+// Allows returns true, if an image of the given syntax and size in bytes
+// should be embedded.
+func (ie ImageEmbedding) Allows(syntax string, size int) bool {
+	maxSize, found := ie.Syntax[syntax]
+	if !found {
+		maxSize = ie.MaxSize
+	}
+	if maxSize < 0 {
+		return true
+	}
+	return maxSize > 0 && size <= maxSize
+}
+
+// Policies for links to zettel that are not part of the slide set.
+const (
+	LinksAnchor      = "anchor"      // Only links to slides of the slide set
+	LinksPresenter   = "presenter"   // Links to the zettel view of presenter
+	LinksZettelstore = "zettelstore" // Links to the web user interface of Zettelstore
+	LinksNone        = "none"        // No links to zettel at all
+)
+
+// ZettelLinks specifies how links to other zettel are generated.
+type ZettelLinks struct {
+	Policy string
+	Base   string // Base URL of Zettelstore, for policy LinksZettelstore
+}
+
+func (v *Generator) Write(b []byte) (int, error)       { return v.env.Write(b) }
+func (v *Generator) WriteString(s string) (int, error) { return v.env.WriteString(s) }
+
+// LogUnknown writes a summary of all node types that were not supported, e.g.
+// because they were introduced by a newer version of Zettelstore.
+func (v *Generator) LogUnknown(zid api.ZettelID) {
+	if len(v.unknown) == 0 {
+		return
+	}
+	syms := make([]string, 0, len(v.unknown))
+	for sym, count := range v.unknown {
+		syms = append(syms, fmt.Sprintf("%s(%d)", sym, count))
+	}
+	sort.Strings(syms)
+	log.Println("UNSP", zid, strings.Join(syms, " "))
+}
+
+// Syntaxes returns the syntaxes of all rendered verbatim-eval nodes.
+func (v *Generator) Syntaxes() map[string]bool { return v.syntaxes }
+
+func (v *Generator) makeEvaluateBlock(oldForm sxpf.Form) sxpf.Form {
+	return sxpf.NewBuiltin(
+		"block", true, 2, -1,
+		func(env sxpf.Environment, args *sxpf.Pair, _ int) (sxpf.Value, error) {
+			a := sexpr.GetAttributes(v.env.GetPair(args))
+			if v.hideSolutions && isSolution(a) {
+				return nil, nil
+			}
+			if val, found := a.Get(""); found {
+				switch val {
+				case "show":
+					if v.role != deck.SlideRoleShow {
+						return nil, nil
+					}
+					v.WriteString("<aside class=\"notes\">")
+					v.EvaluateBlock(v.env.GetPair(args.GetTail()))
+					v.WriteString("</aside>")
+					return nil, nil
+				case "handout":
+					if v.role != deck.SlideRoleHandout {
+						return nil, nil
+					}
+					v.WriteString("<aside class=\"handout\">")
+					v.EvaluateBlock(v.env.GetPair(args.GetTail()))
+					v.WriteString("</aside>")
+					return nil, nil
+				case "both":
+					switch v.role {
+					case deck.SlideRoleShow:
+						v.WriteString("<aside class=\"notes\">")
+					case deck.SlideRoleHandout:
+						v.WriteString("<aside class=\"handout\">")
+					default:
+						return nil, nil
+					}
+					v.EvaluateBlock(v.env.GetPair(args.GetTail()))
+					v.WriteString("</aside>")
+					return nil, nil
+				}
+			}
+			if hasPassThrough(a) {
+				v.evaluateRegionPassThrough(a, args)
+				return nil, nil
+			}
+			return oldForm.Call(env, args)
+		})
+}
+
+// EvaluateNotes writes the content of all top-level regions that contain
+// notes for the speaker, i.e. regions for the slide show. The result is true,
+// if there was at least one such region.
+func (v *Generator) EvaluateNotes(bn *sxpf.Pair) bool {
+	notes := Regions(bn, "show", "both")
+	for _, region := range notes {
+		v.EvaluateBlock(region.Content)
+	}
+	return len(notes) > 0
+}
+
+func (v *Generator) makeEvaluateVerbatimEval(oldForm sxpf.Form) sxpf.Form {
+	return sxpf.NewBuiltin(
+		"verb-eval", true, 1, -1,
+		func(env sxpf.Environment, args *sxpf.Pair, _ int) (sxpf.Value, error) {
+			if syntax := deck.VerbatimSyntax(args); syntax != "" {
+				if v.verbatim.Render(v, syntax, v.env.GetString(args.GetTail())) {
+					v.syntaxes[syntax] = true
+					return nil, nil
+				}
+			}
+			return oldForm.Call(env, args)
+		})
+}
+
+func (v *Generator) generateLinkZettel(senv sxpf.Environment, args *sxpf.Pair, _ int) (sxpf.Value, error) {
+	env := senv.(*html.EncEnvironment)
+	if a, refValue, ok := html.PrepareLink(env, args); ok {
+		zid, _, _ := strings.Cut(refValue, "#")
+		policy := v.links.Policy
+		if policy == LinksNone {
+			html.WriteLink(env, args, a, refValue, "")
+			return nil, nil
+		}
+		// TODO: check for fragment
+		if si := v.curSlide.FindSlide(api.ZettelID(zid)); si != nil {
+			// TODO: process and add fragment
+			a = a.Set("href", fmt.Sprintf("#(%d)", si.Number))
+			html.WriteLink(env, args, a, refValue, "")
+			return nil, nil
+		}
+		switch policy {
+		case LinksPresenter:
+			a = a.Set("href", zid)
+			html.WriteLink(env, args, a, refValue, "&#10547;")
+		case LinksZettelstore:
+			a = a.Set("href", v.links.Base+"h/"+zid).Set("target", "_blank")
+			html.WriteLink(env, args, a, refValue, "&#10547;")
+		default:
+			html.WriteLink(env, args, a, refValue, "")
+		}
+	}
+	return nil, nil
+}
+
+func (v *Generator) generateLinkExternal(senv sxpf.Environment, args *sxpf.Pair, _ int) (sxpf.Value, error) {
+	env := senv.(*html.EncEnvironment)
+	if a, refValue, ok := html.PrepareLink(env, args); ok {
+		a = a.Set("href", refValue).
+			AddClass("external").
+			Set("target", "_blank").
+			Set("rel", "noopener noreferrer")
+		html.WriteLink(env, args, a, refValue, "&#10138;")
+	}
+	return nil, nil
+}
+
+// withImageTitle returns the arguments of an embed node. If the node has no
+// description, the title of the image zettel is used as the description, so
+// that the image gets an alternative text.
+func (v *Generator) withImageTitle(args *sxpf.Pair, zid api.ZettelID) *sxpf.Pair {
+	argRef := args.GetTail()
+	argSyntax := argRef.GetTail()
+	if v.s == nil || !argSyntax.GetTail().IsEmpty() {
+		return args
+	}
+	img, found := v.s.GetImage(zid)
+	if !found || img.Title == "" {
+		return args
+	}
+	text := sxpf.NewPair(sexpr.SymText, sxpf.NewPair(sxpf.NewString(img.Title), nil))
+	return sxpf.NewPair(args.GetFirst(), sxpf.NewPair(argRef.GetFirst(),
+		sxpf.NewPair(argSyntax.GetFirst(), sxpf.NewPair(text, nil))))
+}
+
+func (v *Generator) visitEmbedSVG(src string) {
+	zid := api.ZettelID(src)
+	if v.s != nil && zid.IsValid() && v.s.HasImage(zid) {
+		if svg, found := v.s.GetImage(zid); found && svg.Syntax == api.ValueSyntaxSVG && v.embed.Allows(svg.Syntax, len(svg.Data)) {
+			v.Write(svg.Data)
+			return
+		}
+	}
+	fmt.Fprintf(v, "<figure><embed type=\"image/svg+xml\" src=\"%s\" /></figure>\n", v.imageSource(zid, "svg"))
+}
+func (v *Generator) generateEmbed(senv sxpf.Environment, args *sxpf.Pair, arity int) (sxpf.Value, error) {
+	env := senv.(*html.EncEnvironment)
+	ref := env.GetPair(args.GetTail())
+	src := env.GetString(ref.GetTail())
+	if syntax := env.GetString(args.GetTail().GetTail()); syntax == api.ValueSyntaxSVG {
+		// TODO
+		v.visitEmbedSVG(src)
+		return nil, nil
+	}
+	zid := api.ZettelID(src)
+	if v.s != nil && zid.IsValid() {
+		if video, found := v.s.GetVideo(zid); found {
+			v.writeVideo(args, zid, video)
+			return nil, nil
+		}
+	}
+	args = v.withImageTitle(args, zid)
+	if v.s != nil && zid.IsValid() && v.s.HasImage(zid) {
+		if img, found := v.s.GetImage(zid); found && v.embed.Allows(img.Syntax, len(img.Data)) {
+			var buf bytes.Buffer
+			buf.WriteString("data:image/")
+			buf.WriteString(img.Syntax)
+			buf.WriteString(";base64,")
+			enc := base64.NewEncoder(base64.StdEncoding, &buf)
+			enc.Write(img.Data)
+			enc.Close()
+			env.WriteImageWithSource(args, buf.String())
+			return nil, nil
+		}
+	}
+	if zid.IsValid() {
+		src = v.imageSource(zid, "content")
+	}
+	env.WriteImageWithSource(args, src)
+	return nil, nil
+}
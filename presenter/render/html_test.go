@@ -0,0 +1,93 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"codeberg.org/t73fde/sxpf"
+	"zettelstore.de/c/sexpr"
+	"zettelstore.de/contrib/presenter/deck"
+)
+
+// generate returns the HTML code of the given block nodes, as produced by a
+// generator for the given role, after it was changed by the setup function.
+func generate(t *testing.T, role, src string, setup func(*Generator)) string {
+	t.Helper()
+	val, err := sxpf.ParseString(sexpr.Smk, src)
+	if err != nil {
+		t.Fatalf("unable to parse %q: %v", src, err)
+	}
+	content, ok := val.(*sxpf.Pair)
+	if !ok {
+		t.Fatalf("%q is not a list", src)
+	}
+	var buf bytes.Buffer
+	v := New(&buf, nil, role, nil, 0, ImageEmbedding{}, ZettelLinks{})
+	if setup != nil {
+		setup(v)
+	}
+	v.EvaluateBlock(content)
+	return buf.String()
+}
+
+func TestGenerator(t *testing.T) {
+	const (
+		notes    = `((REGION-BLOCK (("" . "show")) ((PARA (TEXT "note"))) ()))`
+		handout  = `((REGION-BLOCK (("" . "handout")) ((PARA (TEXT "note"))) ()))`
+		both     = `((REGION-BLOCK (("" . "both")) ((PARA (TEXT "note"))) ()))`
+		solution = `((REGION-BLOCK (("class" . "exercise solution")) ((PARA (TEXT "42"))) ()))`
+		speaker  = `((PARA (TEXT "Say") (SPACE) (FORMAT-SPAN (("" . "speaker")) (TEXT "slowly"))))`
+		passThru = `((PARA (FORMAT-SPAN (("id" . "x")) (TEXT "text"))))`
+		emoji    = `((PARA (TEXT "Done :rocket:")))`
+	)
+	hideSolutions := func(v *Generator) { v.SetHideSolutions(true) }
+	hideSpeaker := func(v *Generator) { v.SetHideSpeaker(true) }
+	emojis := func(v *Generator) { v.SetEmojis(NewEmojiMap()) }
+	testcases := []struct {
+		name     string
+		role     string
+		src      string
+		setup    func(*Generator)
+		contains []string
+		missing  []string
+	}{
+		{"notes in show", deck.SlideRoleShow, notes, nil, []string{`<aside class="notes">`, "note", "</aside>"}, nil},
+		{"notes in handout", deck.SlideRoleHandout, notes, nil, nil, []string{"note"}},
+		{"handout in show", deck.SlideRoleShow, handout, nil, nil, []string{"note"}},
+		{"handout in handout", deck.SlideRoleHandout, handout, nil, []string{`<aside class="handout">`, "note"}, nil},
+		{"both in show", deck.SlideRoleShow, both, nil, []string{`<aside class="notes">`, "note"}, nil},
+		{"both in handout", deck.SlideRoleHandout, both, nil, []string{`<aside class="handout">`, "note"}, nil},
+		{"both elsewhere", "zettel", both, nil, nil, []string{"note"}},
+		{"solution shown", deck.SlideRoleHandout, solution, nil, []string{"42"}, nil},
+		{"solution hidden", deck.SlideRoleHandout, solution, hideSolutions, nil, []string{"42"}},
+		{"speaker shown", deck.SlideRoleShow, speaker, nil, []string{"Say", `<span class="speaker">slowly</span>`}, nil},
+		{"speaker hidden", deck.SlideRoleHandout, speaker, hideSpeaker, []string{"Say"}, []string{"slowly", "speaker"}},
+		{"pass through", deck.SlideRoleShow, passThru, nil, []string{`<span id="x">text</span>`}, nil},
+		{"emoji", deck.SlideRoleShow, emoji, emojis, []string{"Done 🚀"}, []string{":rocket:"}},
+		{"no emoji", deck.SlideRoleShow, emoji, nil, []string{"Done :rocket:"}, nil},
+	}
+	for _, tc := range testcases {
+		got := generate(t, tc.role, tc.src, tc.setup)
+		for _, exp := range tc.contains {
+			if !strings.Contains(got, exp) {
+				t.Errorf("%s: %q not found in %q", tc.name, exp, got)
+			}
+		}
+		for _, exp := range tc.missing {
+			if strings.Contains(got, exp) {
+				t.Errorf("%s: %q found in %q", tc.name, exp, got)
+			}
+		}
+	}
+}
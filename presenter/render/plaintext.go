@@ -0,0 +1,85 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"strings"
+
+	"codeberg.org/t73fde/sxpf"
+	"zettelstore.de/c/sexpr"
+)
+
+// NotesText returns the notes for the speaker as plain text, one string for
+// every paragraph.
+func NotesText(bn *sxpf.Pair) []string {
+	var tc textCollector
+	for _, notes := range Regions(bn, "show", "both") {
+		tc.collect(notes.Content)
+		tc.endParagraph()
+	}
+	return tc.paras
+}
+
+// WordCount returns the number of words of the given block nodes. Like the
+// notes for the speaker, code and other content that is not read is ignored.
+func WordCount(bn *sxpf.Pair) int {
+	var tc textCollector
+	tc.collect(bn)
+	tc.endParagraph()
+	count := 0
+	for _, para := range tc.paras {
+		count += len(strings.Fields(para))
+	}
+	return count
+}
+
+// textCollector collects the plain text of zettel content. Code, comments,
+// and other content that cannot be read aloud is ignored.
+type textCollector struct {
+	sb    strings.Builder
+	paras []string
+}
+
+func (tc *textCollector) endParagraph() {
+	if s := strings.Join(strings.Fields(tc.sb.String()), " "); s != "" {
+		tc.paras = append(tc.paras, s)
+	}
+	tc.sb.Reset()
+}
+
+func (tc *textCollector) collect(val sxpf.Value) {
+	p, ok := val.(*sxpf.Pair)
+	if !ok || p.IsNil() {
+		return
+	}
+	switch p.GetFirst() {
+	case sexpr.SymText:
+		if s, err := p.GetTail().GetString(); err == nil {
+			tc.sb.WriteString(s)
+		}
+		return
+	case sexpr.SymSpace, sexpr.SymSoft, sexpr.SymHard:
+		tc.sb.WriteByte(' ')
+		return
+	case sexpr.SymVerbatimCode, sexpr.SymVerbatimComment, sexpr.SymVerbatimEval,
+		sexpr.SymVerbatimHTML, sexpr.SymVerbatimMath, sexpr.SymVerbatimZettel,
+		sexpr.SymLiteralComment, sexpr.SymEmbed, sexpr.SymFootnote, sexpr.SymBLOB:
+		return
+	case sexpr.SymPara, sexpr.SymHeading, sexpr.SymListOrdered, sexpr.SymListUnordered,
+		sexpr.SymListQuote, sexpr.SymRegionBlock, sexpr.SymRegionQuote,
+		sexpr.SymRegionVerse, sexpr.SymTable:
+		tc.endParagraph()
+		defer tc.endParagraph()
+	}
+	for elem := p; !elem.IsNil(); elem = elem.GetTail() {
+		tc.collect(elem.GetFirst())
+	}
+}
@@ -0,0 +1,52 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"codeberg.org/t73fde/sxpf"
+	"zettelstore.de/c/sexpr"
+)
+
+// Region is a top-level region of zettel content.
+type Region struct {
+	Kind    string     // Value of the generic attribute, e.g. "show"
+	Content *sxpf.Pair // Block nodes
+}
+
+// Regions returns all top-level regions, whose generic attribute has one of
+// the given values, in the order of the zettel content.
+func Regions(bn *sxpf.Pair, kinds ...string) []Region {
+	var result []Region
+	for elem := bn; !elem.IsNil(); elem = elem.GetTail() {
+		node, err := elem.GetPair()
+		if err != nil || node.GetFirst() != sexpr.SymRegionBlock {
+			continue
+		}
+		args := node.GetTail()
+		attrs, err := args.GetPair()
+		if err != nil {
+			continue
+		}
+		val, found := sexpr.GetAttributes(attrs).Get("")
+		if !found {
+			continue
+		}
+		for _, kind := range kinds {
+			if val == kind {
+				if content, err := args.GetTail().GetPair(); err == nil {
+					result = append(result, Region{Kind: val, Content: content})
+				}
+				break
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,168 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"strings"
+
+	"codeberg.org/t73fde/sxpf"
+	"zettelstore.de/c/api"
+	"zettelstore.de/c/sexpr"
+	"zettelstore.de/contrib/presenter/deck"
+)
+
+// transclude returns the given nodes, where embedded text zettel are replaced
+// by their content. A paragraph that consists only of such an embed is
+// replaced by all blocks of the zettel. Within other text, the embed is
+// replaced by the text of the zettel, if it consists of one paragraph.
+// Otherwise, or if the zettel is nested too deep, a link to the zettel is
+// produced. The given list is not changed; if nothing is embedded, it is
+// returned unchanged.
+func (v *Generator) transclude(nodes *sxpf.Pair, depth int) *sxpf.Pair {
+	var elems []sxpf.Value
+	changed := false
+	for elem := nodes; !elem.IsNil(); elem = elem.GetTail() {
+		val := elem.GetFirst()
+		if node, isPair := val.(*sxpf.Pair); isPair && !node.IsNil() {
+			if blocks, found := v.embeddedBlocks(node, depth); found {
+				for b := blocks; !b.IsNil(); b = b.GetTail() {
+					elems = append(elems, b.GetFirst())
+				}
+				changed = true
+				if elem.GetTail() == nil {
+					break
+				}
+				continue
+			}
+			if newNode := v.transcludeNode(node, depth); newNode != node {
+				val = newNode
+				changed = true
+			}
+		}
+		elems = append(elems, val)
+		if elem.GetTail() == nil {
+			break
+		}
+	}
+	if !changed {
+		return nodes
+	}
+	return sxpf.NewPairFromSlice(elems)
+}
+
+// transcludeNode replaces the embedded text zettel of a single node.
+func (v *Generator) transcludeNode(node *sxpf.Pair, depth int) *sxpf.Pair {
+	sym, isSym := node.GetFirst().(*sxpf.Symbol)
+	if !isSym {
+		return v.transclude(node, depth) // e.g. a list item, or a table row
+	}
+	name := sym.GetValue()
+	if strings.HasPrefix(name, "VERBATIM-") || strings.HasPrefix(name, "LITERAL-") {
+		return node
+	}
+	args := node.GetTail()
+	if sym == sexpr.SymEmbed {
+		zid, isText := embeddedText(args)
+		if !isText {
+			return node
+		}
+		if content := v.transclusion(zid, depth); content.GetTail().IsNil() && isNode(content.GetFirst(), sexpr.SymPara) {
+			inlines := v.transcludeContent(zid, content.GetFirst().(*sxpf.Pair).GetTail(), depth)
+			return sxpf.NewPair(sexpr.SymFormatSpan, sxpf.NewPair(sxpf.Nil(), inlines))
+		}
+		// (EMBED attrs ref syntax inl...) -> (LINK-ZETTEL attrs zid inl...)
+		text := args.GetTail().GetTail().GetTail()
+		if text.IsNil() {
+			text = sxpf.NewPair(makeText(string(zid)), nil)
+		}
+		return sxpf.NewPair(sexpr.SymLinkZettel, sxpf.NewPair(args.GetFirst(), sxpf.NewPair(sxpf.NewString(string(zid)), text)))
+	}
+	if newArgs := v.transclude(args, depth); newArgs != args {
+		return sxpf.NewPair(sym, newArgs)
+	}
+	return node
+}
+
+// embeddedBlocks returns the blocks of the embedded text zettel, if the given
+// node is a paragraph that only embeds this zettel.
+func (v *Generator) embeddedBlocks(node *sxpf.Pair, depth int) (*sxpf.Pair, bool) {
+	if node.GetFirst() != sexpr.SymPara {
+		return nil, false
+	}
+	var embed *sxpf.Pair
+	for elem := node.GetTail(); !elem.IsNil(); elem = elem.GetTail() {
+		val := elem.GetFirst()
+		switch {
+		case isNode(val, sexpr.SymSpace), isNode(val, sexpr.SymSoft):
+		case isNode(val, sexpr.SymEmbed) && embed == nil:
+			embed = val.(*sxpf.Pair)
+		default:
+			return nil, false
+		}
+		if elem.GetTail() == nil {
+			break
+		}
+	}
+	if embed == nil {
+		return nil, false
+	}
+	zid, isText := embeddedText(embed.GetTail())
+	if !isText {
+		return nil, false
+	}
+	content := v.transclusion(zid, depth)
+	if content.IsNil() {
+		return nil, false
+	}
+	return v.transcludeContent(zid, content, depth), true
+}
+
+// transcludeContent replaces the embedded text zettel within the content of
+// the given embedded zettel. While doing so, the zettel cannot be embedded
+// again.
+func (v *Generator) transcludeContent(zid api.ZettelID, content *sxpf.Pair, depth int) *sxpf.Pair {
+	if v.transcluding == nil {
+		v.transcluding = make(map[api.ZettelID]bool)
+	}
+	v.transcluding[zid] = true
+	result := v.transclude(content, depth+1)
+	delete(v.transcluding, zid)
+	return result
+}
+
+// transclusion returns the content of the embedded text zettel, if it may be
+// embedded at the given depth, and if it does not embed itself.
+func (v *Generator) transclusion(zid api.ZettelID, depth int) *sxpf.Pair {
+	if v.s == nil || depth >= deck.MaxTransclusionDepth || v.transcluding[zid] {
+		return nil
+	}
+	content, _ := v.s.Transclusion(zid)
+	return content
+}
+
+// embeddedText returns the zettel identifier of the arguments of an embed
+// node, if a text zettel is embedded.
+func embeddedText(args *sxpf.Pair) (api.ZettelID, bool) {
+	ref, isPair := args.GetTail().GetFirst().(*sxpf.Pair)
+	if !isPair || ref.IsNil() || ref.GetFirst() != sexpr.SymRefStateZettel {
+		return api.InvalidZID, false
+	}
+	zidVal, err := ref.GetTail().GetString()
+	if err != nil {
+		return api.InvalidZID, false
+	}
+	syntax, err := args.GetTail().GetTail().GetString()
+	if err != nil || !deck.IsTextSyntax(syntax) {
+		return api.InvalidZID, false
+	}
+	zid := api.ZettelID(zidVal)
+	return zid, zid.IsValid()
+}
@@ -0,0 +1,223 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"codeberg.org/t73fde/sxpf"
+	"zettelstore.de/c/sexpr"
+)
+
+// Special spaces that must not be used to break a line.
+const (
+	nbsp       = " " // no-break space
+	narrowNbsp = " " // narrow no-break space
+)
+
+// quoteMarks contains the primary and secondary quotation marks of a language.
+type quoteMarks struct {
+	open, close       string
+	openSec, closeSec string
+}
+
+var langQuotes = map[string]quoteMarks{
+	"da": {"»", "«", "›", "‹"},
+	"de": {"„", "“", "‚", "‘"},
+	"en": {"“", "”", "‘", "’"},
+	"es": {"«", "»", "“", "”"},
+	"fr": {"«" + narrowNbsp, narrowNbsp + "»", "‹" + narrowNbsp, narrowNbsp + "›"},
+	"it": {"«", "»", "“", "”"},
+	"nl": {"“", "”", "‘", "’"},
+	"pl": {"„", "”", "«", "»"},
+	"ru": {"«", "»", "„", "“"},
+	"sv": {"”", "”", "’", "’"},
+}
+
+// germanAbbrevs are abbreviations, whose parts should not be separated.
+var germanAbbrevs = map[string]bool{
+	"d.": true, "z.": true, "u.": true, "o.": true, "v.": true, "s.": true, "S.": true, "Nr.": true,
+}
+
+// typography contains the rules of a language.
+type typography struct {
+	quotes  quoteMarks
+	hasQuot bool
+	lang    string
+}
+
+func newTypography(lang string) typography {
+	lang = strings.ToLower(lang)
+	if pos := strings.IndexAny(lang, "-_"); pos > 0 {
+		lang = lang[:pos]
+	}
+	q, found := langQuotes[lang]
+	return typography{quotes: q, hasQuot: found, lang: lang}
+}
+
+// ApplyTypography returns the given nodes with language-specific typography:
+// quotations get the quotation marks of the language, and some spaces are
+// replaced by no-break spaces, e.g. between a number and its unit, or before
+// the punctuation of French text. The given list is not changed; if no rule
+// applies, it is returned unchanged.
+func ApplyTypography(nodes *sxpf.Pair, lang string) *sxpf.Pair {
+	if lang == "" || nodes.IsNil() {
+		return nodes
+	}
+	return newTypography(lang).list(nodes, 0)
+}
+
+// list applies the rules to all elements of a list.
+func (t typography) list(lst *sxpf.Pair, depth int) *sxpf.Pair {
+	var elems []sxpf.Value
+	changed := false
+	for elem := lst; !elem.IsNil(); elem = elem.GetTail() {
+		val := elem.GetFirst()
+		if node, isPair := val.(*sxpf.Pair); isPair && !node.IsNil() {
+			if newNode := t.node(node, depth); newNode != node {
+				val = newNode
+				changed = true
+			}
+		}
+		elems = append(elems, val)
+		if elem.GetTail() == nil {
+			break
+		}
+	}
+	if spaced := t.spaces(elems); spaced != nil {
+		elems = spaced
+		changed = true
+	}
+	if !changed {
+		return lst
+	}
+	return sxpf.NewPairFromSlice(elems)
+}
+
+// node applies the rules to a single node.
+func (t typography) node(node *sxpf.Pair, depth int) *sxpf.Pair {
+	sym, isSym := node.GetFirst().(*sxpf.Symbol)
+	if !isSym {
+		return t.list(node, depth) // e.g. a list item, or a table row
+	}
+	name := sym.GetValue()
+	if strings.HasPrefix(name, "VERBATIM-") || strings.HasPrefix(name, "LITERAL-") {
+		return node
+	}
+	args := node.GetTail()
+	if sym == sexpr.SymFormatQuote && t.hasQuot {
+		open, close := t.quotes.open, t.quotes.close
+		if depth%2 == 1 {
+			open, close = t.quotes.openSec, t.quotes.closeSec
+		}
+		// (FORMAT-QUOTE attrs inl...) -> (FORMAT-SPAN attrs (TEXT open) inl... (TEXT close))
+		elems := []sxpf.Value{sexpr.SymFormatSpan, args.GetFirst(), makeText(open)}
+		for elem := t.list(args.GetTail(), depth+1); !elem.IsNil(); elem = elem.GetTail() {
+			elems = append(elems, elem.GetFirst())
+		}
+		elems = append(elems, makeText(close))
+		return sxpf.NewPairFromSlice(elems)
+	}
+	if newArgs := t.list(args, depth); newArgs != args {
+		return sxpf.NewPair(sym, newArgs)
+	}
+	return node
+}
+
+// spaces returns the given elements with some SPACE nodes replaced by a
+// no-break space. If no space is replaced, nil is returned.
+func (t typography) spaces(elems []sxpf.Value) []sxpf.Value {
+	var result []sxpf.Value
+	for i, val := range elems {
+		if i == 0 || i == len(elems)-1 || !isNode(val, sexpr.SymSpace) {
+			continue
+		}
+		prev, prevOK := nodeText(elems[i-1])
+		next, nextOK := nodeText(elems[i+1])
+		if !prevOK || !nextOK {
+			continue
+		}
+		if space := t.space(prev, next); space != "" {
+			if result == nil {
+				result = append([]sxpf.Value(nil), elems...)
+			}
+			result[i] = makeText(space)
+		}
+	}
+	return result
+}
+
+// space returns the no-break space that should be placed between the given
+// texts, or the empty string, if a line break is allowed.
+func (t typography) space(prev, next string) string {
+	last, _ := utf8.DecodeLastRuneInString(prev)
+	first, _ := utf8.DecodeRuneInString(next)
+	if t.lang == "fr" && (strings.ContainsRune(";:!?»›", first) || strings.ContainsRune("«‹", last)) {
+		return narrowNbsp
+	}
+	if isNumber(prev) && (isUnit(next) || next == "%") {
+		return narrowNbsp
+	}
+	if t.lang == "de" && germanAbbrevs[prev] && (unicode.IsDigit(first) || strings.HasSuffix(next, ".")) {
+		return narrowNbsp
+	}
+	if last == '§' || last == '¶' {
+		return nbsp
+	}
+	return ""
+}
+
+func isNumber(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsDigit(r) && r != '.' && r != ',' {
+			return false
+		}
+	}
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return unicode.IsDigit(r)
+}
+
+// units contains some units that follow a number.
+var units = map[string]bool{
+	"mm": true, "cm": true, "m": true, "km": true, "g": true, "kg": true, "t": true,
+	"s": true, "ms": true, "min": true, "h": true, "l": true, "ml": true,
+	"B": true, "kB": true, "KB": true, "MB": true, "GB": true, "TB": true, "KiB": true, "MiB": true, "GiB": true,
+	"Hz": true, "kHz": true, "MHz": true, "GHz": true, "W": true, "kW": true, "V": true, "A": true,
+	"°C": true, "°F": true, "K": true, "€": true, "$": true, "£": true, "CHF": true, "EUR": true, "USD": true,
+	"px": true, "pt": true, "em": true, "rem": true,
+}
+
+func isUnit(s string) bool {
+	return units[strings.TrimRightFunc(s, unicode.IsPunct)]
+}
+
+func isNode(val sxpf.Value, sym *sxpf.Symbol) bool {
+	node, isPair := val.(*sxpf.Pair)
+	return isPair && !node.IsNil() && node.GetFirst() == sym
+}
+
+// nodeText returns the string of a TEXT node.
+func nodeText(val sxpf.Value) (string, bool) {
+	if !isNode(val, sexpr.SymText) {
+		return "", false
+	}
+	s, err := val.(*sxpf.Pair).GetTail().GetString()
+	return s, err == nil
+}
+
+func makeText(s string) *sxpf.Pair {
+	return sxpf.NewPair(sexpr.SymText, sxpf.NewPair(sxpf.NewString(s), nil))
+}
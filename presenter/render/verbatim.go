@@ -0,0 +1,71 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"html"
+	"log"
+	"sort"
+	"strings"
+)
+
+// VerbatimSyntax specifies how verbatim content of a syntax is presented.
+type VerbatimSyntax struct {
+	// Transform produces the HTML code for the verbatim content on the
+	// server. If it is nil, the content is wrapped into a div element with
+	// the syntax name as class, to be transformed by the script.
+	Transform func(content string) (string, error)
+
+	// Script is written once at the end of every document that contains
+	// verbatim content of the syntax.
+	Script string
+}
+
+// VerbatimRegistry maps the name of a verbatim syntax to its specification.
+type VerbatimRegistry map[string]*VerbatimSyntax
+
+// Render writes the HTML code for the verbatim content of the given syntax.
+// It returns false, if the syntax is not registered.
+func (vr VerbatimRegistry) Render(g *Generator, syntax, content string) bool {
+	vs, found := vr[syntax]
+	if !found {
+		return false
+	}
+	if vs.Transform != nil {
+		result, err := vs.Transform(content)
+		if err != nil {
+			log.Println("VTRF", syntax, err)
+			return false
+		}
+		g.WriteString(result)
+		return true
+	}
+	g.WriteString("<div class=\"" + html.EscapeString(syntax) + "\">\n")
+	g.WriteString(content)
+	g.WriteString("</div>")
+	return true
+}
+
+// Scripts returns the scripts of all given syntaxes, in a stable order.
+func (vr VerbatimRegistry) Scripts(syntaxes map[string]bool) string {
+	names := make([]string, 0, len(syntaxes))
+	for syntax := range syntaxes {
+		names = append(names, syntax)
+	}
+	sort.Strings(names)
+	var sb strings.Builder
+	for _, syntax := range names {
+		if vs, found := vr[syntax]; found {
+			sb.WriteString(vs.Script)
+		}
+	}
+	return sb.String()
+}
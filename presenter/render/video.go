@@ -0,0 +1,60 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"fmt"
+	"html"
+
+	"codeberg.org/t73fde/sxpf"
+	"zettelstore.de/c/api"
+	"zettelstore.de/c/text"
+	"zettelstore.de/contrib/presenter/deck"
+)
+
+// writeVideo writes an embedded video zettel, together with its caption
+// tracks. The description of the embed node is used as the label of the
+// video, and as a fallback text for browsers that cannot play the video.
+func (v *Generator) writeVideo(args *sxpf.Pair, zid api.ZettelID, video deck.Video) {
+	desc := text.EvaluateInlineString(args.GetTail().GetTail().GetTail())
+	src := string(zid) + ".content"
+	v.WriteString("<video controls preload=\"metadata\"")
+	if desc != "" {
+		fmt.Fprintf(v, " aria-label=\"%s\"", html.EscapeString(desc))
+	}
+	v.WriteString(">\n")
+	fmt.Fprintf(v, "<source src=\"%s\" type=\"video/%s\">\n", src, videoType(video.Syntax))
+	for i, track := range video.Tracks {
+		fmt.Fprintf(v, "<track kind=\"captions\" src=\"%s.%s\"", track.Zid, deck.SyntaxWebVTT)
+		if track.Lang != "" {
+			fmt.Fprintf(v, " srclang=\"%s\"", html.EscapeString(track.Lang))
+		}
+		if track.Label != "" {
+			fmt.Fprintf(v, " label=\"%s\"", html.EscapeString(track.Label))
+		}
+		if i == 0 {
+			v.WriteString(" default")
+		}
+		v.WriteString(">\n")
+	}
+	if desc == "" {
+		desc = string(zid)
+	}
+	fmt.Fprintf(v, "<a href=\"%s\">%s</a>\n</video>", src, html.EscapeString(desc))
+}
+
+// videoType returns the subtype of the MIME type of a video syntax.
+func videoType(syntax string) string {
+	if syntax == "ogv" {
+		return "ogg"
+	}
+	return syntax
+}
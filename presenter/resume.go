@@ -0,0 +1,167 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"zettelstore.de/c/api"
+)
+
+// suffixResume is the URL suffix to store and retrieve the last slide shown
+// by the speaker.
+const suffixResume = "resume"
+
+// The last shown slide is stored in the local storage of the browser. In
+// addition, the page of the speaker, i.e. with the query parameter "speaker",
+// sends the shown slide to zettel presenter, so that the slide show can be
+// resumed on another device, e.g. after a break. These positions are only
+// kept in memory.
+
+// resumePosition is the last slide of a slide show, shown by the speaker.
+type resumePosition struct {
+	Slide  string `json:"slide"`  // id of the section
+	Number int    `json:"number"` // number of the slide, as shown to the audience
+	Time   int64  `json:"time"`   // time of the last change, in milliseconds since epoch
+}
+
+// resumePositions stores the last shown slide of all slide shows.
+type resumePositions struct {
+	mx        sync.Mutex
+	positions map[api.ZettelID]resumePosition
+}
+
+func (rp *resumePositions) get(zid api.ZettelID) (resumePosition, bool) {
+	rp.mx.Lock()
+	defer rp.mx.Unlock()
+	pos, found := rp.positions[zid]
+	return pos, found
+}
+
+func (rp *resumePositions) set(zid api.ZettelID, pos resumePosition) {
+	rp.mx.Lock()
+	defer rp.mx.Unlock()
+	if rp.positions == nil {
+		rp.positions = make(map[api.ZettelID]resumePosition)
+	}
+	rp.positions[zid] = pos
+}
+
+// reResumeSlide matches the id of a section of a slide show.
+var reResumeSlide = regexp.MustCompile(`^\(\d+\)$`)
+
+// processResume returns the last shown slide of the slide show as JSON, or
+// stores it, if the speaker sends it.
+func processResume(w http.ResponseWriter, r *http.Request, cfg *slidesConfig, zid api.ZettelID) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		pos, found := cfg.resume.get(zid)
+		if !found {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(pos)
+	case http.MethodPost:
+		slide := r.PostFormValue("slide")
+		num, err := strconv.Atoi(r.PostFormValue("number"))
+		if !reResumeSlide.MatchString(slide) || err != nil || num < 1 {
+			http.Error(w, "Invalid slide", http.StatusBadRequest)
+			return
+		}
+		cfg.resume.set(zid, resumePosition{Slide: slide, Number: num, Time: time.Now().UnixMilli()})
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// writeResumePrompt writes a script that remembers the shown slide, and that
+// offers to resume at the last shown slide, if the slide show is opened again
+// without referencing a specific slide. The most recent position of the local
+// storage and of the speaker is used.
+func writeResumePrompt(w io.Writer, zid api.ZettelID, lang string) {
+	fmt.Fprintf(w, `<style type="text/css">
+div.zs-resume { position: fixed; z-index: 60; left: 50%%; bottom: 2rem; transform: translateX(-50%%); padding: .5rem 1rem; font-family: sans-serif; font-size: 1rem; color: #000; background: #fff; border: 2px solid #000; border-radius: .25rem }
+div.zs-resume button { margin-left: .5rem; font-size: 1rem }
+@media print { div.zs-resume { display: none } }
+</style>
+<script>
+(function() {
+var key = "zs-resume:" + window.location.pathname;
+var url = "%s.%s";
+var speaker = new URLSearchParams(window.location.search).has("speaker");
+function current() {
+var el = Reveal.getCurrentSlide();
+return {slide: el && el.id ? el.id : "", number: Reveal.getSlidePastCount() + 1, time: Date.now()};
+}
+function remember() {
+var pos = current();
+if (pos.slide === "") { return; }
+try { localStorage.setItem(key, JSON.stringify(pos)); } catch (e) {}
+if (speaker) {
+var body = new URLSearchParams();
+body.set("slide", pos.slide);
+body.set("number", String(pos.number));
+fetch(url, {method: "POST", body: body}).catch(function() {});
+}
+}
+function resume(pos) {
+var el = document.getElementById(pos.slide);
+if (el) {
+var i = Reveal.getIndices(el);
+Reveal.slide(i.h, i.v);
+} else {
+window.location.hash = "#/" + encodeURIComponent(pos.slide);
+}
+}
+function prompt(pos) {
+var box = document.createElement("div");
+box.className = "zs-resume";
+box.setAttribute("role", "dialog");
+box.appendChild(document.createTextNode(%q.replace("%%s", String(pos.number))));
+[[%q, true], [%q, false]].forEach(function(b) {
+var button = document.createElement("button");
+button.type = "button";
+button.textContent = b[0];
+button.addEventListener("click", function() {
+box.remove();
+if (b[1]) { resume(pos); }
+});
+box.appendChild(button);
+});
+document.body.appendChild(box);
+Reveal.on("slidechanged", function() { box.remove(); });
+}
+function start() {
+var hash = window.location.hash.replace(/^#\/?/, "");
+if (hash !== "") { return; }
+var local = null;
+try { local = JSON.parse(localStorage.getItem(key)); } catch (e) {}
+fetch(url).then(function(resp) { return resp.status === 200 ? resp.json() : null; }).catch(function() { return null; }).then(function(remote) {
+var pos = local;
+if (remote && (!pos || remote.time > pos.time)) { pos = remote; }
+if (pos && pos.slide && pos.number > 1 && Reveal.getSlidePastCount() === 0) { prompt(pos); }
+});
+}
+Reveal.on("slidechanged", remember);
+if (Reveal.isReady()) { start(); } else { Reveal.on("ready", start); }
+})();
+</script>
+`, zid, suffixResume, translate(lang, "Resume at slide %s?"), translate(lang, "Resume"), translate(lang, "Start from the beginning"))
+}
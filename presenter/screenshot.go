@@ -0,0 +1,285 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"zettelstore.de/c/api"
+)
+
+// maxParallelScreenshots limits the number of browser processes that run at
+// the same time.
+const maxParallelScreenshots = 2
+
+// Size of a screenshot, the same as the size of a slide of the slide show.
+const (
+	screenshotWidth  = 1920
+	screenshotHeight = 1024
+)
+
+// screenshotParam is the query parameter of a page that is retrieved by the
+// browser. Its value identifies the page, so that its status can be checked,
+// since the browser renders error pages too.
+const screenshotParam = "screenshot"
+
+// screenshotter renders pages of this application to PNG images or to PDF
+// documents with the help of a headless browser, e.g. Chromium.
+type screenshotter struct {
+	browser string // path of the browser executable
+	prefix  string // path prefix of a tenant
+	sem     chan struct{}
+	mx      sync.Mutex
+	pages   map[string]int // status of every page retrieved by the browser, zero if not yet retrieved
+}
+
+// pageStatusError is returned, if the page retrieved by the browser was not
+// found or could not be rendered.
+type pageStatusError struct {
+	status int // zero, if the page was not retrieved
+}
+
+func (err *pageStatusError) Error() string {
+	if err.status == 0 {
+		return "page was not retrieved"
+	}
+	return fmt.Sprintf("page responded with status %d", err.status)
+}
+
+// observe returns a response writer that records the status of the response,
+// if the request was sent by the browser. The query parameter is removed from
+// the returned request, so that the response is remembered as usual.
+func (s *screenshotter) observe(w http.ResponseWriter, r *http.Request) (http.ResponseWriter, *http.Request) {
+	query := r.URL.Query()
+	id := query.Get(screenshotParam)
+	if id == "" {
+		return w, r
+	}
+	s.mx.Lock()
+	_, found := s.pages[id]
+	s.mx.Unlock()
+	if !found {
+		return w, r
+	}
+	query.Del(screenshotParam)
+	u := *r.URL
+	u.RawQuery = query.Encode()
+	r2 := *r
+	r2.URL = &u
+	return &pageStatusWriter{ResponseWriter: w, record: func(status int) {
+		s.mx.Lock()
+		// Only the page itself counts, not other requests with the same
+		// query parameters, e.g. of lazily loaded slides.
+		if old, found := s.pages[id]; found && old == 0 {
+			s.pages[id] = status
+		}
+		s.mx.Unlock()
+	}}, &r2
+}
+
+// pageStatusWriter calls a function with the status of the response.
+type pageStatusWriter struct {
+	http.ResponseWriter
+	record  func(status int)
+	written bool
+}
+
+func (pw *pageStatusWriter) WriteHeader(status int) {
+	if !pw.written {
+		pw.written = true
+		pw.record(status)
+	}
+	pw.ResponseWriter.WriteHeader(status)
+}
+
+func (pw *pageStatusWriter) Write(data []byte) (int, error) {
+	if !pw.written {
+		pw.written = true
+		pw.record(http.StatusOK)
+	}
+	return pw.ResponseWriter.Write(data)
+}
+
+// newScreenshotter returns a new screenshotter, or nil if no browser is given.
+func newScreenshotter(browser, prefix string) *screenshotter {
+	if browser == "" {
+		return nil
+	}
+	return &screenshotter{
+		browser: browser,
+		prefix:  prefix,
+		sem:     make(chan struct{}, maxParallelScreenshots),
+	}
+}
+
+// requestOrigin returns the scheme and the address to retrieve pages of this
+// application, in the same way as the given request was received. The local
+// address of the connection is used, not the header "Host", which is given by
+// the client and might name any other host.
+func requestOrigin(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	host := r.Host
+	if addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok {
+		host = addr.String()
+	}
+	return scheme + "://" + host
+}
+
+// capture returns the PNG image of the given path, retrieved from the given
+// origin.
+func (s *screenshotter) capture(ctx context.Context, origin, path string) ([]byte, error) {
+	return s.run(ctx, origin, path, "screenshot.png", func(file string) []string {
+		return []string{
+			fmt.Sprintf("--window-size=%d,%d", screenshotWidth, screenshotHeight),
+			"--screenshot=" + file,
+		}
+	})
+}
+
+// printPDF returns the PDF document of the given path, retrieved from the
+// given origin, as printed by the browser.
+func (s *screenshotter) printPDF(ctx context.Context, origin, path string) ([]byte, error) {
+	return s.run(ctx, origin, path, "print.pdf", func(file string) []string {
+		return []string{
+			"--no-pdf-header-footer", "--print-to-pdf-no-header",
+			"--print-to-pdf=" + file,
+		}
+	})
+}
+
+// run starts the browser to retrieve the given path below the path prefix and
+// returns the content of the file that the browser has written. The function
+// outArgs returns the arguments to produce the file. If the page was not
+// retrieved successfully, a pageStatusError is returned.
+func (s *screenshotter) run(ctx context.Context, origin, path, name string, outArgs func(file string) []string) ([]byte, error) {
+	select {
+	case s.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-s.sem }()
+
+	dir, err := os.MkdirTemp("", "presenter-screenshot")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, name)
+	args := []string{
+		"--headless", "--disable-gpu", "--hide-scrollbars", "--no-first-run",
+		"--user-data-dir=" + filepath.Join(dir, "profile"),
+		"--virtual-time-budget=10000",
+	}
+	args = append(args, outArgs(file)...)
+	if strings.HasPrefix(origin, "https:") {
+		// The certificate is probably not valid for the local address.
+		args = append(args, "--ignore-certificate-errors")
+	}
+	id := s.register()
+	defer s.unregister(id)
+	cmd := exec.CommandContext(ctx, s.browser, append(args, origin+s.prefix+addScreenshotParam(path, id))...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("%w: %s", err, bytes.TrimSpace(out))
+	}
+	s.mx.Lock()
+	status := s.pages[id]
+	s.mx.Unlock()
+	if status != http.StatusOK {
+		return nil, &pageStatusError{status}
+	}
+	return os.ReadFile(file)
+}
+
+// register returns a new identifier of a page to be retrieved by the browser.
+func (s *screenshotter) register() string {
+	var buf [8]byte
+	rand.Read(buf[:])
+	id := hex.EncodeToString(buf[:])
+	s.mx.Lock()
+	if s.pages == nil {
+		s.pages = make(map[string]int)
+	}
+	s.pages[id] = 0
+	s.mx.Unlock()
+	return id
+}
+
+func (s *screenshotter) unregister(id string) {
+	s.mx.Lock()
+	delete(s.pages, id)
+	s.mx.Unlock()
+}
+
+// addScreenshotParam adds the query parameter that identifies the page to the
+// given path, before its fragment.
+func addScreenshotParam(path, id string) string {
+	path, fragment, hasFragment := strings.Cut(path, "#")
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	path += sep + screenshotParam + "=" + id
+	if hasFragment {
+		path += "#" + fragment
+	}
+	return path
+}
+
+// slidePNGPath returns the path of the slide show that shows the slide with
+// the given number, without controls.
+func slidePNGPath(zid api.ZettelID, slideNo int) string {
+	// Reveal.js overwrites its configuration with the query parameters.
+	return fmt.Sprintf("/%s.reveal?controls=false&progress=false&slideNumber=false#/(%d)", zid, slideNo)
+}
+
+// processSlidePNG sends the image of one slide of the slide show. The number
+// of the slide is given by the query parameter "slide", the first slide is the
+// default.
+func processSlidePNG(w http.ResponseWriter, r *http.Request, cfg *slidesConfig, zid api.ZettelID) {
+	if cfg.screenshots == nil {
+		http.Error(w, "PNG export needs a headless browser, see option -browser", http.StatusNotImplemented)
+		return
+	}
+	slideNo := 1
+	if val := r.URL.Query().Get("slide"); val != "" {
+		num, err := strconv.Atoi(val)
+		if err != nil || num < 1 {
+			http.Error(w, fmt.Sprintf("Invalid slide number %q", val), http.StatusBadRequest)
+			return
+		}
+		slideNo = num
+	}
+	data, err := cfg.screenshots.capture(r.Context(), requestOrigin(r), slidePNGPath(zid, slideNo))
+	if err != nil {
+		log.Println("SHOT", zid, slideNo, err)
+		http.Error(w, fmt.Sprintf("Unable to render slide %d of %s", slideNo, zid), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"%s-%d.png\"", zid, slideNo))
+	w.Write(data)
+}
@@ -0,0 +1,58 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAddScreenshotParam(t *testing.T) {
+	testcases := []struct {
+		path, exp string
+	}{
+		{"/1.reveal", "/1.reveal?screenshot=ID"},
+		{"/1.reveal?print-pdf=", "/1.reveal?print-pdf=&screenshot=ID"},
+		{slidePNGPath("20220101000000", 3), "/20220101000000.reveal?controls=false&progress=false&slideNumber=false&screenshot=ID#/(3)"},
+	}
+	for _, tc := range testcases {
+		if got := addScreenshotParam(tc.path, "ID"); got != tc.exp {
+			t.Errorf("%s: expected %q, but got %q", tc.path, tc.exp, got)
+		}
+	}
+}
+
+func TestScreenshotObserve(t *testing.T) {
+	s := newScreenshotter("chromium", "")
+	id := s.register()
+	defer s.unregister(id)
+
+	// Requests without a registered identifier are not observed.
+	for _, path := range []string{"/1.reveal", "/1.reveal?screenshot=other"} {
+		w, r := httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil)
+		if gotW, gotR := s.observe(w, r); gotW != w || gotR != r {
+			t.Errorf("%s: response writer and request must not be changed", path)
+		}
+	}
+
+	w, r := s.observe(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/1.reveal?print-pdf=&screenshot="+id, nil))
+	if got := r.URL.RequestURI(); got != "/1.reveal?print-pdf=" {
+		t.Errorf("expected request without parameter, but got %q", got)
+	}
+	http.Error(w, "not found", http.StatusNotFound)
+	// A later request with the same parameter does not change the status.
+	w, _ = s.observe(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/1.reveal?screenshot="+id, nil))
+	w.Write([]byte("ok"))
+	if got := s.pages[id]; got != http.StatusNotFound {
+		t.Errorf("expected status %d, but got %d", http.StatusNotFound, got)
+	}
+}
@@ -0,0 +1,408 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parameter of the shared cache.
+const (
+	sharedCacheTTL     = 7 * 24 * time.Hour // expiration of an entry in Redis
+	sharedCacheTimeout = 2 * time.Second    // maximum duration of a Redis command
+)
+
+// sharedBackend stores data of several zettel presenter instances, e.g.
+// behind a load balancer.
+type sharedBackend interface {
+	get(key string) ([]byte, error)
+	set(key string, data []byte) error
+}
+
+// errNotCached is returned by a backend, if a key is not stored.
+var errNotCached = errors.New("not cached")
+
+// sharedCache stores rendered pages and retrieved zettel, so that they are
+// available to all instances that use the same backend. Every key gets a
+// prefix, which contains the name of a tenant. A nil cache stores nothing.
+type sharedCache struct {
+	backend sharedBackend
+	prefix  string
+}
+
+// newSharedCache returns a shared cache for the given location, which is
+// either an URL "redis://[[user]:password@]host:port[/db]" or the path of a
+// directory. The prefix is the name of a tenant, if any: its entries are
+// stored separately.
+func newSharedCache(location, prefix string) (*sharedCache, error) {
+	if location == "" {
+		return nil, nil
+	}
+	if strings.HasPrefix(location, "redis://") {
+		rb, err := newRedisBackend(location)
+		if err != nil {
+			return nil, err
+		}
+		keyPrefix := "zettel-presenter"
+		if prefix != "" {
+			keyPrefix += ":" + prefix
+		}
+		return &sharedCache{backend: rb, prefix: keyPrefix}, nil
+	}
+	dir := location
+	if prefix != "" {
+		dir = filepath.Join(dir, prefix)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &sharedCache{backend: dirBackend{dir: dir}}, nil
+}
+
+func (sc *sharedCache) get(key string) ([]byte, bool) {
+	if sc == nil {
+		return nil, false
+	}
+	data, err := sc.backend.get(sc.prefix + ":" + key)
+	if err != nil {
+		if err != errNotCached {
+			log.Println("SHRD", key, err)
+		}
+		return nil, false
+	}
+	return data, true
+}
+
+func (sc *sharedCache) set(key string, data []byte) {
+	if sc == nil {
+		return
+	}
+	if err := sc.backend.set(sc.prefix+":"+key, data); err != nil {
+		log.Println("SHRD", key, err)
+	}
+}
+
+// getPage returns a rendered page, stored by setPage.
+func (sc *sharedCache) getPage(uri string) (lastGoodEntry, bool) {
+	data, found := sc.get("page:" + uri)
+	if !found {
+		return lastGoodEntry{}, false
+	}
+	tr := textproto.NewReader(bufio.NewReader(bytes.NewReader(data)))
+	line, err := tr.ReadLine()
+	if err != nil {
+		return lastGoodEntry{}, false
+	}
+	created, err := strconv.ParseInt(line, 10, 64)
+	if err != nil {
+		return lastGoodEntry{}, false
+	}
+	header, err := tr.ReadMIMEHeader()
+	if err != nil {
+		return lastGoodEntry{}, false
+	}
+	body, err := io.ReadAll(tr.R)
+	if err != nil {
+		return lastGoodEntry{}, false
+	}
+	return lastGoodEntry{header: http.Header(header), data: body, created: time.Unix(created, 0)}, true
+}
+
+// setPage stores a rendered page together with its header.
+func (sc *sharedCache) setPage(uri string, entry lastGoodEntry) {
+	if sc == nil {
+		return
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%d\r\n", entry.created.Unix())
+	entry.header.Write(&buf)
+	buf.WriteString("\r\n")
+	buf.Write(entry.data)
+	sc.set("page:"+uri, buf.Bytes())
+}
+
+// dirBackend stores every entry as a file of a directory, e.g. on a network
+// file system. The name of a file is a hash value of its key.
+type dirBackend struct {
+	dir string
+}
+
+func (db dirBackend) path(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return filepath.Join(db.dir, hex.EncodeToString(h[:]))
+}
+
+func (db dirBackend) get(key string) ([]byte, error) {
+	data, err := os.ReadFile(db.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errNotCached
+	}
+	return data, err
+}
+
+func (db dirBackend) set(key string, data []byte) error {
+	// Write to a temporary file first, so that other instances never read a
+	// partially written file.
+	f, err := os.CreateTemp(db.dir, "*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), db.path(key))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// redisBackend stores entries in a Redis server. It uses a pool of
+// connections, so that concurrent requests do not wait for each other. An
+// entry is stored asynchronously by a background writer.
+type redisBackend struct {
+	addr     string
+	user     string
+	password string
+	db       int
+
+	idle   chan *redisConn   // connections that are not in use
+	slots  chan struct{}     // limits the number of open connections
+	writes chan redisCommand // entries to be stored
+}
+
+// Parameter of the Redis connection pool.
+const (
+	redisMaxConns   = 16  // maximum number of open connections
+	redisMaxIdle    = 4   // maximum number of unused connections
+	redisWriters    = 2   // number of background writers
+	redisWriteQueue = 256 // maximum number of entries waiting to be stored
+)
+
+type redisCommand []string
+
+// errQueueFull is returned, if an entry cannot be stored, because too many
+// entries are waiting.
+var errQueueFull = errors.New("redis: write queue full")
+
+func newRedisBackend(location string) (*redisBackend, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+	rb := &redisBackend{
+		addr:   u.Host,
+		idle:   make(chan *redisConn, redisMaxIdle),
+		slots:  make(chan struct{}, redisMaxConns),
+		writes: make(chan redisCommand, redisWriteQueue),
+	}
+	if u.Port() == "" {
+		rb.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if pw, ok := u.User.Password(); ok {
+		// Since Redis 6, a user name selects an ACL user.
+		rb.user, rb.password = u.User.Username(), pw
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if rb.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid redis database %q", db)
+		}
+	}
+	for i := 0; i < redisWriters; i++ {
+		go rb.writer()
+	}
+	return rb, nil
+}
+
+func (rb *redisBackend) get(key string) ([]byte, error) {
+	data, err := rb.do("GET", key)
+	if err == nil && data == nil {
+		return nil, errNotCached
+	}
+	return data, err
+}
+
+// set queues the entry, so that the caller need not wait for Redis.
+func (rb *redisBackend) set(key string, data []byte) error {
+	select {
+	case rb.writes <- redisCommand{"SET", key, string(data), "EX", strconv.Itoa(int(sharedCacheTTL / time.Second))}:
+		return nil
+	default:
+		return errQueueFull
+	}
+}
+
+// writer stores all queued entries.
+func (rb *redisBackend) writer() {
+	for cmd := range rb.writes {
+		if _, err := rb.do(cmd...); err != nil {
+			log.Println("SHRD", cmd[1], err)
+		}
+	}
+}
+
+// do sends a command over a connection of the pool and returns the value of
+// its reply. A nil reply returns nil data.
+func (rb *redisBackend) do(args ...string) ([]byte, error) {
+	rc, err := rb.acquire()
+	if err != nil {
+		return nil, err
+	}
+	data, err := rc.command(args...)
+	var rerr redisError
+	if err != nil && !errors.As(err, &rerr) {
+		// Connection is in an unknown state.
+		rb.release(rc, false)
+	} else {
+		rb.release(rc, true)
+	}
+	return data, err
+}
+
+// acquire returns an unused connection of the pool, or opens a new one.
+func (rb *redisBackend) acquire() (*redisConn, error) {
+	select {
+	case rc := <-rb.idle:
+		return rc, nil
+	default:
+	}
+	timer := time.NewTimer(sharedCacheTimeout)
+	defer timer.Stop()
+	select {
+	case rc := <-rb.idle:
+		return rc, nil
+	case rb.slots <- struct{}{}:
+	case <-timer.C:
+		return nil, errors.New("redis: no connection available")
+	}
+	rc, err := rb.connect()
+	if err != nil {
+		<-rb.slots
+		return nil, err
+	}
+	return rc, nil
+}
+
+// release returns the connection to the pool. It is closed, if it is no
+// longer usable or if there are enough unused connections.
+func (rb *redisBackend) release(rc *redisConn, reuse bool) {
+	if reuse {
+		select {
+		case rb.idle <- rc:
+			return
+		default:
+		}
+	}
+	rc.conn.Close()
+	<-rb.slots
+}
+
+func (rb *redisBackend) connect() (*redisConn, error) {
+	conn, err := net.DialTimeout("tcp", rb.addr, sharedCacheTimeout)
+	if err != nil {
+		return nil, err
+	}
+	rc := &redisConn{conn: conn, rd: bufio.NewReader(conn)}
+	if rb.password != "" {
+		if rb.user != "" {
+			_, err = rc.command("AUTH", rb.user, rb.password)
+		} else {
+			_, err = rc.command("AUTH", rb.password)
+		}
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if rb.db != 0 {
+		if _, err = rc.command("SELECT", strconv.Itoa(rb.db)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return rc, nil
+}
+
+// redisConn is a connection to the Redis server.
+type redisConn struct {
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+func (rc *redisConn) command(args ...string) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&buf, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	rc.conn.SetWriteDeadline(time.Now().Add(sharedCacheTimeout))
+	if _, err := rc.conn.Write(buf.Bytes()); err != nil {
+		return nil, err
+	}
+	rc.conn.SetReadDeadline(time.Now().Add(sharedCacheTimeout))
+	return rc.reply()
+}
+
+type redisError string
+
+func (re redisError) Error() string { return "redis: " + string(re) }
+
+// reply reads a reply of the Redis server. Only simple strings, errors,
+// integers, and bulk strings are expected.
+func (rc *redisConn) reply() ([]byte, error) {
+	line, err := rc.rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+	switch line[0] {
+	case '+', ':':
+		return []byte(line[1:]), nil
+	case '-':
+		return nil, redisError(line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		data := make([]byte, n+2)
+		if _, err = io.ReadFull(rc.rd, data); err != nil {
+			return nil, err
+		}
+		return data[:n], nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply %q", line)
+}
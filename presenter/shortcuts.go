@@ -0,0 +1,63 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+
+	"zettelstore.de/contrib/presenter/deck"
+)
+
+// shortcut describes a keyboard shortcut of the slide show.
+type shortcut struct {
+	keys        string
+	description string
+}
+
+// revealShortcuts returns all shortcuts that are enabled for the given slide
+// set.
+func revealShortcuts(*deck.SlideSet) []shortcut {
+	return []shortcut{
+		{"N, Space, →", "Next slide"},
+		{"P, ←", "Previous slide"},
+		{"Home, End", "First / last slide"},
+		{"S", "Speaker view"},
+		{"B, .", "Black screen"},
+		{"O, Esc", "Overview of all slides"},
+		{"F", "Fullscreen"},
+		{"C", "High contrast"},
+		{"?", "Show / hide this help"},
+	}
+}
+
+const helpCSS = `<style type="text/css">
+div.zs-help { display: none; position: fixed; top: 10%; left: 20%; right: 20%; z-index: 100; padding: 1rem 2rem; background: white; border: 2px solid #222; font-family: sans-serif; font-size: 1.5rem }
+div.zs-help.visible { display: block }
+div.zs-help th { text-align: left; padding-right: 2rem }
+</style>
+`
+
+// writeShortcutHelp writes the help overlay, together with the JavaScript
+// code to show it. It must be written after the reveal.js script.
+func writeShortcutHelp(w io.Writer, lang string, shortcuts []shortcut) {
+	fmt.Fprintf(w, "<div class=\"zs-help\" id=\"zs-help\" role=\"dialog\" aria-label=\"%s\">\n<table>\n", translate(lang, "Keyboard shortcuts"))
+	for _, sc := range shortcuts {
+		fmt.Fprintf(w, "<tr><th>%s</th><td>%s</td></tr>\n", html.EscapeString(sc.keys), html.EscapeString(translate(lang, sc.description)))
+	}
+	io.WriteString(w, `</table>
+</div>
+<script>Reveal.addKeyBinding({keyCode: 191, key: "?", description: "Help"}, function() {
+document.getElementById("zs-help").classList.toggle("visible");
+});</script>
+`)
+}
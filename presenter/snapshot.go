@@ -0,0 +1,92 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"net/http"
+	"path"
+	"path/filepath"
+
+	"zettelstore.de/c/api"
+)
+
+// snapshotHandler serves the files of a directory with exported slide shows,
+// e.g. unpacked bundles. Only requests for files that are not in the
+// directory are forwarded to the live handler, which renders them with the
+// help of Zettelstore.
+type snapshotHandler struct {
+	dir   http.Dir
+	files http.Handler
+	live  http.Handler // nil, if Zettelstore was not reachable on start
+}
+
+func newSnapshotHandler(dir string, live http.Handler) *snapshotHandler {
+	d := http.Dir(dir)
+	return &snapshotHandler{dir: d, files: http.FileServer(d), live: live}
+}
+
+func (sh *snapshotHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p := r.URL.Path
+	if (isPreviewRequest(r) || r.URL.Query().Has("asof")) && sh.live != nil {
+		// A preview always shows the current zettel, the history is kept
+		// by the live handler.
+		sh.live.ServeHTTP(w, r)
+		return
+	}
+	if zid, suffix := retrieveZidAndSuffix(p); zid != api.InvalidZID && (suffix == "reveal" || suffix == "slide") {
+		// A bundle is unpacked into a directory named after the slide set.
+		if sh.has("/" + string(zid)) {
+			// The location is relative, because the request may be served
+			// below the path prefix of a tenant.
+			w.Header().Set("Location", string(zid)+"/")
+			w.WriteHeader(http.StatusFound)
+			return
+		}
+	}
+	if p != "/" && sh.has(p) {
+		sh.files.ServeHTTP(w, r)
+		return
+	}
+	if sh.live == nil {
+		http.Error(w, "Not in snapshot, and Zettelstore is not available", http.StatusServiceUnavailable)
+		return
+	}
+	sh.live.ServeHTTP(w, r)
+}
+
+// has returns true, if the snapshot contains a regular file with the given
+// path, or a directory with an "index.html" file.
+func (sh *snapshotHandler) has(p string) bool {
+	f, err := sh.dir.Open(path.Clean(p))
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	if fi.Mode().IsRegular() {
+		return true
+	}
+	if !fi.IsDir() {
+		return false
+	}
+	return sh.has(path.Join(p, "index.html"))
+}
+
+// snapshotDir returns the snapshot directory of the given tenant.
+func snapshotDir(dir string, t tenant) string {
+	if t.name == "" {
+		return dir
+	}
+	return filepath.Join(dir, t.name)
+}
@@ -0,0 +1,29 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+// Text within a slide that is marked with the class "speaker", e.g.
+// "::say: win::{.speaker}", is a hint for the speaker. The slide show
+// hides it from the audience, but shows it in the speaker view, which
+// presents the slides in frames with the query parameter "receiver".
+
+// speakerHead hides the text for the speaker, unless the slide show is shown
+// within the speaker view.
+const speakerHead = `<style type="text/css">
+.reveal span.speaker { display: none }
+html.zs-receiver .reveal span.speaker { display: inline; color: #c0392b; font-style: italic }
+html.zs-receiver .reveal span.speaker::before { content: "[" }
+html.zs-receiver .reveal span.speaker::after { content: "]" }
+</style>
+<script>
+if (new URLSearchParams(window.location.search).has("receiver")) { document.documentElement.classList.add("zs-receiver"); }
+</script>
+`
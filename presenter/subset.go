@@ -0,0 +1,67 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"zettelstore.de/c/api"
+)
+
+// selectSlides returns the slides of the list, which are given by the
+// specification of the query parameter "slides", e.g. "3,5,7-9". A number is
+// the position of a slide within the slide set zettel, starting with 1. The
+// slides are returned in the order of the specification, so that they can be
+// reordered too. A range may be descending, e.g. "9-7".
+func selectSlides(l []api.ZidMetaJSON, spec string) ([]api.ZidMetaJSON, error) {
+	var result []api.ZidMetaJSON
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		fromVal, toVal, isRange := strings.Cut(part, "-")
+		from, err := slidePosition(fromVal, len(l))
+		if err != nil {
+			return nil, err
+		}
+		to := from
+		if isRange {
+			if to, err = slidePosition(toVal, len(l)); err != nil {
+				return nil, err
+			}
+		}
+		step := 1
+		if to < from {
+			step = -1
+		}
+		for pos := from; ; pos += step {
+			result = append(result, l[pos-1])
+			if pos == to {
+				break
+			}
+		}
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no slides selected by %q", spec)
+	}
+	return result, nil
+}
+
+func slidePosition(val string, numSlides int) (int, error) {
+	pos, err := strconv.Atoi(strings.TrimSpace(val))
+	if err != nil || pos < 1 || pos > numSlides {
+		return 0, fmt.Errorf("invalid slide %q, must be a number between 1 and %d", val, numSlides)
+	}
+	return pos, nil
+}
@@ -0,0 +1,55 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"zettelstore.de/c/api"
+)
+
+func TestSelectSlides(t *testing.T) {
+	l := []api.ZidMetaJSON{{ID: "1"}, {ID: "2"}, {ID: "3"}, {ID: "4"}, {ID: "5"}}
+	testcases := []struct {
+		spec string
+		exp  string // zettel identifiers, or "error"
+	}{
+		{"1", "1"},
+		{"3,1", "3,1"},
+		{" 2 - 4 ", "2,3,4"},
+		{"4-2", "4,3,2"},
+		{"5-5,1,,", "5,1"},
+		{"1,1", "1,1"},
+		{"", "error"},
+		{",", "error"},
+		{"0", "error"},
+		{"6", "error"},
+		{"1-6", "error"},
+		{"x", "error"},
+		{"-2", "error"},
+		{"2-", "error"},
+	}
+	for _, tc := range testcases {
+		sel, err := selectSlides(l, tc.spec)
+		got := "error"
+		if err == nil {
+			zids := make([]string, len(sel))
+			for i, zm := range sel {
+				zids[i] = string(zm.ID)
+			}
+			got = strings.Join(zids, ",")
+		}
+		if got != tc.exp {
+			t.Errorf("%q: expected %q, but got %q (%v)", tc.spec, tc.exp, got, err)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+
+	"zettelstore.de/c/api"
+	"zettelstore.de/contrib/presenter/deck"
+)
+
+// Constants for output types that are not slide roles.
+const (
+	OutputZettel = "zettel"
+	OutputList   = "list"
+)
+
+// Names of the templates that may replace a part of the HTML skeleton.
+const (
+	tmplHead   = "head"
+	tmplBody   = "body"
+	tmplFooter = "footer"
+)
+
+// keyTemplate returns the configuration key for the template zettel of the
+// given output type.
+func keyTemplate(output string) string { return "template-" + output }
+
+var templateOutputs = []string{deck.SlideRoleShow, deck.SlideRoleHandout, OutputZettel, OutputList, OutputNotes, OutputContact}
+
+// getTemplate retrieves the user-defined template for the given output type.
+// If there is no such template, or if it is not valid, nil is returned.
+func (cfg *slidesConfig) getTemplate(ctx context.Context, output string) *template.Template {
+	zid, found := cfg.templates[output]
+	if !found {
+		return nil
+	}
+	data, err := cfg.c.GetZettel(ctx, zid, api.PartContent)
+	if err != nil {
+		log.Println("TMPL", zid, err)
+		return nil
+	}
+	t, err := template.New(output).Parse(string(data))
+	if err != nil {
+		log.Println("TMPL", zid, err)
+		return nil
+	}
+	return t
+}
+
+// htmlPage stores all data needed to write the skeleton of a HTML page.
+type htmlPage struct {
+	Output     string // Output type, e.g. "show", "handout", "zettel", "list"
+	Lang       string
+	prefix     string // CSS prefix for default CSS
+	defaultCSS []string
+	tmpl       *template.Template
+}
+
+func (cfg *slidesConfig) newHTMLPage(output, lang, prefix string, tmpl *template.Template) *htmlPage {
+	return &htmlPage{
+		Output:     output,
+		Lang:       lang,
+		prefix:     prefix,
+		defaultCSS: cfg.defaultCSS,
+		tmpl:       tmpl,
+	}
+}
+
+// DefaultCSS returns the style element with the default CSS. It is intended
+// to be used in a "head" template. Since it is generated by presenter, it is
+// not escaped.
+func (p *htmlPage) DefaultCSS() template.HTML {
+	var buf bytes.Buffer
+	writeDefaultCSS(&buf, p.defaultCSS, p.prefix)
+	return template.HTML(buf.String())
+}
+
+// execute writes the named template, if it was defined by the user. The
+// result is true, if the template was written.
+func (p *htmlPage) execute(w io.Writer, name string) bool {
+	if p.tmpl == nil {
+		return false
+	}
+	t := p.tmpl.Lookup(name)
+	if t == nil {
+		return false
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, p); err != nil {
+		log.Println("EXEC", name, err)
+		return false
+	}
+	_, err := w.Write(buf.Bytes())
+	return err == nil
+}
+
+func writeHTMLHeader(w http.ResponseWriter, p *htmlPage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if p.execute(w, tmplHead) {
+		return
+	}
+	io.WriteString(w, "<!DOCTYPE html>\n")
+	if p.Lang == "" {
+		io.WriteString(w, "<html>\n")
+	} else {
+		fmt.Fprintf(w, "<html lang=\"%s\">\n", p.Lang)
+	}
+	io.WriteString(w, `<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1.0, maximum-scale=1.0, user-scalable=no">
+<meta name="generator" content="Zettel Presenter">
+`)
+	writeDefaultCSS(w, p.defaultCSS, p.prefix)
+}
+
+func writeHTMLBody(w http.ResponseWriter, p *htmlPage) {
+	if !p.execute(w, tmplBody) {
+		io.WriteString(w, "</head>\n<body>\n")
+	}
+}
+
+func writeHTMLFooter(w http.ResponseWriter, p *htmlPage, scripts string) {
+	io.WriteString(w, scripts)
+	if !p.execute(w, tmplFooter) {
+		io.WriteString(w, "</body>\n</html>\n")
+	}
+}
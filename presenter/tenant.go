@@ -0,0 +1,121 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// tenant is a Zettelstore that is served below the path prefix "/name/". All
+// pages of presenter use relative links, so that they work below any prefix.
+type tenant struct {
+	name string
+	url  string // URL of Zettelstore, optionally with credentials
+}
+
+// tenantList collects the values of the repeatable option "-tenant".
+type tenantList []tenant
+
+func (tl *tenantList) String() string {
+	vals := make([]string, len(*tl))
+	for i, t := range *tl {
+		vals[i] = t.name + "=" + t.url
+	}
+	return strings.Join(vals, ",")
+}
+
+// Set adds a tenant, given as "name=URL".
+func (tl *tenantList) Set(val string) error {
+	name, u, found := strings.Cut(val, "=")
+	if !found || u == "" {
+		return fmt.Errorf("%q: tenant must be given as name=URL", val)
+	}
+	if !isTenantName(name) {
+		return fmt.Errorf("%q: tenant name must consist of lower case letters, digits, and '-'", name)
+	}
+	for _, t := range *tl {
+		if t.name == name {
+			return fmt.Errorf("tenant %q given twice", name)
+		}
+	}
+	*tl = append(*tl, tenant{name: name, url: u})
+	return nil
+}
+
+func isTenantName(name string) bool {
+	if name == "" || strings.HasPrefix(name, "revealjs") {
+		return false // path prefix of reveal.js files
+	}
+	for _, ch := range name {
+		if (ch < 'a' || ch > 'z') && (ch < '0' || ch > '9') && ch != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// prefix returns the path prefix of the tenant, without a trailing slash.
+func (t tenant) prefix() string {
+	if t.name == "" {
+		return ""
+	}
+	return "/" + t.name
+}
+
+// newTenantStore connects to the Zettelstore of the tenant and reads its
+// configuration zettel. The given options are shared by all tenants, but
+// every tenant gets its own cache directory.
+func newTenantStore(ctx context.Context, t tenant, shared runOptions) (*configStore, error) {
+	c, err := getClient(ctx, t.url)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to zettelstore: %w", err)
+	}
+	cfg, err := getConfig(ctx, newZsClient(c, *shared.timeout))
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve presenter config: %w", err)
+	}
+	opts := shared
+	opts.prefix = t.prefix()
+	opts.cacheSub = t.name
+	if opts.shared, err = newSharedCache(opts.sharedCache, t.name); err != nil {
+		return nil, fmt.Errorf("unable to use shared cache: %w", err)
+	}
+	cfg.lastGood.shared = opts.shared
+	if err = opts.apply(&cfg); err != nil {
+		return nil, fmt.Errorf("unable to use cache directory: %w", err)
+	}
+	return &configStore{
+		cfg:        &cfg,
+		opts:       &opts,
+		clients:    &clientTracker{},
+		adminToken: newAdminToken(),
+	}, nil
+}
+
+// processTenants writes the page that lists all tenants.
+func processTenants(w http.ResponseWriter, r *http.Request, tenants tenantList) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	io.WriteString(w, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>Zettel Presenter</title>\n</head>\n<body>\n<h1>Zettel Presenter</h1>\n<ul>\n")
+	for _, t := range tenants {
+		fmt.Fprintf(w, "<li><a href=\"%s/\">%s</a></li>\n", t.prefix(), html.EscapeString(t.name))
+	}
+	io.WriteString(w, "</ul>\n</body>\n</html>\n")
+}
@@ -0,0 +1,70 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// The text size of a handout is given as a percentage of the font size of
+// the browser. The choice of the user is stored in the local storage of the
+// browser, so that it applies to all handouts.
+
+const (
+	textSizeStorageKey = "zs-text-size"
+	minTextSize        = 70
+	maxTextSize        = 250
+	textSizeStep       = 10
+)
+
+const textSizeCSS = `<style type="text/css">
+div.zs-text-size { position: fixed; z-index: 50; right: 1rem; top: 3.5rem; display: flex; gap: .25rem }
+div.zs-text-size button { padding: .25rem .5rem; font-family: sans-serif; font-size: 16px; color: #000; background: #fff; border: 2px solid #000; border-radius: .25rem; cursor: pointer }
+@media print { div.zs-text-size { display: none } }
+</style>
+`
+
+// writeTextSizeHead writes the CSS of the text size controls, together with
+// a script that applies the stored text size before the page is rendered.
+func writeTextSizeHead(w io.Writer) {
+	io.WriteString(w, textSizeCSS)
+	fmt.Fprintf(w, `<script>try { var zsTextSize = parseInt(localStorage.getItem(%q), 10); if (zsTextSize >= %d && zsTextSize <= %d) { document.documentElement.style.fontSize = zsTextSize + "%%"; } } catch (e) {}</script>
+`, textSizeStorageKey, minTextSize, maxTextSize)
+}
+
+// writeTextSizeControls writes the buttons to decrease, reset, and increase
+// the text size.
+func writeTextSizeControls(w io.Writer, lang string) {
+	fmt.Fprintf(w, `<div class="zs-text-size" role="group" aria-label="%s">
+<button type="button" data-step="-%d" title="%s" aria-label="%s">A&minus;</button>
+<button type="button" data-step="0" title="%s" aria-label="%s">A</button>
+<button type="button" data-step="%d" title="%s" aria-label="%s">A+</button>
+</div>
+`,
+		translate(lang, "Text size"),
+		textSizeStep, translate(lang, "Smaller text"), translate(lang, "Smaller text"),
+		translate(lang, "Normal text size"), translate(lang, "Normal text size"),
+		textSizeStep, translate(lang, "Larger text"), translate(lang, "Larger text"))
+	fmt.Fprintf(w, `<script>(function() {
+var root = document.documentElement;
+document.querySelectorAll("div.zs-text-size button").forEach(function(button) {
+button.addEventListener("click", function() {
+var step = parseInt(button.getAttribute("data-step"), 10);
+var size = parseInt(root.style.fontSize, 10) || 100;
+size = step === 0 ? 100 : Math.min(Math.max(size + step, %d), %d);
+root.style.fontSize = size === 100 ? "" : size + "%%";
+try { localStorage.setItem(%q, String(size)); } catch (e) {}
+});
+});
+})();</script>
+`, minTextSize, maxTextSize, textSizeStorageKey)
+}
@@ -0,0 +1,189 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"zettelstore.de/c/api"
+	"zettelstore.de/c/sexpr"
+	"zettelstore.de/c/text"
+	"zettelstore.de/contrib/presenter/deck"
+)
+
+// Size of a thumbnail image.
+const (
+	thumbWidth  = 320
+	thumbHeight = 180
+)
+
+// thumbCache stores the generated thumbnails, together with the modification
+// timestamp of the slide set zettel they were generated from.
+type thumbCache struct {
+	mx      sync.Mutex
+	entries map[api.ZettelID]thumbEntry
+	hits    int
+	misses  int
+}
+type thumbEntry struct {
+	modified    string
+	contentType string
+	data        []byte
+}
+
+func (tc *thumbCache) get(zid api.ZettelID, modified string) (thumbEntry, bool) {
+	tc.mx.Lock()
+	defer tc.mx.Unlock()
+	if entry, found := tc.entries[zid]; found && entry.modified == modified {
+		tc.hits++
+		return entry, true
+	}
+	tc.misses++
+	return thumbEntry{}, false
+}
+
+// stats returns the number of stored thumbnails, their size in bytes, and
+// the number of cache hits and misses.
+func (tc *thumbCache) stats() (entries, size, hits, misses int) {
+	tc.mx.Lock()
+	defer tc.mx.Unlock()
+	for _, entry := range tc.entries {
+		size += len(entry.data)
+	}
+	return len(tc.entries), size, tc.hits, tc.misses
+}
+
+// flush removes all thumbnails.
+func (tc *thumbCache) flush() {
+	tc.mx.Lock()
+	tc.entries = nil
+	tc.mx.Unlock()
+}
+func (tc *thumbCache) set(zid api.ZettelID, entry thumbEntry) {
+	tc.mx.Lock()
+	if tc.entries == nil {
+		tc.entries = make(map[api.ZettelID]thumbEntry)
+	}
+	tc.entries[zid] = entry
+	tc.mx.Unlock()
+}
+
+// processThumbnail sends the preview image of the title slide. If a headless
+// browser is available, it is a screenshot of the first slide of the slide
+// show. Otherwise, or if the screenshot fails, a SVG image resembles the
+// title slide, but without user-defined CSS and images.
+func processThumbnail(w http.ResponseWriter, r *http.Request, cfg *slidesConfig, zid api.ZettelID) {
+	sMeta, err := cfg.c.GetEvaluatedSexpr(r.Context(), zid, api.PartMeta)
+	if err != nil {
+		reportRetrieveError(w, zid, err, "thumbnail")
+		return
+	}
+	sxMeta := sexpr.MakeMeta(sMeta)
+	modified := sxMeta.GetString(api.KeyModified)
+	entry, found := cfg.thumbs.get(zid, modified)
+	if !found {
+		entry = thumbEntry{modified: modified}
+		if cfg.screenshots != nil {
+			data, err := cfg.screenshots.capture(r.Context(), requestOrigin(r), slidePNGPath(zid, 1))
+			if err == nil {
+				entry.contentType, entry.data = "image/png", data
+				cfg.thumbs.set(zid, entry)
+			} else {
+				log.Println("THMB", zid, err)
+			}
+		}
+		if entry.data == nil {
+			slides := deck.NewMeta(zid, sxMeta)
+			entry.contentType = "image/svg+xml"
+			entry.data = renderThumbnail(slides, slides.Author(cfg.author), &cfg.branding)
+			if cfg.screenshots == nil {
+				// A failed screenshot is tried again on the next request.
+				cfg.thumbs.set(zid, entry)
+			}
+		}
+	}
+	w.Header().Set("Content-Type", entry.contentType)
+	w.Write(entry.data)
+}
+
+// renderThumbnail produces a SVG image that resembles the title slide. It is
+// the fallback, if no headless browser is available to take a screenshot.
+func renderThumbnail(slides *deck.SlideSet, author string, b *branding) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\">\n",
+		thumbWidth, thumbHeight, thumbWidth, thumbHeight)
+	fmt.Fprintf(&buf, "<rect width=\"%d\" height=\"%d\" fill=\"white\" stroke=\"lightgray\"/>\n", thumbWidth, thumbHeight)
+	color := b.primaryColor
+	if color == "" {
+		color = "#222"
+	}
+	font := b.fontFamily
+	if font == "" {
+		font = "Helvetica, sans-serif"
+	}
+	anchor, x := "middle", thumbWidth/2
+	if slides.TitleLayout() == deck.TitleLayoutSplit {
+		anchor, x = "start", 16
+	}
+
+	var lines []string
+	if title := slides.Title(); !title.IsEmpty() {
+		lines = wrapText(text.EvaluateInlineString(title), 22)
+	} else {
+		lines = []string{string(slides.Zid())}
+	}
+	y := thumbHeight/2 - len(lines)*12
+	for _, line := range lines {
+		fmt.Fprintf(&buf, "<text x=\"%d\" y=\"%d\" text-anchor=\"%s\" font-family=\"%s\" font-size=\"22\" font-weight=\"bold\" fill=\"%s\">%s</text>\n",
+			x, y, anchor, html.EscapeString(font), html.EscapeString(color), html.EscapeString(line))
+		y += 26
+	}
+	if slides.TitleLayout() != deck.TitleLayoutMinimal {
+		if subtitle := slides.Subtitle(); !subtitle.IsEmpty() {
+			fmt.Fprintf(&buf, "<text x=\"%d\" y=\"%d\" text-anchor=\"%s\" font-family=\"%s\" font-size=\"14\" fill=\"#444\">%s</text>\n",
+				x, y, anchor, html.EscapeString(font), html.EscapeString(text.EvaluateInlineString(subtitle)))
+			y += 20
+		}
+		if author != "" {
+			fmt.Fprintf(&buf, "<text x=\"%d\" y=\"%d\" text-anchor=\"%s\" font-family=\"%s\" font-size=\"12\" fill=\"#666\">%s</text>\n",
+				x, y+6, anchor, html.EscapeString(font), html.EscapeString(author))
+		}
+	}
+	buf.WriteString("</svg>\n")
+	return buf.Bytes()
+}
+
+// wrapText splits the given text into lines of at most maxLen characters,
+// if possible. Words are not split.
+func wrapText(s string, maxLen int) []string {
+	var result []string
+	var line strings.Builder
+	for _, word := range strings.Fields(s) {
+		if line.Len() > 0 && line.Len()+1+len(word) > maxLen {
+			result = append(result, line.String())
+			line.Reset()
+		}
+		if line.Len() > 0 {
+			line.WriteByte(' ')
+		}
+		line.WriteString(word)
+	}
+	if line.Len() > 0 {
+		result = append(result, line.String())
+	}
+	return result
+}
@@ -0,0 +1,52 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWrapText(t *testing.T) {
+	testcases := []struct {
+		s      string
+		maxLen int
+		exp    string
+	}{
+		{"", 10, ""},
+		{"short", 10, "short"},
+		{"one two three", 7, "one two|three"},
+		{"one  two   three", 20, "one two three"},
+		{"averyveryverylongword x", 5, "averyveryverylongword|x"},
+	}
+	for _, tc := range testcases {
+		if got := strings.Join(wrapText(tc.s, tc.maxLen), "|"); got != tc.exp {
+			t.Errorf("wrapText(%q, %d): expected %q, but got %q", tc.s, tc.maxLen, tc.exp, got)
+		}
+	}
+}
+
+func TestThumbCache(t *testing.T) {
+	var tc thumbCache
+	if _, found := tc.get("20220101000000", "1"); found {
+		t.Error("empty cache returns an entry")
+	}
+	tc.set("20220101000000", thumbEntry{modified: "1", contentType: "image/png", data: []byte("png")})
+	if entry, found := tc.get("20220101000000", "1"); !found || entry.contentType != "image/png" {
+		t.Errorf("expected stored entry, but got %v/%v", entry, found)
+	}
+	if _, found := tc.get("20220101000000", "2"); found {
+		t.Error("entry of a modified zettel is returned")
+	}
+	if entries, size, hits, misses := tc.stats(); entries != 1 || size != 3 || hits != 1 || misses != 2 {
+		t.Errorf("unexpected stats %d %d %d %d", entries, size, hits, misses)
+	}
+}
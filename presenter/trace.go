@@ -0,0 +1,318 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tracing follows OpenTelemetry: every request is a span, and every call to
+// Zettelstore is a child span. Spans are exported in batches via OTLP/HTTP
+// with JSON encoding, so that any OpenTelemetry collector is able to receive
+// them. The W3C header "traceparent" of a request is honored, therefore a
+// render can be part of a distributed trace.
+
+// Parameter of the span exporter.
+const (
+	traceBatchSize     = 512
+	traceQueueSize     = 4096
+	traceFlushInterval = 5 * time.Second
+	traceServiceName   = "zettel-presenter"
+	traceScopeName     = "zettelstore.de/contrib/presenter"
+)
+
+// Kinds of a span, as defined by OTLP.
+const (
+	spanInternal = 1
+	spanServer   = 2
+	spanClient   = 3
+)
+
+// Status codes of a span, as defined by OTLP.
+const (
+	statusUnset = 0
+	statusError = 2
+)
+
+// tracer exports all spans. If it is nil, nothing is traced.
+var tracer *traceExporter
+
+type traceID [16]byte
+type spanID [8]byte
+
+// span is a timed operation within a trace.
+type span struct {
+	trace  traceID
+	id     spanID
+	parent spanID
+	name   string
+	kind   int
+	start  time.Time
+	attrs  map[string]string
+}
+
+type spanKey struct{}
+
+// startSpan starts a span, which is a child of the span of the given
+// context, if any. The returned context contains the new span. If tracing is
+// disabled, the span is nil; all its methods may be called nevertheless.
+func startSpan(ctx context.Context, name string, kind int) (context.Context, *span) {
+	if tracer == nil {
+		return ctx, nil
+	}
+	sp := &span{name: name, kind: kind, start: time.Now(), attrs: make(map[string]string)}
+	if parent, ok := ctx.Value(spanKey{}).(*span); ok && parent != nil {
+		sp.trace = parent.trace
+		sp.parent = parent.id
+	} else {
+		rand.Read(sp.trace[:])
+	}
+	rand.Read(sp.id[:])
+	return context.WithValue(ctx, spanKey{}, sp), sp
+}
+
+// set adds an attribute to the span. Empty values are ignored.
+func (sp *span) set(key, val string) {
+	if sp != nil && val != "" {
+		sp.attrs[key] = val
+	}
+}
+
+// end finishes the span and hands it over to the exporter.
+func (sp *span) end(err error) {
+	if sp == nil {
+		return
+	}
+	status := otlpStatus{Code: statusUnset}
+	if err != nil {
+		status = otlpStatus{Code: statusError, Message: err.Error()}
+	}
+	rec := otlpSpan{
+		TraceID:   hex.EncodeToString(sp.trace[:]),
+		SpanID:    hex.EncodeToString(sp.id[:]),
+		Name:      sp.name,
+		Kind:      sp.kind,
+		StartTime: strconv.FormatInt(sp.start.UnixNano(), 10),
+		EndTime:   strconv.FormatInt(time.Now().UnixNano(), 10),
+		Status:    status,
+	}
+	if sp.parent != (spanID{}) {
+		rec.ParentSpanID = hex.EncodeToString(sp.parent[:])
+	}
+	for k, v := range sp.attrs {
+		rec.Attributes = append(rec.Attributes, otlpAttr(k, v))
+	}
+	tracer.add(rec)
+}
+
+// parseTraceparent retrieves the trace and the parent span of a W3C
+// "traceparent" header, e.g. "00-<32 hex digits>-<16 hex digits>-01".
+func parseTraceparent(val string) (traceID, spanID, bool) {
+	var tid traceID
+	var sid spanID
+	parts := strings.Split(val, "-")
+	if len(parts) != 4 || len(parts[0]) != 2 || parts[0] == "ff" ||
+		len(parts[1]) != hex.EncodedLen(len(tid)) || len(parts[2]) != hex.EncodedLen(len(sid)) {
+		return tid, sid, false
+	}
+	if n, err := hex.Decode(tid[:], []byte(parts[1])); err != nil || n != len(tid) || tid == (traceID{}) {
+		return tid, sid, false
+	}
+	if n, err := hex.Decode(sid[:], []byte(parts[2])); err != nil || n != len(sid) || sid == (spanID{}) {
+		return tid, sid, false
+	}
+	return tid, sid, true
+}
+
+// statusWriter remembers the status code of a response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sw *statusWriter) WriteHeader(code int) {
+	if sw.status == 0 {
+		sw.status = code
+	}
+	sw.ResponseWriter.WriteHeader(code)
+}
+
+func (sw *statusWriter) Write(p []byte) (int, error) {
+	if sw.status == 0 {
+		sw.status = http.StatusOK
+	}
+	return sw.ResponseWriter.Write(p)
+}
+
+// traceHandler wraps a handler, so that every request is traced as a span.
+func traceHandler(h http.Handler) http.Handler {
+	if tracer == nil {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if tid, sid, ok := parseTraceparent(r.Header.Get("traceparent")); ok {
+			ctx = context.WithValue(ctx, spanKey{}, &span{trace: tid, id: sid})
+		}
+		ctx, sp := startSpan(ctx, r.Method+" "+r.URL.Path, spanServer)
+		sp.set("http.method", r.Method)
+		sp.set("http.target", r.URL.RequestURI())
+		sp.set("http.user_agent", r.UserAgent())
+		sw := &statusWriter{ResponseWriter: w}
+		h.ServeHTTP(sw, r.WithContext(ctx))
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+		sp.set("http.status_code", strconv.Itoa(sw.status))
+		var err error
+		if sw.status >= http.StatusInternalServerError {
+			err = errStatus(sw.status)
+		}
+		sp.end(err)
+	})
+}
+
+type errStatus int
+
+func (e errStatus) Error() string { return http.StatusText(int(e)) }
+
+// traceExporter sends finished spans in batches to an OpenTelemetry
+// collector. If the collector is too slow, spans are dropped.
+type traceExporter struct {
+	url    string
+	client http.Client
+	queue  chan otlpSpan
+	once   sync.Once
+}
+
+// newTraceExporter returns an exporter for the given endpoint of a
+// collector, e.g. "http://localhost:4318".
+func newTraceExporter(endpoint string) *traceExporter {
+	return &traceExporter{
+		url:    strings.TrimSuffix(endpoint, "/") + "/v1/traces",
+		client: http.Client{Timeout: 10 * time.Second},
+		queue:  make(chan otlpSpan, traceQueueSize),
+	}
+}
+
+func (te *traceExporter) add(sp otlpSpan) {
+	te.once.Do(func() { go te.run() })
+	select {
+	case te.queue <- sp:
+	default:
+		log.Println("TRCE", "queue full, span dropped:", sp.Name)
+	}
+}
+
+func (te *traceExporter) run() {
+	ticker := time.NewTicker(traceFlushInterval)
+	defer ticker.Stop()
+	batch := make([]otlpSpan, 0, traceBatchSize)
+	for {
+		select {
+		case sp := <-te.queue:
+			batch = append(batch, sp)
+			if len(batch) < traceBatchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+		te.export(batch)
+		batch = batch[:0]
+	}
+}
+
+func (te *traceExporter) export(spans []otlpSpan) {
+	data, err := json.Marshal(otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: []otlpAttribute{otlpAttr("service.name", traceServiceName)}},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: traceScopeName}, Spans: spans}},
+	}}})
+	if err != nil {
+		log.Println("TRCE", err)
+		return
+	}
+	resp, err := te.client.Post(te.url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		log.Println("TRCE", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		log.Println("TRCE", te.url, resp.Status)
+	}
+}
+
+// Data types of the JSON encoding of OTLP.
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID      string          `json:"traceId"`
+	SpanID       string          `json:"spanId"`
+	ParentSpanID string          `json:"parentSpanId,omitempty"`
+	Name         string          `json:"name"`
+	Kind         int             `json:"kind"`
+	StartTime    string          `json:"startTimeUnixNano"`
+	EndTime      string          `json:"endTimeUnixNano"`
+	Attributes   []otlpAttribute `json:"attributes,omitempty"`
+	Status       otlpStatus      `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+func otlpAttr(key, val string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpValue{StringValue: val}}
+}
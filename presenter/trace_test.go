@@ -0,0 +1,50 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	const (
+		tid = "4bf92f3577b34da6a3ce929d0e0e4736"
+		sid = "00f067aa0ba902b7"
+	)
+	tp, sp, ok := parseTraceparent("00-" + tid + "-" + sid + "-01")
+	if !ok {
+		t.Fatal("valid traceparent not accepted")
+	}
+	if got := hex.EncodeToString(tp[:]); got != tid {
+		t.Errorf("expected trace %s, but got %s", tid, got)
+	}
+	if got := hex.EncodeToString(sp[:]); got != sid {
+		t.Errorf("expected span %s, but got %s", sid, got)
+	}
+
+	for _, val := range []string{
+		"",
+		"00-" + tid + "-" + sid,
+		"ff-" + tid + "-" + sid + "-01",
+		"000-" + tid + "-" + sid + "-01",
+		"00-" + tid[:30] + "-" + sid + "-01",
+		"00-" + tid + "00-" + sid + "-01",
+		"00-" + tid + "-" + sid + "00-01",
+		"00-" + tid[:31] + "x-" + sid + "-01",
+		"00-00000000000000000000000000000000-" + sid + "-01",
+		"00-" + tid + "-0000000000000000-01",
+	} {
+		if _, _, ok = parseTraceparent(val); ok {
+			t.Errorf("invalid traceparent %q accepted", val)
+		}
+	}
+}
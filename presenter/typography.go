@@ -0,0 +1,50 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"strings"
+
+	"zettelstore.de/contrib/presenter/deck"
+	"zettelstore.de/contrib/presenter/render"
+)
+
+// KeyTypography is the configuration key to apply language-specific
+// typography, i.e. quotation marks, no-break spaces, and hyphenation.
+const KeyTypography = "typography"
+
+// getTypography returns the value of KeyTypography. Only a false value
+// disables the typography.
+func getTypography(m map[string]string) bool {
+	val := m[KeyTypography]
+	return strings.TrimSpace(val) == "" || deck.IsTrue(val)
+}
+
+// typographyCSS lets the browser hyphenate text, according to the language
+// given in the "lang" attribute.
+var typographyCSS = []string{
+	"p, li, td, th, dd, blockquote { -webkit-hyphens: auto; hyphens: auto }",
+	"h1, h2, h3, h4, h5, h6, pre, code { -webkit-hyphens: manual; hyphens: manual }",
+}
+
+// setTypography enables the typography and the emoji shortcodes of the HTML
+// generator, if configured. The language of the content is used, or the
+// configured language.
+func (cfg *slidesConfig) setTypography(he *render.Generator, lang string) {
+	he.SetEmojis(cfg.emojis)
+	if !cfg.typography {
+		return
+	}
+	if lang == "" {
+		lang = cfg.lang
+	}
+	he.SetTypography(lang)
+}
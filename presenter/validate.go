@@ -0,0 +1,122 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// violation is a problem found while validating a HTML document.
+type violation struct {
+	pos int // position within the document
+	msg string
+}
+
+// voidElements cannot have any content and have no end tag.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"source": true, "track": true, "wbr": true,
+}
+
+// optionalEndElements may be closed implicitly.
+var optionalEndElements = map[string]bool{
+	"p": true, "li": true, "dt": true, "dd": true, "tr": true, "td": true,
+	"th": true, "thead": true, "tbody": true, "tfoot": true, "option": true,
+}
+
+var (
+	reTag    = regexp.MustCompile(`<!--(?s:.*?)-->|<![^>]*>|<(/?)([a-zA-Z][a-zA-Z0-9-]*)((?:[^>"']|"[^"]*"|'[^']*')*)>`)
+	reEntity = regexp.MustCompile(`^&(?:#[0-9]+|#[xX][0-9a-fA-F]+|[a-zA-Z][a-zA-Z0-9]*);`)
+)
+
+// validateHTML checks a HTML document for wrongly nested elements, duplicate
+// ids, and unescaped characters. It is not a full validator, but it detects
+// typical errors of the encoder.
+func validateHTML(data []byte) []violation {
+	var result []violation
+	var stack []string
+	var stackPos []int
+	text := func(from, to int) {
+		for i := from; i < to; i++ {
+			switch data[i] {
+			case '<':
+				result = append(result, violation{i, "unescaped '<' in text"})
+			case '&':
+				if !reEntity.Match(data[i:to]) {
+					result = append(result, violation{i, "unescaped '&' in text"})
+				}
+			}
+		}
+	}
+	last := 0
+	for _, match := range reTag.FindAllSubmatchIndex(data, -1) {
+		start, end := match[0], match[1]
+		if n := len(stack); n > 0 && (stack[n-1] == "script" || stack[n-1] == "style") {
+			// Content of script and style elements is not HTML.
+			if match[4] < 0 || match[3] == match[2] || strings.ToLower(string(data[match[4]:match[5]])) != stack[n-1] {
+				continue
+			}
+		} else {
+			text(last, start)
+		}
+		last = end
+		if match[4] < 0 {
+			continue // comment or doctype
+		}
+		name := strings.ToLower(string(data[match[4]:match[5]]))
+		isEnd := match[3] > match[2]
+		if !isEnd {
+			attrs := string(data[match[6]:match[7]])
+			if voidElements[name] || strings.HasSuffix(attrs, "/") {
+				continue
+			}
+			stack = append(stack, name)
+			stackPos = append(stackPos, start)
+			continue
+		}
+		if voidElements[name] {
+			result = append(result, violation{start, fmt.Sprintf("end tag for void element <%s>", name)})
+			continue
+		}
+		found := -1
+		for i := len(stack) - 1; i >= 0; i-- {
+			if stack[i] == name {
+				found = i
+				break
+			}
+			if !optionalEndElements[stack[i]] {
+				break
+			}
+		}
+		if found < 0 {
+			if len(stack) > 0 {
+				result = append(result, violation{start, fmt.Sprintf("end tag </%s> does not match <%s>", name, stack[len(stack)-1])})
+			} else {
+				result = append(result, violation{start, fmt.Sprintf("end tag </%s> without start tag", name)})
+			}
+			continue
+		}
+		stack, stackPos = stack[:found], stackPos[:found]
+	}
+	text(last, len(data))
+	for i, name := range stack {
+		if !optionalEndElements[name] {
+			result = append(result, violation{stackPos[i], fmt.Sprintf("element <%s> is not closed", name)})
+		}
+	}
+	for _, id := range duplicateIDs(data) {
+		result = append(result, violation{0, fmt.Sprintf("duplicate id %q", id)})
+	}
+	return result
+}
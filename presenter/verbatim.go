@@ -0,0 +1,57 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"zettelstore.de/c/api"
+	"zettelstore.de/contrib/presenter/deck"
+	"zettelstore.de/contrib/presenter/render"
+)
+
+// KeyVerbatimPrefix is the prefix of configuration keys that name a zettel
+// with the script for a verbatim syntax, e.g. "verbatim-mermaid".
+const KeyVerbatimPrefix = "verbatim-"
+
+func newVerbatimRegistry() render.VerbatimRegistry {
+	return render.VerbatimRegistry{
+		deck.SyntaxMermaid: {
+			Script: "<script type=\"text/javascript\">\n//<![CDATA[\n" + mermaid + "//]]>\n</script>\n" +
+				"<script>mermaid.initialize({startOnLoad:true});</script>\n",
+		},
+	}
+}
+
+// getVerbatimRegistry returns the registry of all verbatim syntaxes, with
+// scripts added / replaced by the configuration.
+func getVerbatimRegistry(ctx context.Context, c *zsClient, m map[string]string) render.VerbatimRegistry {
+	vr := newVerbatimRegistry()
+	for key, val := range m {
+		syntax := strings.TrimPrefix(key, KeyVerbatimPrefix)
+		if syntax == key || syntax == "" {
+			continue
+		}
+		zid := api.ZettelID(val)
+		if !zid.IsValid() {
+			continue
+		}
+		data, err := c.GetZettel(ctx, zid, api.PartContent)
+		if err != nil {
+			log.Println("VERB", zid, err)
+			continue
+		}
+		vr[syntax] = &render.VerbatimSyntax{Script: string(data)}
+	}
+	return vr
+}
@@ -0,0 +1,195 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2022 Detlef Stern
+//
+// This file is part of zettelstore slides application.
+//
+// Zettelstore slides application is licensed under the latest version of the
+// EUPL (European Union Public License). Please see file LICENSE.txt for your
+// rights and obligations under this license.
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+	"time"
+
+	"codeberg.org/t73fde/sxpf"
+	"zettelstore.de/c/api"
+	"zettelstore.de/c/client"
+)
+
+// DefaultTimeout is the default maximum duration of a call to Zettelstore.
+const DefaultTimeout = 10 * time.Second
+
+// Parameter of the circuit breaker.
+const (
+	maxFailures    = 5                // consecutive failures to open the circuit
+	breakerTimeout = 30 * time.Second // duration the circuit stays open
+)
+
+// errCircuitOpen is returned if Zettelstore is not called, because too many
+// previous calls failed.
+var errCircuitOpen = errors.New("zettelstore temporarily not available")
+
+// zsClient wraps a Zettelstore client. Every call has a deadline, and calls
+// are rejected for some time, if Zettelstore seems to be unavailable.
+type zsClient struct {
+	c       *client.Client
+	timeout time.Duration
+	breaker circuitBreaker
+}
+
+func newZsClient(c *client.Client, timeout time.Duration) *zsClient {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &zsClient{c: c, timeout: timeout}
+}
+
+// Base returns the base URL of Zettelstore.
+func (zc *zsClient) Base() string { return zc.c.Base() }
+
+// call calls Zettelstore with the given function. The name of the operation
+// and the zettel identifier, if any, are used for tracing.
+func (zc *zsClient) call(ctx context.Context, op string, zid api.ZettelID, fn func(context.Context) error) error {
+	ctx, sp := startSpan(ctx, "zettelstore "+op, spanClient)
+	sp.set("zettel.id", string(zid))
+	if !zc.breaker.allow() {
+		markUnreachable(ctx)
+		sp.end(errCircuitOpen)
+		return errCircuitOpen
+	}
+	callCtx, cancel := context.WithTimeout(ctx, zc.timeout)
+	defer cancel()
+	err := fn(callCtx)
+	if ctx.Err() != nil {
+		// The caller gave up, e.g. the browser closed the connection or the
+		// render timeout has passed. This says nothing about Zettelstore.
+		zc.breaker.abort()
+	} else if isFailure(err) {
+		markUnreachable(ctx)
+		zc.breaker.record(true)
+	} else {
+		zc.breaker.record(false)
+	}
+	sp.end(err)
+	return err
+}
+
+// isFailure returns true, if the error of a call shows that Zettelstore is
+// not available: it could not be reached, it did not answer in time, or it
+// answered with a server error.
+func isFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var cerr *client.Error
+	if errors.As(err, &cerr) {
+		return cerr.StatusCode >= 500
+	}
+	return true
+}
+
+func (zc *zsClient) GetMeta(ctx context.Context, zid api.ZettelID) (m api.ZettelMeta, err error) {
+	err = zc.call(ctx, "GetMeta", zid, func(ctx context.Context) error {
+		m, err = zc.c.GetMeta(ctx, zid)
+		return err
+	})
+	return m, err
+}
+
+func (zc *zsClient) GetZettel(ctx context.Context, zid api.ZettelID, part api.Part) (data []byte, err error) {
+	err = zc.call(ctx, "GetZettel", zid, func(ctx context.Context) error {
+		data, err = zc.c.GetZettel(ctx, zid, part)
+		return err
+	})
+	return data, err
+}
+
+func (zc *zsClient) GetEvaluatedSexpr(ctx context.Context, zid api.ZettelID, part api.Part) (val sxpf.Value, err error) {
+	err = zc.call(ctx, "GetEvaluatedSexpr", zid, func(ctx context.Context) error {
+		val, err = zc.c.GetEvaluatedSexpr(ctx, zid, part)
+		return err
+	})
+	return val, err
+}
+
+func (zc *zsClient) GetZettelOrder(ctx context.Context, zid api.ZettelID) (o api.ZidMetaRelatedList, err error) {
+	err = zc.call(ctx, "GetZettelOrder", zid, func(ctx context.Context) error {
+		o, err = zc.c.GetZettelOrder(ctx, zid)
+		return err
+	})
+	return o, err
+}
+
+func (zc *zsClient) ListZettelJSON(ctx context.Context, query url.Values) (q string, l []api.ZidMetaJSON, err error) {
+	err = zc.call(ctx, "ListZettelJSON", api.InvalidZID, func(ctx context.Context) error {
+		q, l, err = zc.c.ListZettelJSON(ctx, query)
+		return err
+	})
+	return q, l, err
+}
+
+func (zc *zsClient) UpdateZettel(ctx context.Context, zid api.ZettelID, data []byte) error {
+	return zc.call(ctx, "UpdateZettel", zid, func(ctx context.Context) error {
+		return zc.c.UpdateZettel(ctx, zid, data)
+	})
+}
+
+// circuitBreaker counts consecutive failed calls. If there are too many, the
+// circuit opens and all calls are rejected for some time. After that, one
+// call is allowed to check whether Zettelstore is available again.
+type circuitBreaker struct {
+	mx        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func (cb *circuitBreaker) allow() bool {
+	cb.mx.Lock()
+	defer cb.mx.Unlock()
+	if cb.failures < maxFailures {
+		return true
+	}
+	if cb.probing || time.Now().Before(cb.openUntil) {
+		return false
+	}
+	cb.probing = true
+	return true
+}
+
+// state returns the number of consecutive failed calls, and the time until
+// the circuit is open.
+func (cb *circuitBreaker) state() (int, time.Time) {
+	cb.mx.Lock()
+	defer cb.mx.Unlock()
+	return cb.failures, cb.openUntil
+}
+
+// record counts a failed call, or resets the counter after a successful one.
+func (cb *circuitBreaker) record(failed bool) {
+	cb.mx.Lock()
+	defer cb.mx.Unlock()
+	cb.probing = false
+	if !failed {
+		cb.failures = 0
+		return
+	}
+	cb.failures++
+	if cb.failures >= maxFailures {
+		cb.openUntil = time.Now().Add(breakerTimeout)
+	}
+}
+
+// abort notes a call that was aborted by its caller. It is not counted, but
+// another call may check whether Zettelstore is available again.
+func (cb *circuitBreaker) abort() {
+	cb.mx.Lock()
+	cb.probing = false
+	cb.mx.Unlock()
+}